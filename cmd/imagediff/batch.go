@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+)
+
+// batchResult encapsulates the outcome of diffing one of the pairs of a batch, for the JSON output.
+type batchResult struct {
+	diff.Pair
+	Result *diff.Result `json:"result,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// batchDiff diffs the pairs of images read from the provided file, or the
+// standard input, concurrently, and enriches and notifies the results.
+func batchDiff(args []string, parallelism int, d *differ) ([]*diff.BatchResult, error) {
+	if len(args) > 1 {
+		return nil, errors.New("please provide at most one file to read pairs of images from, or none for the standard input")
+	}
+	r := io.Reader(os.Stdin)
+	if len(args) == 1 && args[0] != "-" {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+	pairs, err := diff.ParsePairs(r)
+	if err != nil {
+		return nil, err
+	}
+	log.WithFields(log.Fields{"pairs": len(pairs), "parallelism": parallelism}).Info("diffing images")
+	results := diff.Batch(pairs, d.options, parallelism)
+	for _, result := range results {
+		if result.Err == nil {
+			enrichResult(result.Result, d.enrich)
+			notifyResult(result.Result, d.notifiers)
+		}
+	}
+	return results, nil
+}
+
+// renderBatch renders the successful results one after the other, each under a heading naming the pair of images.
+func renderBatch(w io.Writer, results []*diff.BatchResult, render renderFunc, output string, templated bool) error {
+	sections := []*section{}
+	values := make([]*batchResult, len(results))
+	for i, result := range results {
+		values[i] = &batchResult{Pair: result.Pair, Result: result.Result}
+		if result.Err != nil {
+			values[i].Error = result.Err.Error()
+			continue
+		}
+		sections = append(sections, &section{
+			heading: fmt.Sprintf("%v -> %v", result.X, result.Y),
+			result:  result.Result,
+		})
+	}
+	return renderSections(w, sections, values, render, output, templated)
+}
+
+// batchChangeLogs lists the change logs of the provided successful results.
+func batchChangeLogs(results []*diff.BatchResult) [][]*diff.Change {
+	changeLogs := [][]*diff.Change{}
+	for _, result := range results {
+		if result.Err == nil {
+			changeLogs = append(changeLogs, result.Result.ChangeLog)
+		}
+	}
+	return changeLogs
+}
+
+// batchFailures counts the pairs which could not be diffed.
+func batchFailures(results []*diff.BatchResult) int {
+	failures := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failures++
+		}
+	}
+	return failures
+}
+
+// serveMetrics serves Prometheus metrics on the provided address, e.g. to monitor long batches.
+func serveMetrics(address string) {
+	log.WithField("address", address).Info("serving metrics")
+	if err := http.ListenAndServe(address, promhttp.Handler()); err != nil {
+		log.Errorf("failed to serve metrics: %v", err)
+	}
+}
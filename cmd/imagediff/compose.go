@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/weaveworks-experiments/imagediff/pkg/compose"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+)
+
+// composeOptions encapsulates the flags of the compose subcommand.
+type composeOptions struct {
+	tag          string
+	tagOverrides []string
+}
+
+// composeResult encapsulates the outcome of diffing the image of a service.
+type composeResult struct {
+	*compose.ImageChange
+	Result *diff.Result `json:"result"`
+}
+
+// composeDiff diffs the images of the services of the provided docker-compose
+// file against the other provided docker-compose file's, or the proposed tags.
+func composeDiff(args []string, options *composeOptions, d *differ) ([]*composeResult, error) {
+	hasTags := options.tag != "" || len(options.tagOverrides) > 0
+	if len(args) == 0 || len(args) > 2 || (len(args) == 2) == hasTags {
+		return nil, errors.New("please provide either two docker-compose files, or one along with --tag or --set")
+	}
+	if len(args) == 2 && args[0] == "-" && args[1] == "-" {
+		return nil, errors.New("both docker-compose files cannot be read from the standard input")
+	}
+	current, err := compose.ReadFile(args[0])
+	if err != nil {
+		return nil, err
+	}
+	var changes []*compose.ImageChange
+	if hasTags {
+		tags, err := compose.ParseTags(options.tagOverrides)
+		if err != nil {
+			return nil, err
+		}
+		if options.tag != "" {
+			tags["*"] = options.tag
+		}
+		changes = compose.ChangesToTags(current, tags)
+	} else {
+		proposed, err := compose.ReadFile(args[1])
+		if err != nil {
+			return nil, err
+		}
+		changes = compose.Changes(current, proposed)
+	}
+	if len(changes) == 0 {
+		log.Info("no image to change, nothing to diff")
+	}
+	results := []*composeResult{}
+	for _, change := range changes {
+		log.WithField("service", change.Service).Info("diffing images")
+		result, err := d.diff(change.X, change.Y)
+		if err != nil {
+			return nil, fmt.Errorf("service %v: %v", change.Service, err)
+		}
+		results = append(results, &composeResult{ImageChange: change, Result: result})
+	}
+	return results, nil
+}
+
+// renderCompose renders the provided results one after the other, each under a heading naming the service.
+func renderCompose(w io.Writer, results []*composeResult, render renderFunc, output string, templated bool) error {
+	sections := make([]*section, len(results))
+	for i, result := range results {
+		sections[i] = &section{
+			heading: fmt.Sprintf("%v: %v -> %v", result.Service, result.X, result.Y),
+			result:  result.Result,
+		}
+	}
+	return renderSections(w, sections, results, render, output, templated)
+}
+
+// composeChangeLogs lists the change logs of the provided results.
+func composeChangeLogs(results []*composeResult) [][]*diff.Change {
+	changeLogs := make([][]*diff.Change, len(results))
+	for i, result := range results {
+		changeLogs[i] = result.Result.ChangeLog
+	}
+	return changeLogs
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/enrich"
+	"github.com/weaveworks-experiments/imagediff/pkg/github"
+	"github.com/weaveworks-experiments/imagediff/pkg/gitlab"
+	"github.com/weaveworks-experiments/imagediff/pkg/metrics"
+	"github.com/weaveworks-experiments/imagediff/pkg/repository"
+)
+
+// enrichOptions encapsulates the flags to enrich changes with their source code repository's host's API.
+type enrichOptions struct {
+	enabled      bool
+	gitHubAPIURL string
+	gitHubToken  string
+	gitLabAPIURL string
+	gitLabToken  string
+	// providers are shared across diffs, e.g. in batch or serve, for their
+	// caches of responses to be.
+	providers providers
+}
+
+// providers caches the clients of hosts' APIs, by API URL.
+type providers struct {
+	mutex   sync.Mutex
+	clients map[string]enrich.Provider
+}
+
+// get returns the client of the provided API, creating it the first time.
+func (p *providers) get(apiURL string, create func() enrich.Provider) enrich.Provider {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.clients == nil {
+		p.clients = map[string]enrich.Provider{}
+	}
+	provider, ok := p.clients[apiURL]
+	if !ok {
+		provider = create()
+		p.clients[apiURL] = provider
+	}
+	return provider
+}
+
+// enrichResult populates the pull requests (or merge requests) associated with the
+// changes of the provided diff result. As this is optional, failures are only
+// logged.
+func enrichResult(result *diff.Result, options *enrichOptions) {
+	if !options.enabled {
+		return
+	}
+	provider, apiURL := enrichProvider(result.Repository, options)
+	step := metrics.StartStep(metrics.Enrich)
+	if err := step.Done(enrich.Enrich(provider, result.Repository, result.ChangeLog)); err != nil {
+		log.WithFields(log.Fields{"repository": result.Repository, "api": apiURL}).Warnf("failed to enrich changes with pull requests: %v", err)
+	}
+}
+
+// enrichProvider picks GitLab's API for repositories on GitLab, i.e. if its
+// API's URL is provided, for self-managed instances, or the repository's host
+// looks like GitLab, and GitHub's API otherwise.
+func enrichProvider(repo *repository.GitRepository, options *enrichOptions) (enrich.Provider, string) {
+	if options.gitLabAPIURL != "" || repo.IsGitLab() {
+		apiURL := options.gitLabAPIURL
+		if apiURL == "" {
+			apiURL = gitlab.APIURL(repo)
+		}
+		return options.providers.get(apiURL, func() enrich.Provider {
+			return gitlab.NewClient(apiURL, orEnv(options.gitLabToken, "GITLAB_TOKEN"))
+		}), apiURL
+	}
+	apiURL := options.gitHubAPIURL
+	if apiURL == "" {
+		apiURL = github.APIURL(repo)
+	}
+	return options.providers.get(apiURL, func() enrich.Provider {
+		return github.NewClient(apiURL, orEnv(options.gitHubToken, "GITHUB_TOKEN"))
+	}), apiURL
+}
+
+// orEnv defaults the provided value to the provided environment variable,
+// rather than using the latter as the flag's default, not to leak secrets in
+// --help.
+func orEnv(value, key string) string {
+	if value == "" {
+		return os.Getenv(key)
+	}
+	return value
+}
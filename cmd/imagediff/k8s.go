@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/k8s"
+)
+
+// k8sOptions encapsulates the flags of the k8s subcommand.
+type k8sOptions struct {
+	filename   string
+	to         string
+	tag        string
+	containers []string
+	kubeconfig string
+	context    string
+	namespace  string
+}
+
+// k8sResult encapsulates the outcome of diffing the image of one of a workload's containers.
+type k8sResult struct {
+	*k8s.ImageChange
+	Result *diff.Result `json:"result"`
+}
+
+// k8sDiff diffs the images of the current workloads, read from manifests or
+// from Kubernetes' API, against the proposed tag or workloads.
+func k8sDiff(args []string, options *k8sOptions, d *differ) ([]*k8sResult, error) {
+	if len(args) > 1 {
+		return nil, errors.New("please provide at most one workload, as <kind>/<name>, e.g. deployment/foo")
+	}
+	if (options.tag == "") == (options.to == "") {
+		return nil, errors.New("please provide either --tag or --to")
+	}
+	if options.filename == "-" && options.to == "-" {
+		return nil, errors.New("--filename and --to cannot both be read from the standard input")
+	}
+	current, err := currentWorkloads(args, options)
+	if err != nil {
+		return nil, err
+	}
+	var changes []*k8s.ImageChange
+	if options.tag != "" {
+		changes = k8s.ChangesToTag(current, options.tag)
+	} else {
+		proposed, err := k8s.ReadManifests(options.to)
+		if err != nil {
+			return nil, err
+		}
+		changes = k8s.Changes(current, proposed)
+	}
+	if len(changes) == 0 {
+		log.Info("no image to change, nothing to diff")
+	}
+	results := []*k8sResult{}
+	for _, change := range changes {
+		if len(options.containers) > 0 && !contains(options.containers, change.Container) {
+			continue
+		}
+		log.WithFields(log.Fields{"workload": change.Workload, "container": change.Container}).Info("diffing images")
+		result, err := d.diff(change.X, change.Y)
+		if err != nil {
+			return nil, fmt.Errorf("%v, container %v: %v", change.Workload, change.Container, err)
+		}
+		results = append(results, &k8sResult{ImageChange: change, Result: result})
+	}
+	return results, nil
+}
+
+func currentWorkloads(args []string, options *k8sOptions) ([]*k8s.Workload, error) {
+	var kind, name string
+	if len(args) == 1 {
+		var err error
+		if kind, name, err = k8s.ParseReference(args[0]); err != nil {
+			return nil, err
+		}
+	}
+	if options.filename == "" {
+		if name == "" {
+			return nil, errors.New("please provide either --filename, or a workload to read from Kubernetes' API, as <kind>/<name>, e.g. deployment/foo")
+		}
+		workload, err := apiWorkload(kind, name, options)
+		if err != nil {
+			return nil, err
+		}
+		return []*k8s.Workload{workload}, nil
+	}
+	workloads, err := k8s.ReadManifests(options.filename)
+	if err != nil || name == "" {
+		return workloads, err
+	}
+	for _, workload := range workloads {
+		if workload.Kind == kind && workload.Name == name {
+			return []*k8s.Workload{workload}, nil
+		}
+	}
+	return nil, fmt.Errorf("%v/%v not found in %v", strings.ToLower(kind), name, options.filename)
+}
+
+func apiWorkload(kind, name string, options *k8sOptions) (*k8s.Workload, error) {
+	path := options.kubeconfig
+	if path == "" {
+		path = k8s.DefaultKubeconfigPath()
+	}
+	config, err := k8s.LoadConfig(path, options.context)
+	if err != nil {
+		return nil, err
+	}
+	client, err := k8s.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	return client.Workload(kind, options.namespace, name)
+}
+
+// renderK8s renders the provided results one after the other, each under a heading naming the workload's container.
+func renderK8s(w io.Writer, results []*k8sResult, render renderFunc, output string, templated bool) error {
+	sections := make([]*section, len(results))
+	for i, result := range results {
+		sections[i] = &section{
+			heading: fmt.Sprintf("%v, container %v: %v -> %v", result.Workload, result.Container, result.X, result.Y),
+			result:  result.Result,
+		}
+	}
+	return renderSections(w, sections, results, render, output, templated)
+}
+
+// k8sChangeLogs lists the change logs of the provided results.
+func k8sChangeLogs(results []*k8sResult) [][]*diff.Change {
+	changeLogs := make([][]*diff.Change, len(results))
+	for i, result := range results {
+		changeLogs[i] = result.Result.ChangeLog
+	}
+	return changeLogs
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
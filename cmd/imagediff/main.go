@@ -2,36 +2,284 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	flag "github.com/spf13/pflag"
+	"github.com/weaveworks-experiments/imagediff/pkg/conventional"
 	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/issue"
+	"github.com/weaveworks-experiments/imagediff/pkg/notify"
 	"github.com/weaveworks-experiments/imagediff/pkg/repository"
 )
 
 func main() {
-	dockerConfigPath := flag.String("docker-config-path", "~/.docker/config.json", "Path to your Docker config.json file. This file contains your credentials to authenticate against private Docker registries.")
+	dockerConfigPath := flag.String("docker-config-path", "~/.docker/config.json", "Path to your Docker config.json file. This file contains your credentials to authenticate against private Docker registries. Credentials not found there are prompted for, except by batch and serve.")
 	sshPrivateKeyPath := flag.String("ssh-private-key-path", "~/.ssh/id_rsa", "Path to the private SSH key to use to authenticate against private Git repositories.")
+	output := flag.String("output", "text", "Output format, one of: text, markdown, html, conventional (Markdown grouped by Conventional Commits type), json.")
+	templateText := flag.String("template", "", "Go template to render the changelog with, instead of --output. See README.md for the data model available to templates.")
+	templateFile := flag.String("template-file", "", "Path to a file containing a Go template to render the changelog with, instead of --output.")
+	firstParent := flag.Bool("first-parent", false, "Only show the changes which landed on the mainline, i.e. follow only the first parent of merge commits.")
+	noMerges := flag.Bool("no-merges", false, "Do not show merge commits.")
+	nested := flag.Bool("nested", false, "Show the changes merge commits brought in underneath these.")
+	byPR := flag.Bool("by-pr", false, "List each pull request (or merge request) once, along with its commits, then the commits which are not part of any. Only for text and markdown outputs.")
+	orderName := flag.String("order", "", "Order to list changes in, one of: topo, author-date, committer-date. Defaults to the order the history is walked in.")
+	reverse := flag.Bool("reverse", false, "List the oldest change first.")
+	issues := flag.Bool("issues", false, "Only list the issues referenced by the changes, once each. Only for text, markdown and json outputs.")
+	issueTrackerSpecs := flag.StringArray("issue-tracker", []string{}, "Issue tracker to extract references to issues for, as a pattern and a URL, separated by a space, e.g. \"ABC-[0-9]+ https://jira.example.com/browse/\". Can be repeated. GitHub/GitLab issues referenced with closing keywords, e.g. \"Fixes #12\", are always extracted.")
+	enrichChanges := flag.Bool("enrich", false, "Enrich changes with the titles, authors, labels and reviewers of their pull requests, from GitHub's API, or of their merge requests, along with their milestones and pipelines' statuses, from GitLab's API.")
+	gitHubAPIURL := flag.String("github-api-url", "", "Base URL of GitHub's API, e.g. https://github.example.com/api/v3 for GitHub Enterprise. Defaults to https://api.github.com for github.com, and https://<host>/api/v3 otherwise.")
+	gitHubToken := flag.String("github-token", "", "Token to authenticate against GitHub's API. Defaults to the GITHUB_TOKEN environment variable.")
+	gitLabAPIURL := flag.String("gitlab-api-url", "", "Base URL of GitLab's API, e.g. https://git.example.com/api/v4 for self-managed GitLab. Defaults to https://<host>/api/v4 for hosts named like gitlab.com. Implies GitLab's API is used to enrich changes.")
+	gitLabToken := flag.String("gitlab-token", "", "Token to authenticate against GitLab's API, e.g. a personal access token. Defaults to the GITLAB_TOKEN environment variable.")
+	failOnBreaking := flag.Bool("fail-on-breaking", false, fmt.Sprintf("Exit with status %v if the changelog contains breaking changes, according to the Conventional Commits specification.", exitBreaking))
+	stats := flag.Bool("stats", false, "Compute the number of lines added and deleted in each file, for each change, e.g. to use in templates. This is slow for long changelogs.")
+	submoduleDiff := flag.Bool("submodule-diff", false, "Also list the changes between the revisions of the submodules bumped between the two revisions, cloning their repositories, as configured in .gitmodules.")
+	dependencyDiff := flag.Bool("dependency-diff", false, "Also list the dependencies added, removed, upgraded or downgraded between the two revisions, from go.mod, go.sum, Gopkg.lock, package-lock.json, yarn.lock, requirements.txt, poetry.lock, Cargo.lock and pom.xml files.")
+	configDiff := flag.Bool("config-diff", false, "Also list the changes to the images' configuration: Env, Entrypoint, Cmd, Shell, User, WorkingDir, ExposedPorts, Volumes, Healthcheck, StopSignal and Labels.")
+	layerDiff := flag.Bool("layer-diff", false, "Also compare the images' layers: shared, added and removed layers, with their sizes.")
+	fileDiff := flag.Bool("file-diff", false, "Also list the files added, removed or modified by the layers which changed, honouring whiteouts. Implies --layer-diff. This reads these layers, and is slow for large images.")
+	packageDiff := flag.Bool("package-diff", false, "Also list the OS packages added, removed, or whose version changed, from dpkg, apk and rpm databases. This reads the images' layers, and is slow for large images.")
+	baseDiff := flag.Bool("base-diff", false, "Also diff the images' base images, if these changed, and theirs, recursively. Base images are found from images' org.opencontainers.image.base.name and org.opencontainers.image.base.digest labels, or else among --base-candidate images.")
+	baseCandidates := flag.StringArray("base-candidate", []string{}, "Candidate base image, e.g. alpine:3.12, found to be the base image of images not labelled with theirs if its layers are their bottom layers. Can be repeated.")
+	filename := flag.StringP("filename", "f", "", "k8s: Path to Kubernetes manifests to read the current workloads from, instead of Kubernetes' API: a file, a directory, or - for the standard input, e.g. piped from helm template.")
+	to := flag.String("to", "", "k8s: Path to Kubernetes manifests to read the proposed workloads from, instead of --tag: a file, a directory, or - for the standard input.")
+	tag := flag.String("tag", "", "k8s, compose: Proposed tag for the images of the workloads' containers, or of all the services.")
+	containers := flag.StringArray("container", []string{}, "k8s: Only diff the images of the containers with this name. Can be repeated.")
+	kubeconfig := flag.String("kubeconfig", "", "k8s: Path to the kubeconfig file to access Kubernetes' API with. Defaults to $KUBECONFIG, or ~/.kube/config.")
+	kubeContext := flag.String("context", "", "k8s: kubeconfig context to access Kubernetes' API with. Defaults to the current context.")
+	namespace := flag.StringP("namespace", "n", "", "k8s: Namespace of the workload to read from Kubernetes' API. Defaults to the kubeconfig context's namespace, or default.")
+	tagOverrides := flag.StringArray("set", []string{}, "compose: Proposed tag for the image of a service, as <service>=<tag>, e.g. web=0.9.1. Can be repeated, and takes precedence over --tag.")
+	parallelism := flag.Int("parallelism", 4, "batch, serve: Maximum number of pairs of images to diff concurrently.")
+	metricsAddress := flag.String("metrics-address", "", "batch: Address to serve Prometheus metrics on, at /metrics, while diffing, e.g. :9090. serve always serves these, on --listen.")
+	listen := flag.String("listen", ":8080", "serve: Address to serve diffs on.")
+	maxClones := flag.Int("max-clones", 16, "serve: Maximum number of clones of repositories to keep in memory, the least recently used ones being evicted past it.")
+	timeout := flag.Duration("timeout", 2*time.Minute, "serve: Maximum duration of requests, including waiting for other diffs to complete, past --parallelism.")
+	sinks := flag.StringArray("sink", []string{}, "Sink to post changelogs to, as [<kind>=]<URL>, kind being one of: webhook (POST the diff as JSON, the default), slack (a Slack incoming webhook), teams (a Microsoft Teams incoming webhook). Can be repeated. For serve, enables the webhook receiver, at /webhooks/, and only posts the changelogs of pushed images.")
+	previousTags := flag.StringSlice("previous-tag", []string{"semver", "pushed"}, "serve: Strategies to find the tag to diff pushed tags against, tried in turn: semver (the greatest preceding semantic version, amongst the registry's tags), pushed (the tag pushed before, since the server started).")
+	webhookToken := flag.String("webhook-token", "", "serve: Shared secret webhooks must provide, as a bearer token, or a token query parameter. Defaults to the WEBHOOK_TOKEN environment variable.")
+	flag.Usage = usage
 	flag.Parse()
 	args := flag.Args()
-	if len(args) != 2 {
+	subcommand := ""
+	if len(args) > 0 && (args[0] == "k8s" || args[0] == "compose" || args[0] == "batch" || args[0] == "serve") {
+		subcommand = args[0]
+	}
+	if subcommand == "" && len(args) != 2 {
 		log.Fatal("Please provide two Docker image tags to compare")
 	}
+	tmpl, err := userTemplate(*templateText, *templateFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	render, err := renderer(&outputOptions{
+		output:   *output,
+		template: tmpl,
+		nested:   *nested,
+		byPR:     *byPR,
+		issues:   *issues,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	trackers, err := issueTrackers(*issueTrackerSpecs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	order, err := diff.ParseOrder(*orderName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	notifiers, err := notifiers(*sinks)
+	if err != nil {
+		log.Fatal(err)
+	}
+	d := &differ{
+		options: &diff.Options{
+			DockerConfigPath: string(*dockerConfigPath),
+			GitOptions: &repository.Options{
+				SSHPrivateKeyPath: string(*sshPrivateKeyPath),
+			},
+			FirstParent:    *firstParent,
+			NoMerges:       *noMerges,
+			Order:          order,
+			Reverse:        *reverse,
+			IssueTrackers:  trackers,
+			Stats:          *stats,
+			SubmoduleDiff:  *submoduleDiff,
+			DependencyDiff: *dependencyDiff,
+			ConfigDiff:     *configDiff,
+			LayerDiff:      *layerDiff || *fileDiff,
+			FileDiff:       *fileDiff,
+			PackageDiff:    *packageDiff,
+			BaseDiff:       *baseDiff,
+			BaseCandidates: *baseCandidates,
+		},
+		enrich: &enrichOptions{
+			enabled:      *enrichChanges,
+			gitHubAPIURL: *gitHubAPIURL,
+			gitHubToken:  *gitHubToken,
+			gitLabAPIURL: *gitLabAPIURL,
+			gitLabToken:  *gitLabToken,
+		},
+		notifiers: notifiers,
+	}
+	switch subcommand {
+	case "k8s":
+		results, err := k8sDiff(args[1:], &k8sOptions{
+			filename:   *filename,
+			to:         *to,
+			tag:        *tag,
+			containers: *containers,
+			kubeconfig: *kubeconfig,
+			context:    *kubeContext,
+			namespace:  *namespace,
+		}, d)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := renderK8s(os.Stdout, results, render, *output, tmpl != nil); err != nil {
+			log.Fatal(err)
+		}
+		failIfBreaking(*failOnBreaking, k8sChangeLogs(results)...)
+		return
+	case "compose":
+		results, err := composeDiff(args[1:], &composeOptions{
+			tag:          *tag,
+			tagOverrides: *tagOverrides,
+		}, d)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := renderCompose(os.Stdout, results, render, *output, tmpl != nil); err != nil {
+			log.Fatal(err)
+		}
+		failIfBreaking(*failOnBreaking, composeChangeLogs(results)...)
+		return
+	case "batch":
+		if *metricsAddress != "" {
+			go serveMetrics(*metricsAddress)
+		}
+		results, err := batchDiff(args[1:], *parallelism, d)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := renderBatch(os.Stdout, results, render, *output, tmpl != nil); err != nil {
+			log.Fatal(err)
+		}
+		failIfBreaking(*failOnBreaking, batchChangeLogs(results)...)
+		if failures := batchFailures(results); failures > 0 {
+			log.WithFields(log.Fields{"failed": failures, "pairs": len(results)}).Fatal("failed to diff some pairs of images")
+		}
+		return
+	case "serve":
+		if err := serve(&serveOptions{
+			listen:      *listen,
+			timeout:     *timeout,
+			parallelism: *parallelism,
+			maxClones:   *maxClones,
+			webhooks: &webhookOptions{
+				previousTags:     *previousTags,
+				token:            orEnv(*webhookToken, "WEBHOOK_TOKEN"),
+				dockerConfigPath: *dockerConfigPath,
+			},
+		}, d); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	x := args[0]
 	y := args[1]
-	changeLog, err := diff.Diff(x, y, &diff.Options{
-		DockerConfigPath: string(*dockerConfigPath),
-		GitOptions: &repository.Options{
-			SSHPrivateKeyPath: string(*sshPrivateKeyPath),
-		},
-	})
+	result, err := d.diff(x, y)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"x": x,
 			"y": y,
 		}).Fatal(err)
 	}
-	for _, change := range changeLog {
-		fmt.Printf("%v %v\n", change.Revision[:7], change.Message)
+	if err := render(os.Stdout, result); err != nil {
+		log.Fatal(err)
+	}
+	failIfBreaking(*failOnBreaking, result.ChangeLog)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage:
+  %[1]v [flags] <image> <image>
+      Diff the two provided images.
+  %[1]v k8s [flags] [<kind>/<name>]
+      Diff the images of the Kubernetes workloads read from --filename, or
+      from Kubernetes' API, against --tag, or the workloads read from --to.
+  %[1]v compose [flags] <docker-compose file> [<docker-compose file>]
+      Diff the images of the services of the first docker-compose file
+      against the second's, or against --tag and --set.
+  %[1]v batch [flags] [<file>]
+      Diff the pairs of images read from the provided file, or the standard
+      input, one pair per line, concurrently.
+  %[1]v serve [flags]
+      Serve diffs over HTTP, as GET /diff?from=<image>&to=<image>, and,
+      with --sink, diff images pushed to registries, as notified by their
+      webhooks, against their previous tags.
+
+Flags:
+`, os.Args[0])
+	flag.PrintDefaults()
+}
+
+// differ diffs images, and enriches and notifies the results, according to the provided flags.
+type differ struct {
+	options   *diff.Options
+	enrich    *enrichOptions
+	notifiers []notify.Notifier
+	// results are reused when diffing the same images again, e.g. for several
+	// Kubernetes workloads sharing images.
+	results map[[2]string]*diff.Result
+}
+
+func (d *differ) diff(x, y string) (*diff.Result, error) {
+	if result, ok := d.results[[2]string{x, y}]; ok {
+		return result, nil
+	}
+	result, err := diff.Diff(x, y, d.options)
+	if err != nil {
+		return nil, err
+	}
+	enrichResult(result, d.enrich)
+	notifyResult(result, d.notifiers)
+	if d.results == nil {
+		d.results = map[[2]string]*diff.Result{}
 	}
+	d.results[[2]string{x, y}] = result
+	return result, nil
 }
+
+// failIfBreaking exits with exitBreaking if enabled, and if any of the provided change logs contains breaking changes.
+func failIfBreaking(enabled bool, changeLogs ...[]*diff.Change) {
+	if !enabled {
+		return
+	}
+	count := 0
+	for _, changeLog := range changeLogs {
+		count += len(conventional.Breaking(changeLog))
+	}
+	if count > 0 {
+		log.WithField("breaking", count).Error("changelog contains breaking changes")
+		os.Exit(exitBreaking)
+	}
+}
+
+func issueTrackers(specs []string) ([]*issue.Tracker, error) {
+	trackers := []*issue.Tracker{}
+	for _, spec := range specs {
+		tracker, err := issue.ParseTracker(spec)
+		if err != nil {
+			return nil, err
+		}
+		trackers = append(trackers, tracker)
+	}
+	return trackers, nil
+}
+
+// exitBreaking is the exit status when failing on breaking changes, to tell these apart from errors.
+const exitBreaking = 3
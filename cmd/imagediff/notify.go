@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/metrics"
+	"github.com/weaveworks-experiments/imagediff/pkg/notify"
+)
+
+// notifiers creates the notifiers of the provided sinks, each as [<kind>=]<URL>, e.g.:
+//
+//	slack=https://hooks.slack.com/services/T000/B000/XXXX
+//
+// with webhook being the default kind.
+func notifiers(sinks []string) ([]notify.Notifier, error) {
+	notifiers := []notify.Notifier{}
+	for _, sink := range sinks {
+		kind, url := "webhook", sink
+		if parts := strings.SplitN(sink, "=", 2); len(parts) == 2 && !strings.Contains(parts[0], "/") {
+			kind, url = parts[0], parts[1]
+		}
+		switch kind {
+		case "webhook":
+			notifiers = append(notifiers, notify.NewWebhook(url))
+		case "slack":
+			notifiers = append(notifiers, notify.NewSlack(url))
+		case "teams":
+			notifiers = append(notifiers, notify.NewTeams(url))
+		default:
+			return nil, fmt.Errorf("invalid sink [%v], expected: [webhook|slack|teams=]<URL>", sink)
+		}
+	}
+	return notifiers, nil
+}
+
+// notifyResult sends the provided result to the provided notifiers, and only
+// warns if this fails, as the result is rendered regardless.
+func notifyResult(result *diff.Result, notifiers []notify.Notifier) {
+	for _, notifier := range notifiers {
+		step := metrics.StartStep(metrics.Notify)
+		if err := step.Done(notifier.Notify(result)); err != nil {
+			log.WithFields(log.Fields{"x": result.X, "y": result.Y}).Warnf("failed to notify: %v", err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/render"
+)
+
+type renderFunc func(io.Writer, *diff.Result) error
+
+// outputOptions encapsulates the flags determining how to render diff results.
+type outputOptions struct {
+	output   string
+	template *template.Template
+	nested   bool
+	byPR     bool
+	issues   bool
+}
+
+// renderer selects how to render diff results, according to the provided options.
+func renderer(options *outputOptions) (renderFunc, error) {
+	if options.template != nil {
+		return func(w io.Writer, result *diff.Result) error {
+			return render.Template(w, options.template, result)
+		}, nil
+	}
+	if options.issues {
+		switch options.output {
+		case "text":
+			return render.IssuesText, nil
+		case "markdown":
+			return render.IssuesMarkdown, nil
+		case "json":
+			return render.IssuesJSON, nil
+		default:
+			return nil, fmt.Errorf("--issues is not supported for output format: %v", options.output)
+		}
+	}
+	switch {
+	case options.output == "text" && options.byPR:
+		return render.PullRequestsText, nil
+	case options.output == "text" && options.nested:
+		return render.NestedText, nil
+	case options.output == "text":
+		return render.Text, nil
+	case options.output == "markdown" && options.byPR:
+		return render.PullRequestsMarkdown, nil
+	case options.byPR:
+		return nil, fmt.Errorf("--by-pr is not supported for output format: %v", options.output)
+	case options.output == "markdown":
+		return render.Markdown, nil
+	case options.output == "html":
+		return render.HTML, nil
+	case options.output == "conventional":
+		return render.Conventional, nil
+	case options.output == "json":
+		return render.JSON, nil
+	default:
+		return nil, fmt.Errorf("unsupported output format: %v", options.output)
+	}
+}
+
+func userTemplate(text, path string) (*template.Template, error) {
+	switch {
+	case text != "" && path != "":
+		return nil, errors.New("--template and --template-file are mutually exclusive")
+	case text != "":
+		return render.NewTemplate(text)
+	case path != "":
+		return render.NewTemplateFromFile(path)
+	default:
+		return nil, nil
+	}
+}
+
+// section is the diff result of one of several pairs of images, e.g. of a Kubernetes workload's container.
+type section struct {
+	heading string
+	result  *diff.Result
+}
+
+// renderSections renders the provided sections one after the other, each
+// under its heading, or, for JSON, v, to remain a single valid JSON value.
+func renderSections(w io.Writer, sections []*section, v interface{}, render renderFunc, output string, templated bool) error {
+	if output == "json" && !templated {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+	for i, section := range sections {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if !templated {
+			heading(w, output, section.heading)
+		}
+		if err := render(w, section.result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func heading(w io.Writer, output, text string) {
+	switch output {
+	case "markdown", "conventional":
+		fmt.Fprintf(w, "## %v\n\n", text)
+	case "html":
+		fmt.Fprintf(w, "<h2>%v</h2>\n", html.EscapeString(text))
+	default:
+		fmt.Fprintf(w, "%v\n%v\n", text, strings.Repeat("=", len(text)))
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/client"
+	log "github.com/sirupsen/logrus"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/notify"
+	"github.com/weaveworks-experiments/imagediff/pkg/server"
+	"github.com/weaveworks-experiments/imagediff/pkg/webhook"
+)
+
+// serveOptions encapsulates the flags of the serve subcommand.
+type serveOptions struct {
+	listen      string
+	timeout     time.Duration
+	parallelism int
+	maxClones   int
+	webhooks    *webhookOptions
+}
+
+// serve serves diffs over HTTP until interrupted, sharing clones of
+// repositories across requests, and, if any sink is configured, receives
+// registries' push webhooks. Only the diffs of pushes are notified.
+func serve(options *serveOptions, d *differ) error {
+	docker, err := client.NewEnvClient()
+	if err != nil {
+		return err
+	}
+	diffOptions := *d.options
+	diffOptions.Clones = diff.NewClones(options.maxClones)
+	diffOptions.NonInteractive = true
+	diffFunc := func(ctx context.Context, x, y string) (*diff.Result, error) {
+		result, err := diff.DiffContext(ctx, x, y, &diffOptions)
+		if err != nil {
+			return nil, err
+		}
+		enrichResult(result, d.enrich)
+		return result, nil
+	}
+	s := server.New(diffFunc, &server.Options{
+		Timeout:            options.timeout,
+		MaxConcurrentDiffs: options.parallelism,
+		Ready: func() error {
+			_, err := docker.Ping(context.Background())
+			return err
+		},
+	})
+	handler := s.Handler()
+	var receiver *webhook.Receiver
+	if len(d.notifiers) > 0 {
+		if receiver, err = newReceiver(diffFunc, d.notifiers, options); err != nil {
+			return err
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/webhooks/", receiver.Handler())
+		mux.Handle("/", handler)
+		handler = mux
+	}
+	httpServer := &http.Server{
+		Addr:              options.listen,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		// Leave time to render and write responses once diffs complete:
+		WriteTimeout: options.timeout + 30*time.Second,
+	}
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		log.Info("shutting down")
+		ctx, cancel := context.WithTimeout(context.Background(), options.timeout)
+		defer cancel()
+		if err := httpServer.Shutdown(ctx); err != nil {
+			log.Errorf("failed to shut down gracefully: %v", err)
+		}
+	}()
+	log.WithField("address", options.listen).Info("serving diffs")
+	if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	// ListenAndServe returns as soon as Shutdown starts, so wait for in-flight
+	// requests to complete, including webhooks queuing pushes, before closing
+	// the receiver's queue:
+	<-shutdown
+	if receiver != nil {
+		log.Info("diffing queued pushes")
+		receiver.Close()
+	}
+	return nil
+}
+
+func newReceiver(diffFunc server.DiffFunc, notifiers []notify.Notifier, options *serveOptions) (*webhook.Receiver, error) {
+	finder, err := finder(options.webhooks.previousTags, options.webhooks.dockerConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	return webhook.New(diffFunc, &webhook.Options{
+		Finder:    finder,
+		Notifiers: notifiers,
+		Token:     options.webhooks.token,
+		Workers:   options.parallelism,
+	}), nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/weaveworks-experiments/imagediff/pkg/registry"
+	"github.com/weaveworks-experiments/imagediff/pkg/webhook"
+)
+
+// webhookOptions encapsulates the flags of the serve subcommand's webhook receiver.
+type webhookOptions struct {
+	previousTags     []string
+	token            string
+	dockerConfigPath string
+}
+
+// finder creates the finder of previous tags, trying each of the provided strategies in turn.
+func finder(strategies []string, dockerConfigPath string) (webhook.Finder, error) {
+	chain := webhook.Chain{}
+	for _, strategy := range strategies {
+		switch strategy {
+		case "semver":
+			chain = append(chain, &webhook.SemverFinder{Lister: &registry.Client{AuthConfigs: authConfigs(dockerConfigPath)}})
+		case "pushed":
+			chain = append(chain, webhook.NewPushedFinder())
+		default:
+			return nil, fmt.Errorf("invalid previous tag strategy [%v], expected one of: semver, pushed", strategy)
+		}
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("please provide at least one previous tag strategy, one of: semver, pushed")
+	}
+	return chain, nil
+}
+
+// authConfigs reads the credentials to list tags with, if any, as private repositories are otherwise not listed.
+func authConfigs(dockerConfigPath string) registry.AuthConfigs {
+	if strings.HasPrefix(dockerConfigPath, "~/") {
+		dockerConfigPath = os.Getenv("HOME") + dockerConfigPath[1:]
+	}
+	configs, err := registry.ReadAuthConfigs(dockerConfigPath)
+	if err != nil {
+		log.WithField("path", dockerConfigPath).Warnf("failed to read Docker credentials, only listing tags of public images: %v", err)
+		return nil
+	}
+	return configs
+}
@@ -0,0 +1,159 @@
+package compose
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/image"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Service encapsulates a docker-compose service, and the image it runs.
+type Service struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+}
+
+type file struct {
+	Services map[string]struct {
+		Image string `yaml:"image"`
+	} `yaml:"services"`
+}
+
+// Parse finds the services in the provided docker-compose file, sorted by
+// name, with variables in their images, e.g. "${TAG:-latest}", interpolated
+// from the provided environment, e.g. os.LookupEnv. Services without images,
+// i.e. only built, are ignored.
+func Parse(data []byte, env func(string) (string, bool)) ([]*Service, error) {
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid docker-compose file: %v", err)
+	}
+	services := []*Service{}
+	for name, service := range f.Services {
+		if service.Image == "" {
+			continue
+		}
+		image, err := interpolate(service.Image, env)
+		if err != nil {
+			return nil, fmt.Errorf("service [%v]: %v", name, err)
+		}
+		services = append(services, &Service{Name: name, Image: image})
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services, nil
+}
+
+// ReadFile finds the services in the provided docker-compose file, or, for
+// "-", in the standard input, interpolating variables from the environment.
+func ReadFile(path string) ([]*Service, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	services, err := Parse(data, os.LookupEnv)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	return services, nil
+}
+
+// variableRegex matches docker-compose's variables: "$$" (escaped dollars),
+// "$VAR", "${VAR}", "${VAR:-default}", "${VAR-default}", "${VAR:?error}" and
+// "${VAR?error}".
+var variableRegex = regexp.MustCompile(`\$(?:(\$)|([A-Za-z_][A-Za-z0-9_]*)|\{([A-Za-z_][A-Za-z0-9_]*)(?:(:?[-?])([^}]*))?\})`)
+
+func interpolate(value string, env func(string) (string, bool)) (string, error) {
+	var err error
+	interpolated := variableRegex.ReplaceAllStringFunc(value, func(match string) string {
+		groups := variableRegex.FindStringSubmatch(match)
+		if groups[1] != "" {
+			return "$"
+		}
+		name, operator, argument := groups[2]+groups[3], groups[4], groups[5]
+		v, ok := env(name)
+		switch operator {
+		case ":-":
+			if v == "" {
+				return argument
+			}
+		case "-":
+			if !ok {
+				return argument
+			}
+		case ":?", "?":
+			if !ok || operator == ":?" && v == "" {
+				err = fmt.Errorf("required variable %v is missing: %v", name, argument)
+			}
+		}
+		return v
+	})
+	return interpolated, err
+}
+
+// ImageChange encapsulates the change of the image of a service.
+type ImageChange struct {
+	Service string `json:"service"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// Changes lists the changes of images replacing the provided current services
+// by the provided ones would make. Services are matched by name, and those
+// only in either are ignored.
+func Changes(from, to []*Service) []*ImageChange {
+	byName := map[string]*Service{}
+	for _, service := range to {
+		byName[service.Name] = service
+	}
+	changes := []*ImageChange{}
+	for _, x := range from {
+		if y, ok := byName[x.Name]; ok && y.Image != x.Image {
+			changes = append(changes, &ImageChange{Service: x.Name, X: x.Image, Y: y.Image})
+		}
+	}
+	return changes
+}
+
+// ChangesToTags lists the changes of images deploying the provided tags would
+// make, for the provided services: tags maps services' names to their new
+// tags, with the "*" key, if any, applying to all other services.
+func ChangesToTags(services []*Service, tags map[string]string) []*ImageChange {
+	changes := []*ImageChange{}
+	for _, service := range services {
+		tag, ok := tags[service.Name]
+		if !ok {
+			tag, ok = tags["*"]
+		}
+		if !ok {
+			continue
+		}
+		if y := string(image.Image(service.Image).WithTag(tag)); y != service.Image {
+			changes = append(changes, &ImageChange{Service: service.Name, X: service.Image, Y: y})
+		}
+	}
+	return changes
+}
+
+// ParseTags parses tag overrides, as "<service>=<tag>", into the map ChangesToTags expects.
+func ParseTags(overrides []string) (map[string]string, error) {
+	tags := map[string]string{}
+	for _, override := range overrides {
+		parts := strings.SplitN(override, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid tag override [%v], expected: <service>=<tag>", override)
+		}
+		tags[parts[0]] = parts[1]
+	}
+	return tags, nil
+}
@@ -0,0 +1,105 @@
+package compose_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/compose"
+)
+
+const sampleCompose = `version: "3.7"
+services:
+  web:
+    image: microscaling/microscaling:${TAG:-v0.9.0}
+    ports:
+    - "80:80"
+  worker:
+    image: microscaling/worker:$WORKER_TAG
+  db:
+    image: postgres:11
+  builder:
+    build: .
+`
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+func TestParse(t *testing.T) {
+	services, err := compose.Parse([]byte(sampleCompose), env(map[string]string{"WORKER_TAG": "v0.3.0"}))
+	assert.NoError(t, err)
+	assert.Equal(t, []*compose.Service{
+		{Name: "db", Image: "postgres:11"},
+		{Name: "web", Image: "microscaling/microscaling:v0.9.0"},
+		{Name: "worker", Image: "microscaling/worker:v0.3.0"},
+	}, services)
+
+	services, err = compose.Parse([]byte(sampleCompose), env(map[string]string{"TAG": "v0.9.1", "WORKER_TAG": "v0.3.0"}))
+	assert.NoError(t, err)
+	assert.Equal(t, "microscaling/microscaling:v0.9.1", services[1].Image)
+}
+
+func TestParseInterpolation(t *testing.T) {
+	for image, expected := range map[string]string{
+		"foo:${TAG}":          "foo:",
+		"foo:${TAG-latest}":   "foo:latest",
+		"foo:${EMPTY-latest}": "foo:",
+		"foo:${EMPTY:-v1}":    "foo:v1",
+		"foo:${SET:-v1}":      "foo:v2",
+		"foo:$$TAG":           "foo:$TAG",
+	} {
+		services, err := compose.Parse([]byte("services:\n  foo:\n    image: "+image+"\n"), env(map[string]string{"EMPTY": "", "SET": "v2"}))
+		assert.NoError(t, err, image)
+		assert.Equal(t, expected, services[0].Image, image)
+	}
+	_, err := compose.Parse([]byte("services:\n  foo:\n    image: foo:${TAG:?please set TAG}\n"), env(map[string]string{}))
+	assert.EqualError(t, err, "service [foo]: required variable TAG is missing: please set TAG")
+}
+
+func TestParseInvalidFile(t *testing.T) {
+	_, err := compose.Parse([]byte("services: [foo"), env(map[string]string{}))
+	assert.Error(t, err)
+}
+
+func TestChanges(t *testing.T) {
+	from := []*compose.Service{
+		{Name: "db", Image: "postgres:11"},
+		{Name: "removed", Image: "example/removed:1.0"},
+		{Name: "web", Image: "microscaling/microscaling:v0.9.0"},
+	}
+	to := []*compose.Service{
+		{Name: "added", Image: "example/added:1.0"},
+		{Name: "db", Image: "postgres:11"},
+		{Name: "web", Image: "microscaling/microscaling:v0.9.1"},
+	}
+	assert.Equal(t, []*compose.ImageChange{
+		{Service: "web", X: "microscaling/microscaling:v0.9.0", Y: "microscaling/microscaling:v0.9.1"},
+	}, compose.Changes(from, to))
+}
+
+func TestChangesToTags(t *testing.T) {
+	services := []*compose.Service{
+		{Name: "db", Image: "postgres:11"},
+		{Name: "web", Image: "microscaling/microscaling:v0.9.0"},
+		{Name: "worker", Image: "microscaling/worker:v0.3.0"},
+	}
+	tags, err := compose.ParseTags([]string{"web=v0.9.1", "db=11"})
+	assert.NoError(t, err)
+	assert.Equal(t, []*compose.ImageChange{
+		{Service: "web", X: "microscaling/microscaling:v0.9.0", Y: "microscaling/microscaling:v0.9.1"},
+	}, compose.ChangesToTags(services, tags))
+
+	tags["*"] = "v0.4.0"
+	assert.Equal(t, []*compose.ImageChange{
+		{Service: "web", X: "microscaling/microscaling:v0.9.0", Y: "microscaling/microscaling:v0.9.1"},
+		{Service: "worker", X: "microscaling/worker:v0.3.0", Y: "microscaling/worker:v0.4.0"},
+	}, compose.ChangesToTags(services, tags))
+
+	for _, override := range []string{"web", "=v1", "web="} {
+		_, err := compose.ParseTags([]string{override})
+		assert.Error(t, err, override)
+	}
+}
@@ -0,0 +1,165 @@
+package conventional
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+)
+
+// Commit is a commit message parsed according to the Conventional Commits specification.
+// See also: https://www.conventionalcommits.org
+type Commit struct {
+	Type        string
+	Scope       string
+	Breaking    bool
+	Description string
+	Body        string
+	// BreakingChange is the description of the breaking change, from the
+	// "BREAKING CHANGE:" footer, if any.
+	BreakingChange string
+}
+
+var (
+	headerRegex         = regexp.MustCompile(`^([A-Za-z]+)(?:\(([^()]*)\))?(!)?: +(.+)$`)
+	breakingFooterRegex = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE: *`)
+	// footerRegex matches the start of the footer following another one, e.g.
+	// "Reviewed-by: Z" or "Refs #133".
+	footerRegex = regexp.MustCompile(`\n(?:[A-Za-z][A-Za-z-]*|BREAKING[ -]CHANGE)(?:: | #)`)
+)
+
+// Parse parses the provided commit message, and returns false if it does not
+// follow the Conventional Commits specification. The type is lower-cased.
+func Parse(message string) (*Commit, bool) {
+	message = strings.TrimSpace(message)
+	header, body := message, ""
+	if idx := strings.Index(message, "\n"); idx != -1 {
+		header, body = strings.TrimSpace(message[:idx]), strings.TrimSpace(message[idx+1:])
+	}
+	matches := headerRegex.FindStringSubmatch(header)
+	if matches == nil {
+		return nil, false
+	}
+	commit := &Commit{
+		Type:        strings.ToLower(matches[1]),
+		Scope:       strings.TrimSpace(matches[2]),
+		Breaking:    matches[3] == "!",
+		Description: strings.TrimSpace(matches[4]),
+		Body:        body,
+	}
+	if loc := breakingFooterRegex.FindStringIndex(body); loc != nil {
+		footer := body[loc[1]:]
+		if next := footerRegex.FindStringIndex(footer); next != nil {
+			footer = footer[:next[0]]
+		}
+		commit.Breaking = true
+		commit.BreakingChange = strings.TrimSpace(footer)
+	}
+	return commit, true
+}
+
+// ParseChange parses the provided change's commit message. For merge commits
+// which do not follow the Conventional Commits specification, the first line
+// of their body is tried next, as this is where GitHub puts the title of the
+// merged pull request.
+func ParseChange(change *diff.Change) (*Commit, bool) {
+	commit, ok := Parse(change.Message)
+	if ok || !change.Merge {
+		return commit, ok
+	}
+	message := strings.TrimSpace(change.Message)
+	idx := strings.Index(message, "\n")
+	if idx == -1 {
+		return nil, false
+	}
+	return Parse(message[idx+1:])
+}
+
+// Entry pairs a change with its parsed commit message, if it follows the
+// Conventional Commits specification, nil otherwise.
+type Entry struct {
+	*diff.Change
+	Commit *Commit
+}
+
+// Section groups entries of a changelog under a title, e.g. "Features".
+type Section struct {
+	Title   string
+	Entries []*Entry
+}
+
+// BreakingChanges is the title of the section listing breaking changes, whatever their type.
+const BreakingChanges = "Breaking Changes"
+
+// OtherChanges is the title of the section listing changes of other types, or
+// not following the Conventional Commits specification.
+const OtherChanges = "Other Changes"
+
+// Titles maps commit types to the titles of the sections listing them, in the order sections are listed in.
+var Titles = []struct {
+	Type  string
+	Title string
+}{
+	{"feat", "Features"},
+	{"fix", "Fixes"},
+	{"perf", "Performance Improvements"},
+	{"revert", "Reverts"},
+	{"docs", "Documentation"},
+	{"refactor", "Refactoring"},
+	{"test", "Tests"},
+	{"build", "Build System"},
+	{"ci", "Continuous Integration"},
+	{"style", "Styles"},
+	{"chore", "Chores"},
+}
+
+// Group groups the provided change log in sections, by type: breaking changes
+// first, which are also listed under their type, then one section per type,
+// and finally other changes. Empty sections are omitted, and the order of the
+// change log is preserved within sections.
+func Group(changeLog []*diff.Change) []*Section {
+	breaking := &Section{Title: BreakingChanges}
+	byType := map[string]*Section{}
+	for _, title := range Titles {
+		byType[title.Type] = &Section{Title: title.Title}
+	}
+	other := &Section{Title: OtherChanges}
+	for _, change := range changeLog {
+		commit, ok := ParseChange(change)
+		entry := &Entry{Change: change, Commit: commit}
+		if !ok {
+			other.Entries = append(other.Entries, entry)
+			continue
+		}
+		if commit.Breaking {
+			breaking.Entries = append(breaking.Entries, entry)
+		}
+		if section, ok := byType[commit.Type]; ok {
+			section.Entries = append(section.Entries, entry)
+		} else {
+			other.Entries = append(other.Entries, entry)
+		}
+	}
+	sections := []*Section{}
+	candidates := []*Section{breaking}
+	for _, title := range Titles {
+		candidates = append(candidates, byType[title.Type])
+	}
+	for _, section := range append(candidates, other) {
+		if len(section.Entries) > 0 {
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
+
+// Breaking lists the breaking changes in the provided change log.
+func Breaking(changeLog []*diff.Change) []*Entry {
+	entries := []*Entry{}
+	for _, change := range changeLog {
+		if commit, ok := ParseChange(change); ok && commit.Breaking {
+			entries = append(entries, &Entry{Change: change, Commit: commit})
+		}
+	}
+	return entries
+}
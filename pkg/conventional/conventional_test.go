@@ -0,0 +1,113 @@
+package conventional_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/conventional"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+)
+
+func TestParse(t *testing.T) {
+	commit, ok := conventional.Parse("feat(k8s): get image from deployment object\n\nSo that labels can be retrieved.\n")
+	assert.True(t, ok)
+	assert.Equal(t, &conventional.Commit{
+		Type:        "feat",
+		Scope:       "k8s",
+		Description: "get image from deployment object",
+		Body:        "So that labels can be retrieved.",
+	}, commit)
+}
+
+func TestParseWithoutScope(t *testing.T) {
+	commit, ok := conventional.Parse("Fix: typo")
+	assert.True(t, ok)
+	assert.Equal(t, &conventional.Commit{Type: "fix", Description: "typo"}, commit)
+}
+
+func TestParseBreakingWithBang(t *testing.T) {
+	commit, ok := conventional.Parse("refactor(api)!: drop v1 endpoints")
+	assert.True(t, ok)
+	assert.Equal(t, &conventional.Commit{Type: "refactor", Scope: "api", Breaking: true, Description: "drop v1 endpoints"}, commit)
+}
+
+func TestParseBreakingWithFooter(t *testing.T) {
+	commit, ok := conventional.Parse("feat: use a separate KubeLabelConfig type\n\nRefs: #40\nBREAKING CHANGE: the config file format changed.\n")
+	assert.True(t, ok)
+	assert.True(t, commit.Breaking)
+	assert.Equal(t, "the config file format changed.", commit.BreakingChange)
+
+	commit, ok = conventional.Parse("feat: foo\n\nBREAKING-CHANGE: bar")
+	assert.True(t, ok)
+	assert.True(t, commit.Breaking)
+	assert.Equal(t, "bar", commit.BreakingChange)
+}
+
+func TestParseBreakingWithTrailingFooters(t *testing.T) {
+	commit, ok := conventional.Parse("feat: drop v1 endpoints\n\nBREAKING CHANGE: v1 endpoints are gone,\nuse v2 ones instead.\nReviewed-by: Jane Doe\nRefs #133\n")
+	assert.True(t, ok)
+	assert.True(t, commit.Breaking)
+	assert.Equal(t, "v1 endpoints are gone,\nuse v2 ones instead.", commit.BreakingChange)
+}
+
+func TestParseNonConventional(t *testing.T) {
+	for _, message := range []string{
+		"Bump version",
+		"Merge pull request #40 from microscaling/k8s-labels",
+		"feat no colon",
+		"feat:no space",
+		": no type",
+	} {
+		_, ok := conventional.Parse(message)
+		assert.False(t, ok, message)
+	}
+}
+
+func TestParseChangeFallsBackToMergeBody(t *testing.T) {
+	change := &diff.Change{Message: "Merge pull request #40 from microscaling/k8s-labels\n\nfeat(k8s): add labels\n", Merge: true}
+	commit, ok := conventional.ParseChange(change)
+	assert.True(t, ok)
+	assert.Equal(t, &conventional.Commit{Type: "feat", Scope: "k8s", Description: "add labels"}, commit)
+
+	change.Merge = false
+	_, ok = conventional.ParseChange(change)
+	assert.False(t, ok)
+}
+
+func TestGroup(t *testing.T) {
+	feat := &diff.Change{Revision: "1", Message: "feat: add foo"}
+	breakingFix := &diff.Change{Revision: "2", Message: "fix!: rename bar"}
+	fix := &diff.Change{Revision: "3", Message: "fix(baz): handle nil"}
+	other := &diff.Change{Revision: "4", Message: "Bump version"}
+	unknown := &diff.Change{Revision: "5", Message: "wip: something"}
+	feat2 := &diff.Change{Revision: "6", Message: "feat: add qux"}
+
+	sections := conventional.Group([]*diff.Change{feat, breakingFix, fix, other, unknown, feat2})
+	titles := []string{}
+	revisions := [][]string{}
+	for _, section := range sections {
+		titles = append(titles, section.Title)
+		sectionRevisions := []string{}
+		for _, entry := range section.Entries {
+			sectionRevisions = append(sectionRevisions, entry.Revision)
+		}
+		revisions = append(revisions, sectionRevisions)
+	}
+	assert.Equal(t, []string{"Breaking Changes", "Features", "Fixes", "Other Changes"}, titles)
+	assert.Equal(t, [][]string{{"2"}, {"1", "6"}, {"2", "3"}, {"4", "5"}}, revisions)
+	assert.Nil(t, sections[3].Entries[0].Commit)
+	assert.Equal(t, "wip", sections[3].Entries[1].Commit.Type)
+}
+
+func TestBreaking(t *testing.T) {
+	changeLog := []*diff.Change{
+		{Revision: "1", Message: "feat: add foo"},
+		{Revision: "2", Message: "fix!: rename bar"},
+		{Revision: "3", Message: "chore: bump\n\nBREAKING CHANGE: requires Go 1.10"},
+	}
+	breaking := conventional.Breaking(changeLog)
+	assert.Len(t, breaking, 2)
+	assert.Equal(t, "2", breaking[0].Revision)
+	assert.Equal(t, "3", breaking[1].Revision)
+	assert.Empty(t, conventional.Breaking(changeLog[:1]))
+}
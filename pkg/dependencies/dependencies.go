@@ -0,0 +1,324 @@
+package dependencies
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/weaveworks-experiments/imagediff/pkg/semver"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// Ecosystems, i.e. the package managers dependencies are listed for.
+const (
+	Go    = "go"
+	Npm   = "npm"
+	PyPI  = "pypi"
+	Cargo = "cargo"
+	Maven = "maven"
+)
+
+// Dependency is a dependency listed by a manifest, e.g. go.mod.
+type Dependency struct {
+	Name    string
+	Version string
+}
+
+// Kinds of Change.
+const (
+	Added      = "added"
+	Removed    = "removed"
+	Upgraded   = "upgraded"
+	Downgraded = "downgraded"
+	// Changed is a change to a version which is not comparable, e.g. a
+	// revision instead of a semantic version.
+	Changed = "changed"
+)
+
+// Change is a dependency added, removed, or whose version changed, between two revisions.
+type Change struct {
+	// Path is the path of the manifest listing the dependency, e.g. "go.mod".
+	Path      string `json:"path"`
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+	// Change is one of Added, Removed, Upgraded, Downgraded or Changed.
+	Change string `json:"change"`
+	// X and Y are the dependency's versions in both revisions, if any.
+	X string `json:"x,omitempty"`
+	Y string `json:"y,omitempty"`
+	// CompareURL is the URL of the web page comparing both versions on the
+	// host of the dependency's source code repository, if recognised.
+	CompareURL string `json:"compareURL,omitempty"`
+}
+
+func (c *Change) String() string {
+	switch c.Change {
+	case Added:
+		return strings.TrimSpace(fmt.Sprintf("%v: added %v", c.Name, c.Y))
+	case Removed:
+		return strings.TrimSpace(fmt.Sprintf("%v: removed %v", c.Name, c.X))
+	default:
+		return fmt.Sprintf("%v: %v → %v", c.Name, c.X, c.Y)
+	}
+}
+
+// manifest lists dependencies from a file, and possibly others next to it,
+// e.g. go.sum, next to go.mod, the content of which read provides.
+type manifest struct {
+	ecosystem string
+	// siblings are the names of the other files read, if any.
+	siblings []string
+	parse    func(data []byte, read func(name string) []byte) ([]*Dependency, error)
+	// compareURL links to the web page comparing the provided versions of the provided dependency, if possible.
+	compareURL func(name, x, y string) string
+}
+
+// manifests are the supported manifests, by file name.
+var manifests = map[string]*manifest{
+	"go.mod":            {ecosystem: Go, siblings: []string{"go.sum"}, parse: parseGoMod, compareURL: goCompareURL},
+	"Gopkg.lock":        {ecosystem: Go, parse: parseGopkgLock, compareURL: goCompareURL},
+	"package-lock.json": {ecosystem: Npm, parse: parsePackageLock},
+	"yarn.lock":         {ecosystem: Npm, parse: parseYarnLock},
+	"requirements.txt":  {ecosystem: PyPI, parse: parseRequirements},
+	"poetry.lock":       {ecosystem: PyPI, parse: parsePoetryLock},
+	"Cargo.lock":        {ecosystem: Cargo, parse: parseCargoLock},
+	"pom.xml":           {ecosystem: Maven, parse: parsePom},
+}
+
+// ignoredDirs are the directories never read manifests from, as these contain
+// copies of dependencies, or test data, rather than what the repository
+// depends on.
+var ignoredDirs = map[string]bool{
+	"vendor":       true,
+	"node_modules": true,
+	"testdata":     true,
+}
+
+// manifestFile is a manifest found in a revision's tree.
+type manifestFile struct {
+	*manifest
+	// dir is the tree of the directory the manifest is in.
+	dir  *object.Tree
+	name string
+	// hashes identify the content of the manifest, and of its siblings.
+	hashes string
+}
+
+// Diff lists the dependencies added, removed, or whose version changed,
+// between the two provided revisions, from the manifests found anywhere in
+// their trees, ordered by manifest's path, and dependency's name. Manifests
+// which fail to parse are skipped, with a warning.
+func Diff(x, y *object.Commit) ([]*Change, error) {
+	xFiles, err := manifestFiles(x)
+	if err != nil {
+		return nil, err
+	}
+	yFiles, err := manifestFiles(y)
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{}
+	for p := range xFiles {
+		paths = append(paths, p)
+	}
+	for p := range yFiles {
+		if _, ok := xFiles[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	changes := []*Change{}
+	for _, p := range paths {
+		xFile, yFile := xFiles[p], yFiles[p]
+		if xFile != nil && yFile != nil && xFile.hashes == yFile.hashes {
+			continue
+		}
+		xDependencies, err := xFile.dependencies()
+		if err != nil {
+			log.WithFields(log.Fields{"path": p, "revision": x.Hash.String()}).Warnf("failed to read dependencies: %v", err)
+			continue
+		}
+		yDependencies, err := yFile.dependencies()
+		if err != nil {
+			log.WithFields(log.Fields{"path": p, "revision": y.Hash.String()}).Warnf("failed to read dependencies: %v", err)
+			continue
+		}
+		m := xFile
+		if m == nil {
+			m = yFile
+		}
+		for _, change := range Compare(xDependencies, yDependencies) {
+			change.Path, change.Ecosystem = p, m.ecosystem
+			if change.X != "" && change.Y != "" && m.compareURL != nil {
+				change.CompareURL = m.compareURL(change.Name, change.X, change.Y)
+			}
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+// manifestFiles finds the supported manifests in the provided revision's tree, by path.
+func manifestFiles(commit *object.Commit) (map[string]*manifestFile, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]*manifestFile{}
+	return files, walk(tree, "", files)
+}
+
+func walk(tree *object.Tree, dir string, files map[string]*manifestFile) error {
+	for _, entry := range tree.Entries {
+		switch {
+		case entry.Mode == filemode.Dir && !ignoredDirs[entry.Name]:
+			subtree, err := tree.Tree(entry.Name)
+			if err != nil {
+				return err
+			}
+			if err := walk(subtree, path.Join(dir, entry.Name), files); err != nil {
+				return err
+			}
+		case entry.Mode.IsFile():
+			m, ok := manifests[entry.Name]
+			if !ok {
+				continue
+			}
+			hashes := []string{entry.Hash.String()}
+			for _, sibling := range m.siblings {
+				if e, err := tree.FindEntry(sibling); err == nil {
+					hashes = append(hashes, e.Hash.String())
+				}
+			}
+			files[path.Join(dir, entry.Name)] = &manifestFile{manifest: m, dir: tree, name: entry.Name, hashes: strings.Join(hashes, ",")}
+		}
+	}
+	return nil
+}
+
+// dependencies lists the dependencies of the manifest, if any.
+func (f *manifestFile) dependencies() ([]*Dependency, error) {
+	if f == nil {
+		return []*Dependency{}, nil
+	}
+	data, err := f.read(f.name)
+	if err != nil {
+		return nil, err
+	}
+	dependencies, err := f.parse(data, func(name string) []byte {
+		data, _ := f.read(name)
+		return data
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merge(dependencies), nil
+}
+
+// merge merges dependencies listed several times, e.g. packages installed
+// with several versions, into one, with all of their distinct versions,
+// ordered, and comma separated.
+func merge(dependencies []*Dependency) []*Dependency {
+	versions := map[string][]string{}
+	merged := []*Dependency{}
+	for _, d := range dependencies {
+		if _, ok := versions[d.Name]; !ok {
+			merged = append(merged, &Dependency{Name: d.Name})
+		}
+		if !contains(versions[d.Name], d.Version) {
+			versions[d.Name] = append(versions[d.Name], d.Version)
+		}
+	}
+	for _, d := range merged {
+		v := versions[d.Name]
+		sort.Slice(v, func(i, j int) bool {
+			x, xOK := semver.Parse(v[i])
+			y, yOK := semver.Parse(v[j])
+			if xOK && yOK {
+				return x.Less(y)
+			}
+			return v[i] < v[j]
+		})
+		d.Version = strings.Join(v, ", ")
+	}
+	return merged
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// read reads the file with the provided name, next to the manifest, or nil if there is none.
+func (f *manifestFile) read(name string) ([]byte, error) {
+	file, err := f.dir.File(name)
+	if err == object.ErrFileNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	content, err := file.Contents()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(content), nil
+}
+
+// Compare lists the dependencies added, removed, or whose version changed, from x to y, ordered by name.
+func Compare(x, y []*Dependency) []*Change {
+	xVersions, yVersions := map[string]string{}, map[string]string{}
+	names := []string{}
+	for _, d := range x {
+		xVersions[d.Name] = d.Version
+		names = append(names, d.Name)
+	}
+	for _, d := range y {
+		if _, ok := xVersions[d.Name]; !ok {
+			names = append(names, d.Name)
+		}
+		yVersions[d.Name] = d.Version
+	}
+	sort.Strings(names)
+	changes := []*Change{}
+	for i, name := range names {
+		if i > 0 && names[i-1] == name {
+			continue
+		}
+		xVersion, inX := xVersions[name]
+		yVersion, inY := yVersions[name]
+		switch {
+		case !inX:
+			changes = append(changes, &Change{Name: name, Change: Added, Y: yVersion})
+		case !inY:
+			changes = append(changes, &Change{Name: name, Change: Removed, X: xVersion})
+		case xVersion != yVersion:
+			changes = append(changes, &Change{Name: name, Change: versionChange(xVersion, yVersion), X: xVersion, Y: yVersion})
+		}
+	}
+	return changes
+}
+
+// versionChange tells whether the provided versions are an upgrade, or a
+// downgrade, if these are semantic versions, or merely a change otherwise.
+func versionChange(x, y string) string {
+	xVersion, xOK := semver.Parse(x)
+	yVersion, yOK := semver.Parse(y)
+	switch {
+	case !xOK || !yOK:
+		return Changed
+	case xVersion.Less(yVersion):
+		return Upgraded
+	case yVersion.Less(xVersion):
+		return Downgraded
+	default:
+		return Changed
+	}
+}
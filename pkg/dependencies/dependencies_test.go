@@ -0,0 +1,96 @@
+package dependencies_test
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/dependencies"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+// commit creates a commit with the provided files, by path, in the provided storage.
+func commit(t *testing.T, storage *memory.Storage, files map[string]string) *object.Commit {
+	commit := &object.Commit{
+		Author:    object.Signature{Name: "Jane Doe", Email: "jane@example.com"},
+		Committer: object.Signature{Name: "Jane Doe", Email: "jane@example.com"},
+		Message:   "Update dependencies",
+		TreeHash:  tree(t, storage, files),
+	}
+	obj := storage.NewEncodedObject()
+	assert.NoError(t, commit.Encode(obj))
+	hash, err := storage.SetEncodedObject(obj)
+	assert.NoError(t, err)
+	commit, err = object.GetCommit(storage, hash)
+	assert.NoError(t, err)
+	return commit
+}
+
+func tree(t *testing.T, storage *memory.Storage, files map[string]string) plumbing.Hash {
+	blobs, dirs := map[string]string{}, map[string]map[string]string{}
+	for path, content := range files {
+		if i := strings.Index(path, "/"); i != -1 {
+			if dirs[path[:i]] == nil {
+				dirs[path[:i]] = map[string]string{}
+			}
+			dirs[path[:i]][path[i+1:]] = content
+		} else {
+			blobs[path] = content
+		}
+	}
+	entries := []object.TreeEntry{}
+	for name, content := range blobs {
+		entries = append(entries, object.TreeEntry{Name: name, Mode: filemode.Regular, Hash: blob(t, storage, content)})
+	}
+	for name, files := range dirs {
+		entries = append(entries, object.TreeEntry{Name: name, Mode: filemode.Dir, Hash: tree(t, storage, files)})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	obj := storage.NewEncodedObject()
+	assert.NoError(t, (&object.Tree{Entries: entries}).Encode(obj))
+	hash, err := storage.SetEncodedObject(obj)
+	assert.NoError(t, err)
+	return hash
+}
+
+func blob(t *testing.T, storage *memory.Storage, content string) plumbing.Hash {
+	obj := storage.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	w, err := obj.Writer()
+	assert.NoError(t, err)
+	_, err = w.Write([]byte(content))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	hash, err := storage.SetEncodedObject(obj)
+	assert.NoError(t, err)
+	return hash
+}
+
+func TestCompare(t *testing.T) {
+	changes := dependencies.Compare([]*dependencies.Dependency{
+		{Name: "a", Version: "v1.0.0"},
+		{Name: "b", Version: "v1.2.0"},
+		{Name: "c", Version: "v2.0.0"},
+		{Name: "d", Version: "daa7c04131f5"},
+		{Name: "e", Version: "v1.0.0"},
+	}, []*dependencies.Dependency{
+		{Name: "b", Version: "v1.10.0"},
+		{Name: "c", Version: "v2.0.0-rc.1"},
+		{Name: "d", Version: "0c2fd3b8e9b5"},
+		{Name: "e", Version: "v1.0.0"},
+		{Name: "f", Version: "v0.1.0"},
+	})
+	assert.Equal(t, []*dependencies.Change{
+		{Name: "a", Change: dependencies.Removed, X: "v1.0.0"},
+		{Name: "b", Change: dependencies.Upgraded, X: "v1.2.0", Y: "v1.10.0"},
+		{Name: "c", Change: dependencies.Downgraded, X: "v2.0.0", Y: "v2.0.0-rc.1"},
+		{Name: "d", Change: dependencies.Changed, X: "daa7c04131f5", Y: "0c2fd3b8e9b5"},
+		{Name: "f", Change: dependencies.Added, Y: "v0.1.0"},
+	}, changes)
+	assert.Equal(t, "b: v1.2.0 → v1.10.0", changes[1].String())
+	assert.Equal(t, "f: added v0.1.0", changes[4].String())
+}
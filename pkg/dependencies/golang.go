@@ -0,0 +1,213 @@
+package dependencies
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/repository"
+	"github.com/weaveworks-experiments/imagediff/pkg/semver"
+)
+
+// parseGoMod parses go.mod's requirements, see also:
+// https://golang.org/ref/mod#go-mod-file, along with, for modules before Go
+// 1.17, go.sum's modules which go.mod does not require, i.e. indirect
+// dependencies, as go.mod only lists all of these since Go 1.17. go.sum is
+// otherwise ignored, as it keeps the checksums of modules no longer required.
+// Replacements are ignored.
+func parseGoMod(data []byte, read func(string) []byte) ([]*Dependency, error) {
+	dependencies := []*Dependency{}
+	required := map[string]bool{}
+	goVersion := ""
+	block := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i != -1 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		directive := block
+		switch {
+		case block != "" && fields[0] == ")":
+			block = ""
+			continue
+		case block == "" && len(fields) == 2 && fields[1] == "(":
+			block = fields[0]
+			continue
+		case block == "":
+			// Single line directives, e.g. "require golang.org/x/net v0.1.0":
+			directive, fields = fields[0], fields[1:]
+		}
+		if directive == "go" && len(fields) == 1 {
+			goVersion = fields[0]
+		}
+		if directive != "require" {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid requirement: %v", strings.TrimSpace(line))
+		}
+		name := strings.Trim(fields[0], `"`)
+		dependencies = append(dependencies, &Dependency{Name: name, Version: fields[1]})
+		required[name] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if block != "" {
+		return nil, fmt.Errorf("unterminated %v block", block)
+	}
+	if listsAllRequirements(goVersion) {
+		return dependencies, nil
+	}
+	return append(dependencies, parseGoSum(read("go.sum"), required)...), nil
+}
+
+// listsAllRequirements tells whether go.mod files of the provided go
+// directive's version, e.g. "1.17", list all of their modules' requirements,
+// including indirect ones, i.e. since Go 1.17.
+func listsAllRequirements(goVersion string) bool {
+	parts := strings.SplitN(goVersion, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+	return major > 1 || major == 1 && minor >= 17
+}
+
+// parseGoSum lists the modules go.sum has the checksums of the content of,
+// i.e. the ones built, with their greatest version, as Go's minimal version
+// selection would select, except for the provided ones.
+func parseGoSum(data []byte, except map[string]bool) []*Dependency {
+	versions := map[string]*semver.Version{}
+	names := []string{}
+	dependencies := map[string]string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		// Checksums of go.mod files only are for modules whose requirements were read, but which were not built:
+		if len(fields) != 3 || except[fields[0]] || strings.HasSuffix(fields[1], "/go.mod") {
+			continue
+		}
+		name, version := fields[0], fields[1]
+		v, ok := semver.Parse(version)
+		if !ok {
+			continue
+		}
+		if greatest, seen := versions[name]; seen && !greatest.Less(v) {
+			continue
+		}
+		if _, seen := versions[name]; !seen {
+			names = append(names, name)
+		}
+		versions[name], dependencies[name] = v, version
+	}
+	sort.Strings(names)
+	sum := []*Dependency{}
+	for _, name := range names {
+		sum = append(sum, &Dependency{Name: name, Version: dependencies[name]})
+	}
+	return sum
+}
+
+// parseGopkgLock parses dep's Gopkg.lock, see also:
+// https://golang.github.io/dep/docs/Gopkg.lock.html, listing projects with
+// their versions, if any, or else their revisions.
+func parseGopkgLock(data []byte, _ func(string) []byte) ([]*Dependency, error) {
+	projects, err := tomlTables(data, "projects")
+	if err != nil {
+		return nil, err
+	}
+	dependencies := []*Dependency{}
+	for _, project := range projects {
+		if project["name"] == "" {
+			return nil, fmt.Errorf("invalid project, without name: %v", project)
+		}
+		version := project["version"]
+		if version == "" {
+			version = project["revision"]
+		}
+		dependencies = append(dependencies, &Dependency{Name: project["name"], Version: version})
+	}
+	return dependencies, nil
+}
+
+var (
+	// majorVersionRegex matches the major version suffix of modules' paths, e.g. "/v2".
+	majorVersionRegex = regexp.MustCompile(`/v\d+$`)
+	// gopkgInRegex matches gopkg.in's paths, e.g. "gopkg.in/yaml.v2", or "gopkg.in/src-d/go-git.v4".
+	gopkgInRegex = regexp.MustCompile(`^gopkg\.in/(?:([^/]+)/)?([^/]+)\.v\d+(?:/|$)`)
+	// pseudoVersionRegex matches pseudo-versions' revisions, e.g. "v0.0.0-20191109021931-daa7c04131f5".
+	pseudoVersionRegex = regexp.MustCompile(`\d{14}-([0-9a-f]{12})$`)
+)
+
+// goCompareURL links to the web page comparing the provided versions of the
+// provided module, or project, if hosted on GitHub, GitLab or Bitbucket,
+// or on these behind well known vanity domains, e.g. golang.org/x.
+func goCompareURL(name, x, y string) string {
+	repo, subdir := goRepository(name)
+	if repo == nil {
+		return ""
+	}
+	return repo.CompareURL(goRef(subdir, x), goRef(subdir, y))
+}
+
+// goRepository finds the source code repository of the provided module, and
+// the directory of the module within it, if any.
+func goRepository(module string) (*repository.GitRepository, string) {
+	module = majorVersionRegex.ReplaceAllString(module, "")
+	segments := strings.Split(module, "/")
+	var url string
+	switch {
+	case gopkgInRegex.MatchString(module):
+		matches := gopkgInRegex.FindStringSubmatch(module)
+		user := matches[1]
+		if user == "" {
+			user = "go-" + matches[2]
+		}
+		return &repository.GitRepository{Host: "github.com", Organization: user, Repository: matches[2]}, ""
+	case len(segments) >= 3 && segments[0] == "golang.org" && segments[1] == "x":
+		return &repository.GitRepository{Host: "github.com", Organization: "golang", Repository: segments[2]}, path.Join(segments[3:]...)
+	case len(segments) >= 3 && (segments[0] == "github.com" || segments[0] == "bitbucket.org"):
+		url = strings.Join(segments[:3], "/")
+	case len(segments) >= 3 && segments[0] == "gitlab.com":
+		// GitLab's projects may be nested in subgroups, hence considering the whole path as the project's:
+		url = module
+	default:
+		return nil, ""
+	}
+	repo, err := repository.New("https://" + url)
+	if err != nil {
+		return nil, ""
+	}
+	return repo, strings.TrimPrefix(strings.TrimPrefix(module, url), "/")
+}
+
+// goRef is the Git reference of the provided version, of a module in the
+// provided directory of its repository, i.e. the revision of pseudo-versions,
+// or the tag of others, prefixed with the module's directory, if any.
+func goRef(subdir, version string) string {
+	version = strings.TrimSuffix(version, "+incompatible")
+	if matches := pseudoVersionRegex.FindStringSubmatch(version); matches != nil {
+		return matches[1]
+	}
+	if _, ok := semver.Parse(version); ok && subdir != "" {
+		return subdir + "/" + version
+	}
+	return version
+}
@@ -0,0 +1,204 @@
+package dependencies_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/dependencies"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+const goModX = `module github.com/weaveworks-experiments/imagediff
+
+go 1.13
+
+require (
+	github.com/pkg/errors v0.8.1
+	github.com/sirupsen/logrus v1.4.2 // indirect
+	golang.org/x/crypto v0.0.0-20191011191535-87dc89f01550
+	gopkg.in/yaml.v2 v2.2.4
+)
+
+require github.com/gorilla/mux v1.7.3
+
+replace github.com/pkg/errors => github.com/pkg/errors v0.8.0
+`
+
+const goModY = `module github.com/weaveworks-experiments/imagediff
+
+go 1.14
+
+require (
+	github.com/pkg/errors v0.9.1
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
+	gopkg.in/yaml.v2 v2.3.0
+	github.com/aws/aws-sdk-go-v2/service/s3 v1.2.0
+	gitlab.com/group/subgroup/project v1.0.1
+)
+
+require github.com/gorilla/mux v1.7.3
+`
+
+const goSumX = `github.com/gorilla/mux v1.7.3 h1:gnP5JzjVOuiZD07fKKToCAOjS0yOpj/qPETTXCCS6hw=
+github.com/gorilla/mux v1.7.3/go.mod h1:1lud6UwP+6orDFRuTfBEV8e9/aOM/c4fVVCaMa2zaAs=
+github.com/konsorten/go-windows-terminal-sequences v1.0.1 h1:mweAR1A6xJ3oS2pRaGiHgQ4OO8tzTaLawm8vnODuwDk=
+github.com/konsorten/go-windows-terminal-sequences v1.0.1/go.mod h1:T0+1ngSBFLxvqU3pZ+m/2kptfBszLMUkC4ZK/EgS/cQ=
+github.com/stretchr/testify v1.2.2/go.mod h1:a8OnRcib4nhh0OaRAV+Yts87kKdq0PP7pXfy6kDkUVs=
+`
+
+const goSumY = `github.com/gorilla/mux v1.7.3 h1:gnP5JzjVOuiZD07fKKToCAOjS0yOpj/qPETTXCCS6hw=
+github.com/konsorten/go-windows-terminal-sequences v1.0.1 h1:mweAR1A6xJ3oS2pRaGiHgQ4OO8tzTaLawm8vnODuwDk=
+github.com/konsorten/go-windows-terminal-sequences v1.0.3 h1:CE8S1cTafDpPvMhIxNJKvHsGVBgn1xWYf1NbHQhywc8=
+github.com/konsorten/go-windows-terminal-sequences v1.0.3/go.mod h1:T0+1ngSBFLxvqU3pZ+m/2kptfBszLMUkC4ZK/EgS/cQ=
+`
+
+const gopkgLockX = `# This file is autogenerated, do not edit; changes may be undone by the next 'dep ensure'.
+
+
+[[projects]]
+  digest = "1:5e8b9e4f6a0f83d2e7b7f1d0c3d28f7d02a6a5e2a7c0df4ef1e7c3b2b3a0e1d4"
+  name = "github.com/docker/go-units"
+  packages = ["."]
+  pruneopts = "UT"
+  revision = "47565b4f722fb6ceae66b95f853feed578a4a51c"
+  version = "v0.3.3"
+
+[[projects]]
+  branch = "master"
+  name = "golang.org/x/net"
+  packages = [
+    "context",
+    "proxy",
+  ]
+  revision = "1c05540f6879653db88113bc4a2b70aec4bd491f"
+
+[solve-meta]
+  analyzer-name = "dep"
+  input-imports = ["github.com/docker/go-units"]
+`
+
+const gopkgLockY = `[[projects]]
+  name = "github.com/docker/go-units"
+  packages = ["."]
+  revision = "519db1ee28dcc9fd2474ae59fca29a810482bfb1"
+  version = "v0.4.0"
+
+[[projects]]
+  branch = "master"
+  name = "golang.org/x/net"
+  packages = ["context"]
+  revision = "d3edc9973b7eb1fb302b0ff2c62357091cea9a30"
+
+[solve-meta]
+  analyzer-name = "dep"
+`
+
+func TestDiffGo(t *testing.T) {
+	storage := memory.NewStorage()
+	x := commit(t, storage, map[string]string{
+		"go.mod":                       goModX,
+		"go.sum":                       goSumX,
+		"Gopkg.lock":                   gopkgLockX,
+		"tools/go.mod":                 "module tools\n\nrequire golang.org/x/tools v0.0.1\n",
+		"vendor/github.com/x/y/go.mod": "module github.com/x/y\n\nrequire golang.org/x/text v0.3.0\n",
+		"README.md":                    "imagediff\n",
+	})
+	y := commit(t, storage, map[string]string{
+		"go.mod":                       goModY,
+		"go.sum":                       goSumY,
+		"Gopkg.lock":                   gopkgLockY,
+		"tools/go.mod":                 "module tools\n\nrequire golang.org/x/tools v0.0.1\n",
+		"vendor/github.com/x/y/go.mod": "module github.com/x/y\n\nrequire golang.org/x/text v0.3.2\n",
+		"README.md":                    "imagediff\nDiffs images.\n",
+	})
+
+	changes, err := dependencies.Diff(x, y)
+	assert.NoError(t, err)
+	assert.Equal(t, []*dependencies.Change{
+		{Path: "Gopkg.lock", Ecosystem: dependencies.Go, Name: "github.com/docker/go-units", Change: dependencies.Upgraded, X: "v0.3.3", Y: "v0.4.0",
+			CompareURL: "https://github.com/docker/go-units/compare/v0.3.3...v0.4.0"},
+		{Path: "Gopkg.lock", Ecosystem: dependencies.Go, Name: "golang.org/x/net", Change: dependencies.Changed, X: "1c05540f6879653db88113bc4a2b70aec4bd491f", Y: "d3edc9973b7eb1fb302b0ff2c62357091cea9a30",
+			CompareURL: "https://github.com/golang/net/compare/1c05540f6879653db88113bc4a2b70aec4bd491f...d3edc9973b7eb1fb302b0ff2c62357091cea9a30"},
+		{Path: "go.mod", Ecosystem: dependencies.Go, Name: "github.com/aws/aws-sdk-go-v2/service/s3", Change: dependencies.Added, Y: "v1.2.0"},
+		{Path: "go.mod", Ecosystem: dependencies.Go, Name: "github.com/konsorten/go-windows-terminal-sequences", Change: dependencies.Upgraded, X: "v1.0.1", Y: "v1.0.3",
+			CompareURL: "https://github.com/konsorten/go-windows-terminal-sequences/compare/v1.0.1...v1.0.3"},
+		{Path: "go.mod", Ecosystem: dependencies.Go, Name: "github.com/pkg/errors", Change: dependencies.Upgraded, X: "v0.8.1", Y: "v0.9.1",
+			CompareURL: "https://github.com/pkg/errors/compare/v0.8.1...v0.9.1"},
+		{Path: "go.mod", Ecosystem: dependencies.Go, Name: "github.com/sirupsen/logrus", Change: dependencies.Removed, X: "v1.4.2"},
+		{Path: "go.mod", Ecosystem: dependencies.Go, Name: "gitlab.com/group/subgroup/project", Change: dependencies.Added, Y: "v1.0.1"},
+		{Path: "go.mod", Ecosystem: dependencies.Go, Name: "golang.org/x/crypto", Change: dependencies.Upgraded, X: "v0.0.0-20191011191535-87dc89f01550", Y: "v0.0.0-20200622213623-75b288015ac9",
+			CompareURL: "https://github.com/golang/crypto/compare/87dc89f01550...75b288015ac9"},
+		{Path: "go.mod", Ecosystem: dependencies.Go, Name: "gopkg.in/yaml.v2", Change: dependencies.Upgraded, X: "v2.2.4", Y: "v2.3.0",
+			CompareURL: "https://github.com/go-yaml/yaml/compare/v2.2.4...v2.3.0"},
+	}, changes)
+}
+
+func TestDiffGoIgnoresGoSumSince117(t *testing.T) {
+	storage := memory.NewStorage()
+	x := commit(t, storage, map[string]string{
+		"go.mod": "module github.com/weaveworks-experiments/imagediff\n\ngo 1.17\n\nrequire github.com/pkg/errors v0.8.1\n",
+		"go.sum": goSumX,
+	})
+	y := commit(t, storage, map[string]string{
+		"go.mod": "module github.com/weaveworks-experiments/imagediff\n\ngo 1.21.0\n\nrequire github.com/pkg/errors v0.9.1\n",
+		"go.sum": goSumY,
+	})
+
+	changes, err := dependencies.Diff(x, y)
+	assert.NoError(t, err)
+	assert.Equal(t, []*dependencies.Change{
+		{Path: "go.mod", Ecosystem: dependencies.Go, Name: "github.com/pkg/errors", Change: dependencies.Upgraded, X: "v0.8.1", Y: "v0.9.1",
+			CompareURL: "https://github.com/pkg/errors/compare/v0.8.1...v0.9.1"},
+	}, changes)
+}
+
+func TestDiffGoCompareURLs(t *testing.T) {
+	storage := memory.NewStorage()
+	x := commit(t, storage, map[string]string{"go.mod": `module m
+
+require (
+	github.com/aws/aws-sdk-go-v2/service/s3 v1.2.0
+	github.com/go-redis/redis/v8 v8.0.0
+	gitlab.com/group/subgroup/project v1.0.1
+	gopkg.in/src-d/go-git.v4 v4.13.0
+	github.com/docker/docker v1.13.1+incompatible
+	example.com/vanity v1.0.0
+)
+`})
+	y := commit(t, storage, map[string]string{"go.mod": `module m
+
+require (
+	github.com/aws/aws-sdk-go-v2/service/s3 v1.3.0
+	github.com/go-redis/redis/v8 v8.1.0
+	gitlab.com/group/subgroup/project v1.1.0
+	gopkg.in/src-d/go-git.v4 v4.13.1
+	github.com/docker/docker v17.12.0-ce-rc1.0.20200618181300-9dc6525e6118+incompatible
+	example.com/vanity v1.1.0
+)
+`})
+	changes, err := dependencies.Diff(x, y)
+	assert.NoError(t, err)
+	urls := map[string]string{}
+	for _, change := range changes {
+		urls[change.Name] = change.CompareURL
+	}
+	assert.Equal(t, map[string]string{
+		"example.com/vanity":                      "",
+		"github.com/aws/aws-sdk-go-v2/service/s3": "https://github.com/aws/aws-sdk-go-v2/compare/service/s3/v1.2.0...service/s3/v1.3.0",
+		"github.com/docker/docker":                "https://github.com/docker/docker/compare/v1.13.1...9dc6525e6118",
+		"github.com/go-redis/redis/v8":            "https://github.com/go-redis/redis/compare/v8.0.0...v8.1.0",
+		"gitlab.com/group/subgroup/project":       "https://gitlab.com/group/subgroup/project/-/compare/v1.0.1...v1.1.0",
+		"gopkg.in/src-d/go-git.v4":                "https://github.com/src-d/go-git/compare/v4.13.0...v4.13.1",
+	}, urls)
+}
+
+func TestDiffSkipsInvalidManifests(t *testing.T) {
+	storage := memory.NewStorage()
+	x := commit(t, storage, map[string]string{"go.mod": "module m\n\nrequire (\n\tgithub.com/pkg/errors v0.8.1\n)\n", "a/go.mod": "module a\n"})
+	y := commit(t, storage, map[string]string{"go.mod": "module m\n\nrequire (\n\tgithub.com/pkg/errors\n)\n", "a/go.mod": "module a\n\nrequire github.com/pkg/errors v0.9.1\n"})
+	changes, err := dependencies.Diff(x, y)
+	assert.NoError(t, err)
+	assert.Equal(t, []*dependencies.Change{
+		{Path: "a/go.mod", Ecosystem: dependencies.Go, Name: "github.com/pkg/errors", Change: dependencies.Added, Y: "v0.9.1"},
+	}, changes)
+}
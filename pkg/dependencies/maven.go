@@ -0,0 +1,63 @@
+package dependencies
+
+import (
+	"encoding/xml"
+	"regexp"
+)
+
+// pom is Maven's pom.xml, see also: https://maven.apache.org/pom.html
+type pom struct {
+	Version string `xml:"version"`
+	Parent  struct {
+		Version string `xml:"version"`
+	} `xml:"parent"`
+	Properties struct {
+		Properties []struct {
+			XMLName xml.Name
+			Value   string `xml:",chardata"`
+		} `xml:",any"`
+	} `xml:"properties"`
+	Dependencies        []*pomDependency `xml:"dependencies>dependency"`
+	ManagedDependencies []*pomDependency `xml:"dependencyManagement>dependencies>dependency"`
+}
+
+type pomDependency struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version"`
+}
+
+var pomPropertyRegex = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// parsePom parses Maven's pom.xml, listing its dependencies, and the ones it
+// manages the versions of, by "groupId:artifactId", with their versions, if
+// any, resolving properties defined by the pom.xml itself, e.g.
+// "${jackson.version}". Parent POMs, and therefore the dependencies these
+// define, are not read.
+func parsePom(data []byte, _ func(string) []byte) ([]*Dependency, error) {
+	p := &pom{}
+	if err := xml.Unmarshal(data, p); err != nil {
+		return nil, err
+	}
+	properties := map[string]string{
+		"project.version":        p.Version,
+		"project.parent.version": p.Parent.Version,
+	}
+	if p.Version == "" {
+		properties["project.version"] = p.Parent.Version
+	}
+	for _, property := range p.Properties.Properties {
+		properties[property.XMLName.Local] = property.Value
+	}
+	dependencies := []*Dependency{}
+	for _, d := range append(p.Dependencies, p.ManagedDependencies...) {
+		version := pomPropertyRegex.ReplaceAllStringFunc(d.Version, func(reference string) string {
+			if value, ok := properties[reference[2:len(reference)-1]]; ok {
+				return value
+			}
+			return reference
+		})
+		dependencies = append(dependencies, &Dependency{Name: d.GroupID + ":" + d.ArtifactID, Version: version})
+	}
+	return dependencies, nil
+}
@@ -0,0 +1,98 @@
+package dependencies_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/dependencies"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+const pomX = `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <modelVersion>4.0.0</modelVersion>
+  <parent>
+    <groupId>org.springframework.boot</groupId>
+    <artifactId>spring-boot-starter-parent</artifactId>
+    <version>2.2.7.RELEASE</version>
+  </parent>
+  <groupId>com.example</groupId>
+  <artifactId>app</artifactId>
+  <version>1.0.0</version>
+  <properties>
+    <jackson.version>2.10.4</jackson.version>
+  </properties>
+  <dependencies>
+    <dependency>
+      <groupId>org.springframework.boot</groupId>
+      <artifactId>spring-boot-starter-web</artifactId>
+    </dependency>
+    <dependency>
+      <groupId>com.fasterxml.jackson.core</groupId>
+      <artifactId>jackson-databind</artifactId>
+      <version>${jackson.version}</version>
+    </dependency>
+    <dependency>
+      <groupId>com.example</groupId>
+      <artifactId>common</artifactId>
+      <version>${project.version}</version>
+    </dependency>
+  </dependencies>
+</project>
+`
+
+const pomY = `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <modelVersion>4.0.0</modelVersion>
+  <groupId>com.example</groupId>
+  <artifactId>app</artifactId>
+  <version>1.1.0</version>
+  <properties>
+    <jackson.version>2.11.1</jackson.version>
+  </properties>
+  <dependencyManagement>
+    <dependencies>
+      <dependency>
+        <groupId>org.springframework.boot</groupId>
+        <artifactId>spring-boot-dependencies</artifactId>
+        <version>2.3.1.RELEASE</version>
+      </dependency>
+    </dependencies>
+  </dependencyManagement>
+  <dependencies>
+    <dependency>
+      <groupId>org.springframework.boot</groupId>
+      <artifactId>spring-boot-starter-web</artifactId>
+    </dependency>
+    <dependency>
+      <groupId>com.fasterxml.jackson.core</groupId>
+      <artifactId>jackson-databind</artifactId>
+      <version>${jackson.version}</version>
+    </dependency>
+    <dependency>
+      <groupId>com.example</groupId>
+      <artifactId>common</artifactId>
+      <version>${project.version}</version>
+    </dependency>
+    <dependency>
+      <groupId>io.micrometer</groupId>
+      <artifactId>micrometer-registry-prometheus</artifactId>
+      <version>${micrometer.version}</version>
+    </dependency>
+  </dependencies>
+</project>
+`
+
+func TestDiffMaven(t *testing.T) {
+	storage := memory.NewStorage()
+	x := commit(t, storage, map[string]string{"pom.xml": pomX})
+	y := commit(t, storage, map[string]string{"pom.xml": pomY})
+	changes, err := dependencies.Diff(x, y)
+	assert.NoError(t, err)
+	assert.Equal(t, []*dependencies.Change{
+		{Path: "pom.xml", Ecosystem: dependencies.Maven, Name: "com.example:common", Change: dependencies.Upgraded, X: "1.0.0", Y: "1.1.0"},
+		{Path: "pom.xml", Ecosystem: dependencies.Maven, Name: "com.fasterxml.jackson.core:jackson-databind", Change: dependencies.Upgraded, X: "2.10.4", Y: "2.11.1"},
+		{Path: "pom.xml", Ecosystem: dependencies.Maven, Name: "io.micrometer:micrometer-registry-prometheus", Change: dependencies.Added, Y: "${micrometer.version}"},
+		{Path: "pom.xml", Ecosystem: dependencies.Maven, Name: "org.springframework.boot:spring-boot-dependencies", Change: dependencies.Added, Y: "2.3.1.RELEASE"},
+	}, changes)
+}
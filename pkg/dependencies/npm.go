@@ -0,0 +1,91 @@
+package dependencies
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// packageLock is npm's package-lock.json, see also:
+// https://docs.npmjs.com/cli/configuring-npm/package-lock-json
+type packageLock struct {
+	// Packages lists packages by path, e.g. "node_modules/a/node_modules/b", from lockfileVersion 2.
+	Packages map[string]*struct {
+		Version string `json:"version"`
+		Link    bool   `json:"link"`
+	} `json:"packages"`
+	// Dependencies lists packages by name, nested in the packages depending on these, up to lockfileVersion 2.
+	Dependencies map[string]*packageLockDependency `json:"dependencies"`
+}
+
+type packageLockDependency struct {
+	Version      string                            `json:"version"`
+	Dependencies map[string]*packageLockDependency `json:"dependencies"`
+}
+
+// parsePackageLock parses npm's package-lock.json, listing all installed
+// packages, i.e. including transitive ones, some of which may be installed
+// with several versions.
+func parsePackageLock(data []byte, _ func(string) []byte) ([]*Dependency, error) {
+	lock := &packageLock{}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, err
+	}
+	dependencies := []*Dependency{}
+	if lock.Packages != nil {
+		for path, p := range lock.Packages {
+			const nodeModules = "node_modules/"
+			i := strings.LastIndex(path, nodeModules)
+			// The root package, and workspaces, are not dependencies:
+			if i == -1 || p.Link || p.Version == "" {
+				continue
+			}
+			dependencies = append(dependencies, &Dependency{Name: path[i+len(nodeModules):], Version: p.Version})
+		}
+		return dependencies, nil
+	}
+	var add func(map[string]*packageLockDependency)
+	add = func(nested map[string]*packageLockDependency) {
+		for name, d := range nested {
+			dependencies = append(dependencies, &Dependency{Name: name, Version: d.Version})
+			add(d.Dependencies)
+		}
+	}
+	add(lock.Dependencies)
+	return dependencies, nil
+}
+
+// parseYarnLock parses Yarn's yarn.lock, both Yarn 1's, and Yarn 2's, which
+// is YAML, listing all installed packages, each preceded by the ranges of
+// versions these satisfy, e.g.:
+//
+//	"@babel/code-frame@^7.0.0", "@babel/code-frame@^7.8.3":
+//	  version "7.8.3"
+func parseYarnLock(data []byte, _ func(string) []byte) ([]*Dependency, error) {
+	dependencies := []*Dependency{}
+	name := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		switch {
+		case strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#"):
+			continue
+		case !strings.HasPrefix(line, " "):
+			name = yarnName(strings.TrimSuffix(line, ":"))
+		case name != "" && strings.HasPrefix(line, "  version"):
+			version := strings.TrimPrefix(strings.TrimPrefix(line, "  version"), ":")
+			dependencies = append(dependencies, &Dependency{Name: name, Version: strings.Trim(strings.TrimSpace(version), `"`)})
+			name = ""
+		}
+	}
+	return dependencies, nil
+}
+
+// yarnName extracts the name of the package from the provided entry's header,
+// e.g. "@babel/code-frame" from `"@babel/code-frame@^7.0.0", "@babel/code-frame@^7.8.3"`,
+// or "" for entries which are not packages, e.g. Yarn 2's metadata, or workspaces.
+func yarnName(header string) string {
+	spec := strings.Trim(strings.TrimSpace(strings.Split(header, ",")[0]), `"`)
+	i := strings.LastIndex(spec, "@")
+	if i <= 0 || strings.Contains(spec[i:], "@workspace:") {
+		return ""
+	}
+	return spec[:i]
+}
@@ -0,0 +1,131 @@
+package dependencies_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/dependencies"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+const packageLockV1 = `{
+  "name": "app",
+  "version": "1.0.0",
+  "lockfileVersion": 1,
+  "requires": true,
+  "dependencies": {
+    "debug": {
+      "version": "2.6.9",
+      "requires": {"ms": "2.0.0"},
+      "dependencies": {
+        "ms": {"version": "2.0.0"}
+      }
+    },
+    "express": {"version": "4.17.1"},
+    "lodash": {"version": "4.17.15"},
+    "ms": {"version": "2.1.2"}
+  }
+}
+`
+
+const packageLockV2 = `{
+  "name": "app",
+  "version": "1.0.1",
+  "lockfileVersion": 2,
+  "packages": {
+    "": {"name": "app", "version": "1.0.1"},
+    "node_modules/@babel/code-frame": {"version": "7.10.4"},
+    "node_modules/debug": {"version": "2.6.9"},
+    "node_modules/debug/node_modules/ms": {"version": "2.0.0"},
+    "node_modules/lodash": {"version": "4.17.19"},
+    "node_modules/ms": {"version": "2.1.2"},
+    "node_modules/shared": {"resolved": "packages/shared", "link": true},
+    "packages/shared": {"version": "0.1.0"}
+  },
+  "dependencies": {
+    "lodash": {"version": "4.17.19"}
+  }
+}
+`
+
+const yarnLockV1 = `# THIS IS AN AUTOGENERATED FILE. DO NOT EDIT THIS FILE DIRECTLY.
+# yarn lockfile v1
+
+
+"@babel/code-frame@^7.0.0", "@babel/code-frame@^7.8.3":
+  version "7.8.3"
+  resolved "https://registry.yarnpkg.com/@babel/code-frame/-/code-frame-7.8.3.tgz#33e25903d7481181534e12ec0a25f16b6fcf419e"
+  dependencies:
+    "@babel/highlight" "^7.8.3"
+
+left-pad@^1.3.0:
+  version "1.3.0"
+`
+
+const yarnLockV2 = `# This file is generated by running "yarn install" inside your project.
+
+__metadata:
+  version: 4
+  cacheKey: 7
+
+"@babel/code-frame@npm:^7.0.0, @babel/code-frame@npm:^7.10.4":
+  version: 7.10.4
+  resolution: "@babel/code-frame@npm:7.10.4"
+  dependencies:
+    "@babel/highlight": ^7.10.4
+
+"app@workspace:.":
+  version: 0.0.0-use.local
+  resolution: "app@workspace:."
+`
+
+func TestDiffNpm(t *testing.T) {
+	storage := memory.NewStorage()
+	x := commit(t, storage, map[string]string{
+		"package-lock.json":           packageLockV1,
+		"web/yarn.lock":               yarnLockV1,
+		"node_modules/a/yarn.lock":    yarnLockV1,
+		"web/node_modules/.yarn-rc":   "",
+		"web/package.json":            `{"name": "web"}`,
+		"lib/package-lock.json":       `{"lockfileVersion": 1}`,
+		"broken/package-lock.json":    `{}`,
+		"unchanged/package-lock.json": packageLockV1,
+	})
+	y := commit(t, storage, map[string]string{
+		"package-lock.json":           packageLockV2,
+		"web/yarn.lock":               yarnLockV2,
+		"node_modules/a/yarn.lock":    yarnLockV2,
+		"web/package.json":            `{"name": "web"}`,
+		"broken/package-lock.json":    `{`,
+		"unchanged/package-lock.json": packageLockV1,
+	})
+	changes, err := dependencies.Diff(x, y)
+	assert.NoError(t, err)
+	assert.Equal(t, []*dependencies.Change{
+		{Path: "package-lock.json", Ecosystem: dependencies.Npm, Name: "@babel/code-frame", Change: dependencies.Added, Y: "7.10.4"},
+		{Path: "package-lock.json", Ecosystem: dependencies.Npm, Name: "express", Change: dependencies.Removed, X: "4.17.1"},
+		{Path: "package-lock.json", Ecosystem: dependencies.Npm, Name: "lodash", Change: dependencies.Upgraded, X: "4.17.15", Y: "4.17.19"},
+		{Path: "web/yarn.lock", Ecosystem: dependencies.Npm, Name: "@babel/code-frame", Change: dependencies.Upgraded, X: "7.8.3", Y: "7.10.4"},
+		{Path: "web/yarn.lock", Ecosystem: dependencies.Npm, Name: "left-pad", Change: dependencies.Removed, X: "1.3.0"},
+	}, changes)
+}
+
+func TestDiffNpmWithSeveralVersions(t *testing.T) {
+	storage := memory.NewStorage()
+	x := commit(t, storage, map[string]string{"package-lock.json": packageLockV1})
+	y := commit(t, storage, map[string]string{"package-lock.json": `{
+  "lockfileVersion": 1,
+  "dependencies": {
+    "debug": {"version": "2.6.9"},
+    "ms": {"version": "2.1.2"}
+  }
+}
+`})
+	changes, err := dependencies.Diff(x, y)
+	assert.NoError(t, err)
+	assert.Equal(t, []*dependencies.Change{
+		{Path: "package-lock.json", Ecosystem: dependencies.Npm, Name: "express", Change: dependencies.Removed, X: "4.17.1"},
+		{Path: "package-lock.json", Ecosystem: dependencies.Npm, Name: "lodash", Change: dependencies.Removed, X: "4.17.15"},
+		{Path: "package-lock.json", Ecosystem: dependencies.Npm, Name: "ms", Change: dependencies.Changed, X: "2.0.0, 2.1.2", Y: "2.1.2"},
+	}, changes)
+}
@@ -0,0 +1,61 @@
+package dependencies
+
+import (
+	"regexp"
+	"strings"
+)
+
+// requirementRegex matches requirements, e.g. "requests[security]==2.24.0 ; python_version > '3'",
+// see also: https://pip.pypa.io/en/stable/reference/requirements-file-format/
+var requirementRegex = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)\s*(?:\[[^\]]*\])?\s*([^;#]*)`)
+
+// parseRequirements parses pip's requirements.txt, listing packages with their
+// pinned versions, e.g. "2.24.0" for "requests==2.24.0", or else their
+// version specifiers, if any, e.g. ">=2.0,<3". Options, e.g. "-r
+// other.txt", and requirements which are not packages' names, e.g. URLs, are
+// ignored.
+func parseRequirements(data []byte, _ func(string) []byte) ([]*Dependency, error) {
+	dependencies := []*Dependency{}
+	text := strings.Replace(string(data), "\\\n", " ", -1)
+	for _, line := range strings.Split(text, "\n") {
+		if i := strings.Index(line, " #"); i != -1 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		// Hashes, e.g. "--hash=sha256:...", follow requirements:
+		if i := strings.Index(line, " --"); i != -1 {
+			line = line[:i]
+		}
+		matches := requirementRegex.FindStringSubmatch(line)
+		if matches == nil || strings.Contains(matches[0], "://") {
+			continue
+		}
+		version := strings.Replace(strings.TrimSpace(matches[2]), " ", "", -1)
+		if strings.HasPrefix(version, "==") && !strings.Contains(version, ",") {
+			version = strings.TrimPrefix(version, "==")
+		}
+		dependencies = append(dependencies, &Dependency{Name: pythonName(matches[1]), Version: version})
+	}
+	return dependencies, nil
+}
+
+// parsePoetryLock parses Poetry's poetry.lock, listing all installed packages.
+func parsePoetryLock(data []byte, _ func(string) []byte) ([]*Dependency, error) {
+	packages, err := tomlTables(data, "package")
+	if err != nil {
+		return nil, err
+	}
+	dependencies := []*Dependency{}
+	for _, p := range packages {
+		dependencies = append(dependencies, &Dependency{Name: pythonName(p["name"]), Version: p["version"]})
+	}
+	return dependencies, nil
+}
+
+var pythonSeparatorsRegex = regexp.MustCompile(`[-_.]+`)
+
+// pythonName normalises the provided package's name, as packages' names are
+// case insensitive, and consider "-", "_" and "." equal, see also: PEP 503.
+func pythonName(name string) string {
+	return strings.ToLower(pythonSeparatorsRegex.ReplaceAllString(name, "-"))
+}
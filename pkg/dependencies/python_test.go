@@ -0,0 +1,78 @@
+package dependencies_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/dependencies"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+const requirementsX = `# Production dependencies
+-r base.txt
+--index-url https://pypi.example.com/simple
+Django==2.2.13
+requests[security]==2.23.0 ; python_version > "3"
+celery>=4.0,<5
+PyYAML==5.3 \
+    --hash=sha256:0113bc0ec2ad727182326b61326afa3d1d8280ae1122493553fd6f4397f33df9
+git+https://github.com/org/lib.git#egg=lib
+-e .
+`
+
+const requirementsY = `Django==3.0.7  # LTS
+requests[security]==2.24.0 ; python_version > "3"
+celery>=4.4,<5
+pyyaml==5.3 \
+    --hash=sha256:0113bc0ec2ad727182326b61326afa3d1d8280ae1122493553fd6f4397f33df9
+gunicorn
+`
+
+const poetryLockX = `[[package]]
+category = "main"
+description = "HTTP library"
+name = "Requests"
+optional = false
+python-versions = ">=2.7"
+version = "2.23.0"
+
+[package.dependencies]
+idna = ">=2.5,<3"
+
+[package.extras]
+security = ["pyOpenSSL (>=0.14)"]
+
+[[package]]
+name = "idna"
+version = "2.9"
+
+[metadata]
+content-hash = "abc"
+python-versions = "^3.8"
+`
+
+const poetryLockY = `[[package]]
+name = "requests"
+version = "2.24.0"
+
+[[package]]
+name = "idna"
+version = "2.10"
+`
+
+func TestDiffPython(t *testing.T) {
+	storage := memory.NewStorage()
+	x := commit(t, storage, map[string]string{"requirements.txt": requirementsX, "api/poetry.lock": poetryLockX})
+	y := commit(t, storage, map[string]string{"requirements.txt": requirementsY, "api/poetry.lock": poetryLockY})
+	changes, err := dependencies.Diff(x, y)
+	assert.NoError(t, err)
+	assert.Equal(t, []*dependencies.Change{
+		{Path: "api/poetry.lock", Ecosystem: dependencies.PyPI, Name: "idna", Change: dependencies.Upgraded, X: "2.9", Y: "2.10"},
+		{Path: "api/poetry.lock", Ecosystem: dependencies.PyPI, Name: "requests", Change: dependencies.Upgraded, X: "2.23.0", Y: "2.24.0"},
+		{Path: "requirements.txt", Ecosystem: dependencies.PyPI, Name: "celery", Change: dependencies.Changed, X: ">=4.0,<5", Y: ">=4.4,<5"},
+		{Path: "requirements.txt", Ecosystem: dependencies.PyPI, Name: "django", Change: dependencies.Upgraded, X: "2.2.13", Y: "3.0.7"},
+		{Path: "requirements.txt", Ecosystem: dependencies.PyPI, Name: "gunicorn", Change: dependencies.Added},
+		{Path: "requirements.txt", Ecosystem: dependencies.PyPI, Name: "requests", Change: dependencies.Upgraded, X: "2.23.0", Y: "2.24.0"},
+	}, changes)
+	assert.Equal(t, "gunicorn: added", changes[4].String())
+}
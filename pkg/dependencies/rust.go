@@ -0,0 +1,20 @@
+package dependencies
+
+// parseCargoLock parses Cargo's Cargo.lock, listing all packages, i.e.
+// including transitive ones, some of which may be listed with several
+// versions, and the workspace's own packages.
+func parseCargoLock(data []byte, _ func(string) []byte) ([]*Dependency, error) {
+	packages, err := tomlTables(data, "package")
+	if err != nil {
+		return nil, err
+	}
+	dependencies := []*Dependency{}
+	for _, p := range packages {
+		// The workspace's own packages have no source:
+		if p["source"] == "" {
+			continue
+		}
+		dependencies = append(dependencies, &Dependency{Name: p["name"], Version: p["version"]})
+	}
+	return dependencies, nil
+}
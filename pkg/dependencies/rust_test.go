@@ -0,0 +1,67 @@
+package dependencies_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/dependencies"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+const cargoLockX = `# This file is automatically @generated by Cargo.
+# It is not intended for manual editing.
+[[package]]
+name = "app"
+version = "0.1.0"
+dependencies = [
+ "serde 1.0.110 (registry+https://github.com/rust-lang/crates.io-index)",
+]
+
+[[package]]
+name = "rand"
+version = "0.6.5"
+source = "registry+https://github.com/rust-lang/crates.io-index"
+
+[[package]]
+name = "rand"
+version = "0.7.3"
+source = "registry+https://github.com/rust-lang/crates.io-index"
+
+[[package]]
+name = "serde"
+version = "1.0.110"
+source = "registry+https://github.com/rust-lang/crates.io-index"
+
+[metadata]
+"checksum serde 1.0.110 (registry+https://github.com/rust-lang/crates.io-index)" = "99e7b308464d16b56eba9964e4972a3eee817760ab60d88c3f86e1fecb08204c"
+`
+
+const cargoLockY = `version = 3
+
+[[package]]
+name = "app"
+version = "0.2.0"
+
+[[package]]
+name = "rand"
+version = "0.7.3"
+source = "registry+https://github.com/rust-lang/crates.io-index"
+
+[[package]]
+name = "serde"
+version = "1.0.114"
+source = "registry+https://github.com/rust-lang/crates.io-index"
+checksum = "5317f7588f0a5078ee60ef675ef96735a1442132dc645eb1d12c018620ed8cd3"
+`
+
+func TestDiffCargo(t *testing.T) {
+	storage := memory.NewStorage()
+	x := commit(t, storage, map[string]string{"Cargo.lock": cargoLockX})
+	y := commit(t, storage, map[string]string{"Cargo.lock": cargoLockY})
+	changes, err := dependencies.Diff(x, y)
+	assert.NoError(t, err)
+	assert.Equal(t, []*dependencies.Change{
+		{Path: "Cargo.lock", Ecosystem: dependencies.Cargo, Name: "rand", Change: dependencies.Changed, X: "0.6.5, 0.7.3", Y: "0.7.3"},
+		{Path: "Cargo.lock", Ecosystem: dependencies.Cargo, Name: "serde", Change: dependencies.Upgraded, X: "1.0.110", Y: "1.0.114"},
+	}, changes)
+}
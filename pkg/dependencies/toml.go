@@ -0,0 +1,39 @@
+package dependencies
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tomlTables reads the string values of the provided array of tables, e.g.
+// "package" for "[[package]]", in the provided TOML document, as lock files
+// use, e.g. Cargo.lock. Other values, e.g. arrays, or inline tables, are
+// ignored, as are nested tables, e.g. "[package.extras]".
+func tomlTables(data []byte, name string) ([]map[string]string, error) {
+	tables := []map[string]string{}
+	var table map[string]string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			table = nil
+			if line == "[["+name+"]]" {
+				table = map[string]string{}
+				tables = append(tables, table)
+			}
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if table == nil || len(parts) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		if !strings.HasPrefix(value, `"`) {
+			continue
+		}
+		if len(value) < 2 || !strings.HasSuffix(value, `"`) {
+			return nil, fmt.Errorf("invalid string on line %v: %v", i+1, value)
+		}
+		table[strings.Trim(strings.TrimSpace(parts[0]), `"`)] = strings.Replace(value[1:len(value)-1], `\"`, `"`, -1)
+	}
+	return tables, nil
+}
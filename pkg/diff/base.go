@@ -0,0 +1,124 @@
+package diff
+
+import (
+	"context"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	log "github.com/sirupsen/logrus"
+	"github.com/weaveworks-experiments/imagediff/pkg/metrics"
+)
+
+// Labels naming images' base images, as per the OCI image specification's
+// pre-defined annotations, which builders may also set as labels.
+const (
+	baseNameLabel   = "org.opencontainers.image.base.name"
+	baseDigestLabel = "org.opencontainers.image.base.digest"
+)
+
+// maxBaseDepth bounds how many base images deep diffs go, e.g. in case of
+// images mislabelled as being their own bases.
+const maxBaseDepth = 5
+
+// baseDiff diffs the base images of the provided images, if found, and if
+// these changed, or returns nil otherwise. This only warns on failures, as the
+// provided images' diff is still worth reporting.
+func baseDiff(ctx context.Context, docker *client.Client, x, y *types.ImageInspect, options *Options, depth int) *Result {
+	var candidates []*Candidate
+	if baseLabel(x) == "" || baseLabel(y) == "" {
+		candidates = inspectCandidates(ctx, docker, options)
+	}
+	xBase, err := BaseImage(x, candidates)
+	if err != nil {
+		log.WithField("image", x.ID).Warnf("failed to find base image: %v", err)
+		return nil
+	}
+	yBase, err := BaseImage(y, candidates)
+	if err != nil {
+		log.WithField("image", y.ID).Warnf("failed to find base image: %v", err)
+		return nil
+	}
+	if xBase == "" || yBase == "" {
+		log.WithFields(log.Fields{"x": xBase, "y": yBase}).Info("base image not found, not diffing base images")
+		return nil
+	}
+	if xBase == yBase {
+		return nil
+	}
+	result, err := diff(ctx, xBase, yBase, options, depth)
+	if err != nil {
+		log.WithFields(log.Fields{"x": xBase, "y": yBase}).Warnf("failed to diff base images: %v", err)
+		return nil
+	}
+	return result
+}
+
+func baseLabel(inspect *types.ImageInspect) string {
+	return inspect.Config.Labels[baseNameLabel]
+}
+
+// Candidate is an image which other images may be based on.
+type Candidate struct {
+	Image string
+	// Layers are the candidate's layers, from the bottom one.
+	Layers []string
+}
+
+// BaseImage finds the base image of the provided image: the one it is
+// labelled with, pinned to its digest if labelled with it too, or else the
+// candidate the most of its bottom layers are the layers of, the first one
+// if several, if any, or "" otherwise.
+func BaseImage(inspect *types.ImageInspect, candidates []*Candidate) (string, error) {
+	if name := baseLabel(inspect); name != "" {
+		digest := inspect.Config.Labels[baseDigestLabel]
+		if digest == "" {
+			return name, nil
+		}
+		named, err := reference.ParseNormalizedNamed(name)
+		if err != nil {
+			return "", err
+		}
+		return reference.FamiliarString(reference.TrimNamed(named)) + "@" + digest, nil
+	}
+	base, most := "", 0
+	for _, candidate := range candidates {
+		if len(candidate.Layers) > most && isPrefix(candidate.Layers, inspect.RootFS.Layers) {
+			base, most = candidate.Image, len(candidate.Layers)
+		}
+	}
+	return base, nil
+}
+
+// inspectCandidates lists the layers of each of the candidate base images,
+// pulling these if needed, and skipping these which fail.
+func inspectCandidates(ctx context.Context, docker *client.Client, options *Options) []*Candidate {
+	candidates := []*Candidate{}
+	for _, candidate := range options.BaseCandidates {
+		step := metrics.StartStep(metrics.Pull)
+		if err := step.Done(pull(ctx, docker, candidate, options)); err != nil {
+			log.WithField("image", candidate).Warnf("failed to pull candidate base image: %v", err)
+			continue
+		}
+		step = metrics.StartStep(metrics.Inspect)
+		inspect, err := imageInspect(ctx, docker, candidate)
+		if err := step.Done(err); err != nil {
+			log.WithField("image", candidate).Warnf("failed to inspect candidate base image: %v", err)
+			continue
+		}
+		candidates = append(candidates, &Candidate{Image: candidate, Layers: inspect.RootFS.Layers})
+	}
+	return candidates
+}
+
+func isPrefix(prefix, layers []string) bool {
+	if len(prefix) > len(layers) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != layers[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+)
+
+func inspect(labels map[string]string, layers ...string) *types.ImageInspect {
+	return &types.ImageInspect{
+		Config: &container.Config{Labels: labels},
+		RootFS: types.RootFS{Type: "layers", Layers: layers},
+	}
+}
+
+func TestBaseImageFromLabels(t *testing.T) {
+	candidates := []*diff.Candidate{{Image: "alpine:3.11", Layers: []string{"sha256:a"}}}
+
+	base, err := diff.BaseImage(inspect(map[string]string{
+		"org.opencontainers.image.base.name": "docker.io/library/alpine:3.12",
+	}, "sha256:a", "sha256:b"), candidates)
+	assert.NoError(t, err)
+	assert.Equal(t, "docker.io/library/alpine:3.12", base)
+
+	base, err = diff.BaseImage(inspect(map[string]string{
+		"org.opencontainers.image.base.name":   "docker.io/library/alpine:3.12",
+		"org.opencontainers.image.base.digest": "sha256:a15790640a6690aa1730c38cf0a440e2aa44aaca9b0e8931a9f2b0d7cc90fd65",
+	}), candidates)
+	assert.NoError(t, err)
+	assert.Equal(t, "alpine@sha256:a15790640a6690aa1730c38cf0a440e2aa44aaca9b0e8931a9f2b0d7cc90fd65", base)
+
+	_, err = diff.BaseImage(inspect(map[string]string{
+		"org.opencontainers.image.base.name":   "Alpine",
+		"org.opencontainers.image.base.digest": "sha256:a15790640a6690aa1730c38cf0a440e2aa44aaca9b0e8931a9f2b0d7cc90fd65",
+	}), candidates)
+	assert.Error(t, err)
+}
+
+func TestBaseImageFromCandidates(t *testing.T) {
+	candidates := []*diff.Candidate{
+		{Image: "alpine:3.11", Layers: []string{"sha256:a"}},
+		{Image: "golang:1.13-alpine", Layers: []string{"sha256:a", "sha256:b"}},
+		{Image: "golang:1.13-alpine3.11", Layers: []string{"sha256:a", "sha256:b"}},
+		{Image: "golang:1.14-alpine", Layers: []string{"sha256:a", "sha256:c"}},
+		{Image: "debian:buster", Layers: []string{"sha256:d"}},
+	}
+
+	base, err := diff.BaseImage(inspect(nil, "sha256:a", "sha256:b", "sha256:e"), candidates)
+	assert.NoError(t, err)
+	assert.Equal(t, "golang:1.13-alpine", base)
+
+	base, err = diff.BaseImage(inspect(nil, "sha256:a", "sha256:e"), candidates)
+	assert.NoError(t, err)
+	assert.Equal(t, "alpine:3.11", base)
+
+	// Images built only changing their base images' configuration share all their layers:
+	base, err = diff.BaseImage(inspect(nil, "sha256:d"), candidates)
+	assert.NoError(t, err)
+	assert.Equal(t, "debian:buster", base)
+
+	base, err = diff.BaseImage(inspect(nil, "sha256:e"), candidates)
+	assert.NoError(t, err)
+	assert.Equal(t, "", base)
+
+	base, err = diff.BaseImage(inspect(nil, "sha256:a"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "", base)
+}
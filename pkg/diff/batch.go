@@ -0,0 +1,238 @@
+package diff
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/weaveworks-experiments/imagediff/pkg/metrics"
+	"github.com/weaveworks-experiments/imagediff/pkg/repository"
+	git "gopkg.in/src-d/go-git.v4"
+)
+
+// Pair encapsulates two images to diff.
+type Pair struct {
+	X string `json:"x"`
+	Y string `json:"y"`
+}
+
+// ParsePairs reads pairs of images to diff, one pair per line, with both
+// images separated by whitespace. Blank lines and lines starting with "#" are
+// ignored.
+func ParsePairs(r io.Reader) ([]Pair, error) {
+	pairs := []Pair{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid pair of images on line %v: [%v], expected: <image> <image>", n, line)
+		}
+		pairs = append(pairs, Pair{X: fields[0], Y: fields[1]})
+	}
+	return pairs, scanner.Err()
+}
+
+// BatchResult encapsulates the outcome of diffing one of the pairs of a batch: either its result, or the error diffing it.
+type BatchResult struct {
+	Pair
+	Result *Result
+	Err    error
+}
+
+// Batch diffs the provided pairs of images, with up to the provided number of
+// diffs running concurrently, and sharing clones of repositories across
+// diffs. Failing to diff a pair does not prevent diffing the others, and its
+// error is reported in its result. Credentials are never prompted for, as
+// concurrent diffs would interleave their prompts.
+func Batch(pairs []Pair, options *Options, parallelism int) []*BatchResult {
+	batchOptions := *options
+	batchOptions.NonInteractive = true
+	if batchOptions.Clones == nil {
+		batchOptions.Clones = NewClones(0)
+	}
+	return RunBatch(pairs, parallelism, func(x, y string) (*Result, error) {
+		return Diff(x, y, &batchOptions)
+	})
+}
+
+// RunBatch runs the provided diff function on the provided pairs of images,
+// with up to the provided number of calls running concurrently. Results are
+// in the order of the pairs.
+func RunBatch(pairs []Pair, parallelism int, diff func(x, y string) (*Result, error)) []*BatchResult {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	results := make([]*BatchResult, len(pairs))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < parallelism; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				result, err := diff(pairs[i].X, pairs[i].Y)
+				if err != nil {
+					log.WithFields(log.Fields{"x": pairs[i].X, "y": pairs[i].Y}).Error(err)
+				}
+				results[i] = &BatchResult{Pair: pairs[i], Result: result, Err: err}
+			}
+		}()
+	}
+	for i := range pairs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// Clones caches clones of repositories, to share these across diffs, e.g. of
+// several images built from the same repository. It is safe for concurrent
+// use: concurrent diffs needing the same repository wait for a single clone.
+// Failed clones are not cached, so that they are retried by the next diff.
+// Clones are refreshed, by fetching what they miss, at most once per
+// refreshInterval, so that diffs of revisions missing from a repository do
+// not fetch it again and again. Past its capacity, the least recently used
+// clones are evicted, so that long-running servers do not hold every
+// repository they ever diffed in memory.
+type Clones struct {
+	mutex    sync.Mutex
+	clones   map[string]*clone
+	capacity int
+	// uses counts the uses of clones, to order these from the least to the most recently used.
+	uses uint64
+}
+
+// refreshInterval is how long after it was cloned, or last fetched, a repository can be fetched again.
+const refreshInterval = time.Minute
+
+type clone struct {
+	done    chan struct{}
+	repo    *git.Repository
+	err     error
+	updated time.Time
+	used    uint64
+	// abandoned is true if cloning failed as its diff was cancelled, rather
+	// than because of the repository, in which case diffs waiting for it try
+	// again.
+	abandoned bool
+}
+
+func (c *clone) isDone() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// NewClones creates a new, empty, cache of up to the provided number of
+// clones, or of any number of these, if not positive.
+func NewClones(capacity int) *Clones {
+	return &Clones{clones: map[string]*clone{}, capacity: capacity}
+}
+
+// Clone clones the provided repository, or returns its existing clone, if
+// any, unless the provided context is done first.
+func (c *Clones) Clone(ctx context.Context, repo *repository.GitRepository, options *repository.Options) (*git.Repository, error) {
+	return c.clone(ctx, repo, options, nil)
+}
+
+// Refresh fetches what the provided repository's provided stale clone misses,
+// e.g. as it predates the revisions to diff, unless this was already done
+// since, or the stale clone is less than refreshInterval old, in which case
+// it is returned as is. The stale clone itself is left untouched, as other
+// diffs may still be reading it.
+func (c *Clones) Refresh(ctx context.Context, repo *repository.GitRepository, options *repository.Options, stale *git.Repository) (*git.Repository, error) {
+	return c.clone(ctx, repo, options, stale)
+}
+
+func (c *Clones) clone(ctx context.Context, repo *repository.GitRepository, options *repository.Options, stale *git.Repository) (*git.Repository, error) {
+	key := repo.HTTPS()
+	c.mutex.Lock()
+	existing, ok := c.clones[key]
+	var previous *clone
+	if ok && stale != nil && existing.isDone() && existing.repo == stale {
+		if time.Since(existing.updated) < refreshInterval {
+			c.mutex.Unlock()
+			log.WithField("repository", repo).Info("clone refreshed recently, not fetching again")
+			return stale, nil
+		}
+		previous, ok = existing, false
+	}
+	if !ok {
+		existing = &clone{done: make(chan struct{})}
+		c.clones[key] = existing
+		c.evict()
+	}
+	c.uses++
+	existing.used = c.uses
+	c.mutex.Unlock()
+	if ok {
+		metrics.CacheRequests.WithLabelValues(metrics.Clones, metrics.Hit).Inc()
+		log.WithField("repository", repo).Debug("reusing clone")
+		select {
+		case <-existing.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if existing.abandoned && ctx.Err() == nil {
+			return c.clone(ctx, repo, options, stale)
+		}
+		return existing.repo, existing.err
+	}
+	metrics.CacheRequests.WithLabelValues(metrics.Clones, metrics.Miss).Inc()
+	if stale != nil {
+		step := metrics.StartStep(metrics.Refresh)
+		existing.repo, existing.err = repo.Fetch(ctx, stale, options)
+		step.Done(existing.err)
+	} else {
+		step := metrics.StartStep(metrics.Clone)
+		existing.repo, existing.err = repo.Clone(ctx, options)
+		step.Done(existing.err)
+	}
+	existing.updated = time.Now()
+	if existing.err != nil {
+		existing.abandoned = ctx.Err() != nil
+		// Failing to refresh a clone still leaves it usable, for other revisions:
+		c.mutex.Lock()
+		if c.clones[key] == existing {
+			if previous != nil {
+				c.clones[key] = previous
+			} else {
+				delete(c.clones, key)
+			}
+		}
+		c.mutex.Unlock()
+	}
+	close(existing.done)
+	return existing.repo, existing.err
+}
+
+// evict evicts the least recently used clones past this cache's capacity.
+// Clones in progress are never evicted, as diffs are waiting for these.
+func (c *Clones) evict() {
+	for c.capacity > 0 && len(c.clones) > c.capacity {
+		var lru string
+		for key, clone := range c.clones {
+			if clone.isDone() && (lru == "" || clone.used < c.clones[lru].used) {
+				lru = key
+			}
+		}
+		if lru == "" {
+			return
+		}
+		log.WithField("repository", lru).Debug("evicting clone")
+		delete(c.clones, lru)
+	}
+}
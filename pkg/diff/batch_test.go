@@ -0,0 +1,65 @@
+package diff_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+)
+
+func TestParsePairs(t *testing.T) {
+	pairs, err := diff.ParsePairs(strings.NewReader(`# release 42
+microscaling/microscaling:0.9.0 microscaling/microscaling:0.9.1
+
+  microscaling/worker:0.3.0	microscaling/worker:0.4.0
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, []diff.Pair{
+		{X: "microscaling/microscaling:0.9.0", Y: "microscaling/microscaling:0.9.1"},
+		{X: "microscaling/worker:0.3.0", Y: "microscaling/worker:0.4.0"},
+	}, pairs)
+
+	_, err = diff.ParsePairs(strings.NewReader("a b\nc\n"))
+	assert.EqualError(t, err, "invalid pair of images on line 2: [c], expected: <image> <image>")
+}
+
+func TestRunBatch(t *testing.T) {
+	pairs := []diff.Pair{}
+	for _, x := range []string{"a", "b", "c", "d", "e", "f"} {
+		pairs = append(pairs, diff.Pair{X: x, Y: x + "'"})
+	}
+	var mutex sync.Mutex
+	running, maxRunning := 0, 0
+	results := diff.RunBatch(pairs, 2, func(x, y string) (*diff.Result, error) {
+		mutex.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mutex.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+		if x == "c" {
+			return nil, errors.New("no revision label")
+		}
+		return &diff.Result{X: x, Y: y}, nil
+	})
+	assert.Equal(t, 2, maxRunning)
+	assert.Len(t, results, len(pairs))
+	for i, result := range results {
+		assert.Equal(t, pairs[i], result.Pair)
+		if pairs[i].X == "c" {
+			assert.EqualError(t, result.Err, "no revision label")
+			assert.Nil(t, result.Result)
+		} else {
+			assert.NoError(t, result.Err)
+			assert.Equal(t, pairs[i].Y, result.Result.Y)
+		}
+	}
+}
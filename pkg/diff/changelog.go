@@ -0,0 +1,181 @@
+package diff
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// Change encapsulates the revision number, commit message and metadata for a code change.
+type Change struct {
+	Revision  string    `json:"revision"`
+	Message   string    `json:"message"`
+	Author    Signature `json:"author"`
+	Committer Signature `json:"committer"`
+	Parents   []string  `json:"parents"`
+	Merge     bool      `json:"merge"`
+	// Files is only populated when diffing with Options.Stats.
+	Files []*FileStat `json:"files,omitempty"`
+	// PullRequests are only populated when enriching changes with the source
+	// code repository's host's API, e.g. GitHub's.
+	PullRequests []*PullRequest `json:"pullRequests,omitempty"`
+}
+
+// Signature identifies who authored or committed a change, and when.
+type Signature struct {
+	Name  string    `json:"name"`
+	Email string    `json:"email"`
+	When  time.Time `json:"when"`
+}
+
+// FileStat encapsulates the number of lines added and deleted in a file by a change.
+// For merge commits, these are computed against their first parent.
+type FileStat struct {
+	Path    string `json:"path"`
+	Added   int    `json:"added"`
+	Deleted int    `json:"deleted"`
+}
+
+// PullRequest encapsulates the metadata of a pull request (GitHub) or merge
+// request (GitLab) associated with a change, as provided by its host's API.
+type PullRequest struct {
+	Number    int      `json:"number"`
+	Title     string   `json:"title"`
+	URL       string   `json:"url"`
+	Author    string   `json:"author"`
+	Labels    []string `json:"labels"`
+	Reviewers []string `json:"reviewers"`
+	// Milestone and PipelineStatus are only provided by GitLab.
+	Milestone      string `json:"milestone,omitempty"`
+	PipelineStatus string `json:"pipelineStatus,omitempty"`
+}
+
+// ChangeLog lists the changes from xCommit (excluded) to yCommit (included),
+// i.e. the changes reachable from yCommit but not from xCommit, like
+// "git log xCommit..yCommit" would.
+func ChangeLog(xCommit, yCommit *object.Commit, options *Options) ([]*Change, error) {
+	if options == nil {
+		options = &Options{}
+	}
+	if xCommit.Hash == yCommit.Hash {
+		return []*Change{}, nil
+	}
+	excluded, err := ancestors(xCommit)
+	if err != nil {
+		return nil, err
+	}
+	changeLog := []*Change{}
+	commits := map[string]*object.Commit{}
+	found := false
+	err = object.NewCommitPostorderIter(yCommit, excluded).ForEach(func(c *object.Commit) error {
+		for _, parent := range c.ParentHashes {
+			found = found || parent == xCommit.Hash
+		}
+		change := newChange(c)
+		changeLog = append(changeLog, change)
+		commits[change.Revision] = c
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("commit with hash [%s] could not be found in the history of commit with hash [%s]", xCommit.Hash, yCommit.Hash)
+	}
+	changeLog, err = Sort(filter(changeLog, options), options.Order)
+	if err != nil {
+		return nil, err
+	}
+	if options.Reverse {
+		changeLog = Reverse(changeLog)
+	}
+	if options.Stats {
+		for _, change := range changeLog {
+			if change.Files, err = fileStats(commits[change.Revision]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return changeLog, nil
+}
+
+// ancestors lists the provided commit and all its ancestors.
+func ancestors(c *object.Commit) ([]plumbing.Hash, error) {
+	hashes := []plumbing.Hash{}
+	err := object.NewCommitPreorderIter(c, nil, nil).ForEach(func(c *object.Commit) error {
+		hashes = append(hashes, c.Hash)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// filter only keeps the changes matching the provided options' history mode.
+func filter(changeLog []*Change, options *Options) []*Change {
+	var mainline map[string]bool
+	if options.FirstParent {
+		changes := make(map[string]*Change, len(changeLog))
+		for _, change := range changeLog {
+			changes[change.Revision] = change
+		}
+		mainline = firstParents(head(changeLog), changes)
+	}
+	filtered := []*Change{}
+	for _, change := range changeLog {
+		if options.FirstParent && !mainline[change.Revision] {
+			continue
+		}
+		if options.NoMerges && change.Merge {
+			continue
+		}
+		filtered = append(filtered, change)
+	}
+	return filtered
+}
+
+func newChange(c *object.Commit) *Change {
+	return &Change{
+		Revision:  c.Hash.String(),
+		Message:   c.Message,
+		Author:    signature(c.Author),
+		Committer: signature(c.Committer),
+		Parents:   parents(c),
+		Merge:     c.NumParents() > 1,
+	}
+}
+
+func signature(s object.Signature) Signature {
+	return Signature{
+		Name:  s.Name,
+		Email: s.Email,
+		When:  s.When,
+	}
+}
+
+func parents(c *object.Commit) []string {
+	parents := make([]string, len(c.ParentHashes))
+	for i, hash := range c.ParentHashes {
+		parents[i] = hash.String()
+	}
+	return parents
+}
+
+func fileStats(c *object.Commit) ([]*FileStat, error) {
+	stats, err := c.Stats()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute stats for commit with hash [%s]: %s", c.Hash, err)
+	}
+	files := make([]*FileStat, len(stats))
+	for i, stat := range stats {
+		files[i] = &FileStat{
+			Path:    stat.Name,
+			Added:   stat.Addition,
+			Deleted: stat.Deletion,
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,271 @@
+package diff_test
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+func TestChangeLog(t *testing.T) {
+	h := newHistory(t)
+	a := h.commit("a", map[string]string{"README.md": "imagediff\n"})
+	h.commit("b", map[string]string{"README.md": "imagediff\n", "main.go": "package main\n"}, "a")
+	c := h.commit("c", map[string]string{"README.md": "imagediff\nDiffs images.\n", "main.go": "package main\n"}, "b")
+
+	changeLog, err := diff.ChangeLog(a, c, &diff.Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c", "b"}, messages(changeLog))
+
+	change := changeLog[0]
+	assert.Equal(t, c.Hash.String(), change.Revision)
+	assert.Equal(t, []string{h.hash("b")}, change.Parents)
+	assert.False(t, change.Merge)
+	assert.Equal(t, diff.Signature{Name: "Author c", Email: "c@example.com", When: c.Author.When}, change.Author)
+	assert.Equal(t, diff.Signature{Name: "Committer c", Email: "committer@example.com", When: c.Committer.When}, change.Committer)
+	assert.Nil(t, change.Files)
+}
+
+func TestChangeLogWithStats(t *testing.T) {
+	h := newHistory(t)
+	a := h.commit("a", map[string]string{"README.md": "imagediff\n"})
+	h.commit("b", map[string]string{"README.md": "imagediff\n", "main.go": "package main\n"}, "a")
+	c := h.commit("c", map[string]string{"README.md": "imagediff\nDiffs images.\n"}, "b")
+
+	changeLog, err := diff.ChangeLog(a, c, &diff.Options{Stats: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c", "b"}, messages(changeLog))
+	assert.Equal(t, []*diff.FileStat{
+		{Path: "README.md", Added: 1, Deleted: 0},
+		{Path: "main.go", Added: 0, Deleted: 1},
+	}, sortedByPath(changeLog[0].Files))
+	assert.Equal(t, []*diff.FileStat{
+		{Path: "main.go", Added: 1, Deleted: 0},
+	}, sortedByPath(changeLog[1].Files))
+}
+
+func TestChangeLogWithMerge(t *testing.T) {
+	h := newHistory(t)
+	a := h.commit("a", nil)
+	h.commit("b", nil, "a")
+	h.commit("c", nil, "b")
+	m := h.commit("m", nil, "b", "c")
+
+	changeLog, err := diff.ChangeLog(a, m, &diff.Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"m", "c", "b"}, messages(changeLog))
+	assert.True(t, changeLog[0].Merge)
+	assert.Equal(t, []string{h.hash("b"), h.hash("c")}, changeLog[0].Parents)
+}
+
+func TestChangeLogWithBranchesForkedFromX(t *testing.T) {
+	h := newHistory(t)
+	a := h.commit("a", nil)
+	h.commit("b", nil, "a")
+	h.commit("c", nil, "a")
+	m := h.commit("m", nil, "b", "c")
+
+	changeLog, err := diff.ChangeLog(a, m, &diff.Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"m", "c", "b"}, messages(changeLog))
+}
+
+func TestChangeLogWithXOnMergedBranch(t *testing.T) {
+	// History, from oldest to most recent:
+	//   a - b - m
+	//    \     /
+	//     x --
+	h := newHistory(t)
+	h.commit("a", nil)
+	h.commit("b", nil, "a")
+	x := h.commit("x", nil, "a")
+	m := h.commit("m", nil, "b", "x")
+
+	changeLog, err := diff.ChangeLog(x, m, &diff.Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"m", "b"}, messages(changeLog))
+}
+
+func TestChangeLogWithBranchForkedBeforeX(t *testing.T) {
+	// History, from oldest to most recent:
+	//   a - x - b - m
+	//    \         /
+	//     c ---- d
+	h := newHistory(t)
+	h.commit("a", nil)
+	x := h.commit("x", nil, "a")
+	h.commit("b", nil, "x")
+	h.commit("c", nil, "a")
+	h.commit("d", nil, "c")
+	m := h.commit("m", nil, "b", "d")
+
+	changeLog, err := diff.ChangeLog(x, m, &diff.Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"m", "d", "c", "b"}, messages(changeLog))
+}
+
+func TestChangeLogFirstParent(t *testing.T) {
+	h := mergedHistory(t)
+	changeLog, err := diff.ChangeLog(h.commits["a"], h.commits["e"], &diff.Options{FirstParent: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e", "m", "b"}, messages(changeLog))
+}
+
+func TestChangeLogNoMerges(t *testing.T) {
+	h := mergedHistory(t)
+	changeLog, err := diff.ChangeLog(h.commits["a"], h.commits["e"], &diff.Options{NoMerges: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e", "d", "c", "b"}, messages(changeLog))
+}
+
+func TestChangeLogFirstParentNoMerges(t *testing.T) {
+	h := mergedHistory(t)
+	changeLog, err := diff.ChangeLog(h.commits["a"], h.commits["e"], &diff.Options{FirstParent: true, NoMerges: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e", "b"}, messages(changeLog))
+}
+
+func TestChangeLogIsDeterministic(t *testing.T) {
+	h := mergedHistory(t)
+	for i := 0; i < 10; i++ {
+		changeLog, err := diff.ChangeLog(h.commits["a"], h.commits["e"], &diff.Options{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"e", "m", "d", "c", "b"}, messages(changeLog))
+	}
+}
+
+func TestChangeLogSameCommit(t *testing.T) {
+	h := newHistory(t)
+	a := h.commit("a", nil)
+	changeLog, err := diff.ChangeLog(a, a, &diff.Options{})
+	assert.NoError(t, err)
+	assert.Empty(t, changeLog)
+}
+
+func TestChangeLogUnrelatedCommits(t *testing.T) {
+	h := newHistory(t)
+	h.commit("a", nil)
+	b := h.commit("b", nil, "a")
+	c := h.commit("c", nil, "a")
+	_, err := diff.ChangeLog(b, c, &diff.Options{})
+	assert.Error(t, err)
+}
+
+// mergedHistory creates the following history, from oldest to most recent:
+//
+//	a - b ----- m - e
+//	 \         /
+//	  c ---- d
+func mergedHistory(t *testing.T) *history {
+	h := newHistory(t)
+	h.commit("a", nil)
+	h.commit("b", nil, "a")
+	h.commit("c", nil, "a")
+	h.commit("d", nil, "c")
+	h.commit("m", nil, "b", "d")
+	h.commit("e", nil, "m")
+	return h
+}
+
+// history builds Git histories in memory, one commit at a time.
+type history struct {
+	t       *testing.T
+	storage *memory.Storage
+	commits map[string]*object.Commit
+	clock   time.Time
+}
+
+func newHistory(t *testing.T) *history {
+	return &history{
+		t:       t,
+		storage: memory.NewStorage(),
+		commits: map[string]*object.Commit{},
+		clock:   time.Date(2018, time.June, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+// commit creates a commit with the provided files, using its name as its message.
+func (h *history) commit(name string, files map[string]string, parents ...string) *object.Commit {
+	return h.commitTree(name, h.tree(files), parents...)
+}
+
+// commitTree creates a commit with the provided tree, using its name as its message.
+func (h *history) commitTree(name string, tree plumbing.Hash, parents ...string) *object.Commit {
+	h.clock = h.clock.Add(time.Hour)
+	commit := &object.Commit{
+		Author:    object.Signature{Name: "Author " + name, Email: name + "@example.com", When: h.clock},
+		Committer: object.Signature{Name: "Committer " + name, Email: "committer@example.com", When: h.clock},
+		Message:   name,
+		TreeHash:  tree,
+	}
+	for _, parent := range parents {
+		commit.ParentHashes = append(commit.ParentHashes, h.commits[parent].Hash)
+	}
+	obj := h.storage.NewEncodedObject()
+	assert.NoError(h.t, commit.Encode(obj))
+	hash, err := h.storage.SetEncodedObject(obj)
+	assert.NoError(h.t, err)
+	commit, err = object.GetCommit(h.storage, hash)
+	assert.NoError(h.t, err)
+	h.commits[name] = commit
+	return commit
+}
+
+func (h *history) hash(name string) string {
+	return h.commits[name].Hash.String()
+}
+
+func (h *history) tree(files map[string]string) plumbing.Hash {
+	paths := []string{}
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	entries := []object.TreeEntry{}
+	for _, path := range paths {
+		entries = append(entries, object.TreeEntry{Name: path, Mode: filemode.Regular, Hash: h.blob(files[path])})
+	}
+	return h.entries(entries...)
+}
+
+// entries creates a tree with the provided entries, which must be sorted by name.
+func (h *history) entries(entries ...object.TreeEntry) plumbing.Hash {
+	tree := &object.Tree{Entries: entries}
+	obj := h.storage.NewEncodedObject()
+	assert.NoError(h.t, tree.Encode(obj))
+	hash, err := h.storage.SetEncodedObject(obj)
+	assert.NoError(h.t, err)
+	return hash
+}
+
+func (h *history) blob(content string) plumbing.Hash {
+	obj := h.storage.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	w, err := obj.Writer()
+	assert.NoError(h.t, err)
+	_, err = w.Write([]byte(content))
+	assert.NoError(h.t, err)
+	assert.NoError(h.t, w.Close())
+	hash, err := h.storage.SetEncodedObject(obj)
+	assert.NoError(h.t, err)
+	return hash
+}
+
+func messages(changeLog []*diff.Change) []string {
+	messages := []string{}
+	for _, change := range changeLog {
+		messages = append(messages, change.Message)
+	}
+	return messages
+}
+
+func sortedByPath(files []*diff.FileStat) []*diff.FileStat {
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
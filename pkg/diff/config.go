@@ -0,0 +1,194 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// Kinds of ConfigChange.
+const (
+	ConfigAdded   = "added"
+	ConfigRemoved = "removed"
+	ConfigChanged = "changed"
+)
+
+// ConfigChange is a change to a field of images' configuration, e.g. to an
+// environment variable, or to the entrypoint.
+type ConfigChange struct {
+	// Field is the changed field, as named by "docker inspect", e.g. Env, or Entrypoint.
+	Field string `json:"field"`
+	// Key identifies the changed entry of fields made of several entries, e.g.
+	// the variable's name for Env, or the port for ExposedPorts.
+	Key string `json:"key,omitempty"`
+	// Change is one of ConfigAdded, ConfigRemoved or ConfigChanged.
+	Change string `json:"change"`
+	// X and Y are the field's, or entry's, values in both images, if any.
+	// Entries of ExposedPorts and Volumes have no value.
+	X string `json:"x,omitempty"`
+	Y string `json:"y,omitempty"`
+}
+
+// Name names the changed field, or entry, e.g. "Env PATH".
+func (c *ConfigChange) Name() string {
+	if c.Key == "" {
+		return c.Field
+	}
+	return c.Field + " " + c.Key
+}
+
+func (c *ConfigChange) String() string {
+	switch {
+	case c.Change == ConfigChanged:
+		return fmt.Sprintf("%v: changed from %q to %q", c.Name(), c.X, c.Y)
+	case c.Change == ConfigAdded && c.Y != "":
+		return fmt.Sprintf("%v: added %q", c.Name(), c.Y)
+	case c.Change == ConfigRemoved && c.X != "":
+		return fmt.Sprintf("%v: removed %q", c.Name(), c.X)
+	default:
+		return fmt.Sprintf("%v: %v", c.Name(), c.Change)
+	}
+}
+
+// ConfigDiff lists the changes from x's configuration to y's, field by field,
+// for the fields most likely to change how containers behave: Env,
+// Entrypoint, Cmd, Shell, User, WorkingDir, ExposedPorts, Volumes,
+// Healthcheck, StopSignal and Labels.
+func ConfigDiff(x, y *container.Config) []*ConfigChange {
+	if x == nil {
+		x = &container.Config{}
+	}
+	if y == nil {
+		y = &container.Config{}
+	}
+	changes := []*ConfigChange{}
+	changes = append(changes, diffEntries("Env", env(x.Env), env(y.Env))...)
+	changes = append(changes, diffValues("Entrypoint", command(x.Entrypoint), command(y.Entrypoint))...)
+	changes = append(changes, diffValues("Cmd", command(x.Cmd), command(y.Cmd))...)
+	changes = append(changes, diffValues("Shell", command(x.Shell), command(y.Shell))...)
+	changes = append(changes, diffValues("User", x.User, y.User)...)
+	changes = append(changes, diffValues("WorkingDir", x.WorkingDir, y.WorkingDir)...)
+	xPorts, yPorts := map[string]string{}, map[string]string{}
+	for port := range x.ExposedPorts {
+		xPorts[string(port)] = ""
+	}
+	for port := range y.ExposedPorts {
+		yPorts[string(port)] = ""
+	}
+	changes = append(changes, diffEntries("ExposedPorts", xPorts, yPorts)...)
+	changes = append(changes, diffEntries("Volumes", set(x.Volumes), set(y.Volumes))...)
+	changes = append(changes, diffValues("Healthcheck", healthcheck(x.Healthcheck), healthcheck(y.Healthcheck))...)
+	changes = append(changes, diffValues("StopSignal", x.StopSignal, y.StopSignal)...)
+	changes = append(changes, diffEntries("Labels", x.Labels, y.Labels)...)
+	return changes
+}
+
+func diffValues(field, x, y string) []*ConfigChange {
+	switch {
+	case x == y:
+		return nil
+	case x == "":
+		return []*ConfigChange{{Field: field, Change: ConfigAdded, Y: y}}
+	case y == "":
+		return []*ConfigChange{{Field: field, Change: ConfigRemoved, X: x}}
+	default:
+		return []*ConfigChange{{Field: field, Change: ConfigChanged, X: x, Y: y}}
+	}
+}
+
+// diffEntries lists the changes from x's entries to y's, ordered by key.
+func diffEntries(field string, x, y map[string]string) []*ConfigChange {
+	keys := []string{}
+	for key := range x {
+		keys = append(keys, key)
+	}
+	for key := range y {
+		if _, ok := x[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	changes := []*ConfigChange{}
+	for _, key := range keys {
+		xValue, inX := x[key]
+		yValue, inY := y[key]
+		switch {
+		case !inX:
+			changes = append(changes, &ConfigChange{Field: field, Key: key, Change: ConfigAdded, Y: yValue})
+		case !inY:
+			changes = append(changes, &ConfigChange{Field: field, Key: key, Change: ConfigRemoved, X: xValue})
+		case xValue != yValue:
+			changes = append(changes, &ConfigChange{Field: field, Key: key, Change: ConfigChanged, X: xValue, Y: yValue})
+		}
+	}
+	return changes
+}
+
+// env maps environment variables, as "NAME=value", to their values.
+func env(variables []string) map[string]string {
+	values := map[string]string{}
+	for _, variable := range variables {
+		parts := strings.SplitN(variable, "=", 2)
+		if len(parts) == 2 {
+			values[parts[0]] = parts[1]
+		} else {
+			values[parts[0]] = ""
+		}
+	}
+	return values
+}
+
+func set(entries map[string]struct{}) map[string]string {
+	values := map[string]string{}
+	for entry := range entries {
+		values[entry] = ""
+	}
+	return values
+}
+
+// command formats commands in their exec form, e.g. ["/app", "--verbose"], the way Dockerfiles do.
+func command(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		bytes, _ := json.Marshal(arg)
+		quoted[i] = string(bytes)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// healthcheck formats healthchecks the way Dockerfiles do, e.g. --interval=30s CMD ["/healthcheck"].
+func healthcheck(config *container.HealthConfig) string {
+	if config == nil || len(config.Test) == 0 {
+		return ""
+	}
+	options := []string{}
+	if config.Interval > 0 {
+		options = append(options, fmt.Sprintf("--interval=%v", config.Interval))
+	}
+	if config.Timeout > 0 {
+		options = append(options, fmt.Sprintf("--timeout=%v", config.Timeout))
+	}
+	if config.StartPeriod > 0 {
+		options = append(options, fmt.Sprintf("--start-period=%v", config.StartPeriod))
+	}
+	if config.Retries > 0 {
+		options = append(options, fmt.Sprintf("--retries=%v", config.Retries))
+	}
+	switch config.Test[0] {
+	case "NONE":
+		options = append(options, "NONE")
+	case "CMD-SHELL":
+		options = append(options, "CMD "+strings.Join(config.Test[1:], " "))
+	case "CMD":
+		options = append(options, "CMD "+command(config.Test[1:]))
+	default:
+		options = append(options, command(config.Test))
+	}
+	return strings.Join(options, " ")
+}
@@ -0,0 +1,58 @@
+package diff_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+)
+
+func TestConfigDiff(t *testing.T) {
+	x := &container.Config{
+		Env:          []string{"PATH=/usr/bin", "DEBUG=true", "EMPTY="},
+		Entrypoint:   []string{"/app"},
+		Cmd:          []string{"--port", "8080"},
+		User:         "root",
+		WorkingDir:   "/app",
+		ExposedPorts: nat.PortSet{"8080/tcp": {}},
+		Volumes:      map[string]struct{}{"/data": {}},
+		Labels:       map[string]string{"org.opencontainers.image.revision": "4756fd6", "maintainer": "jane@example.com"},
+	}
+	y := &container.Config{
+		Env:          []string{"PATH=/app/bin:/usr/bin", "EMPTY=", "LOG_LEVEL=info"},
+		Entrypoint:   []string{"/app"},
+		Cmd:          []string{"--port", "9090"},
+		User:         "app",
+		WorkingDir:   "/app",
+		ExposedPorts: nat.PortSet{"9090/tcp": {}},
+		Volumes:      map[string]struct{}{"/data": {}},
+		Healthcheck:  &container.HealthConfig{Test: []string{"CMD-SHELL", "curl -f http://localhost:9090/"}, Interval: 30 * time.Second, Retries: 3},
+		StopSignal:   "SIGINT",
+		Labels:       map[string]string{"org.opencontainers.image.revision": "45b22cb", "maintainer": "jane@example.com"},
+	}
+	assert.Equal(t, []*diff.ConfigChange{
+		{Field: "Env", Key: "DEBUG", Change: diff.ConfigRemoved, X: "true"},
+		{Field: "Env", Key: "LOG_LEVEL", Change: diff.ConfigAdded, Y: "info"},
+		{Field: "Env", Key: "PATH", Change: diff.ConfigChanged, X: "/usr/bin", Y: "/app/bin:/usr/bin"},
+		{Field: "Cmd", Change: diff.ConfigChanged, X: `["--port", "8080"]`, Y: `["--port", "9090"]`},
+		{Field: "User", Change: diff.ConfigChanged, X: "root", Y: "app"},
+		{Field: "ExposedPorts", Key: "8080/tcp", Change: diff.ConfigRemoved},
+		{Field: "ExposedPorts", Key: "9090/tcp", Change: diff.ConfigAdded},
+		{Field: "Healthcheck", Change: diff.ConfigAdded, Y: "--interval=30s --retries=3 CMD curl -f http://localhost:9090/"},
+		{Field: "StopSignal", Change: diff.ConfigAdded, Y: "SIGINT"},
+		{Field: "Labels", Key: "org.opencontainers.image.revision", Change: diff.ConfigChanged, X: "4756fd6", Y: "45b22cb"},
+	}, diff.ConfigDiff(x, y))
+
+	assert.Empty(t, diff.ConfigDiff(x, x))
+	assert.Equal(t, []*diff.ConfigChange{{Field: "User", Change: diff.ConfigRemoved, X: "root"}}, diff.ConfigDiff(&container.Config{User: "root"}, nil))
+}
+
+func TestConfigChangeString(t *testing.T) {
+	assert.Equal(t, `Env PATH: changed from "/usr/bin" to "/app/bin:/usr/bin"`, (&diff.ConfigChange{Field: "Env", Key: "PATH", Change: diff.ConfigChanged, X: "/usr/bin", Y: "/app/bin:/usr/bin"}).String())
+	assert.Equal(t, `User: added "app"`, (&diff.ConfigChange{Field: "User", Change: diff.ConfigAdded, Y: "app"}).String())
+	assert.Equal(t, `Cmd: removed "[\"/app\"]"`, (&diff.ConfigChange{Field: "Cmd", Change: diff.ConfigRemoved, X: `["/app"]`}).String())
+	assert.Equal(t, `ExposedPorts 8080/tcp: removed`, (&diff.ConfigChange{Field: "ExposedPorts", Key: "8080/tcp", Change: diff.ConfigRemoved}).String())
+}
@@ -12,12 +12,18 @@ import (
 	"strings"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/docker/docker/pkg/term"
 	log "github.com/sirupsen/logrus"
+	"github.com/weaveworks-experiments/imagediff/pkg/dependencies"
 	"github.com/weaveworks-experiments/imagediff/pkg/image"
+	"github.com/weaveworks-experiments/imagediff/pkg/issue"
+	"github.com/weaveworks-experiments/imagediff/pkg/layers"
+	"github.com/weaveworks-experiments/imagediff/pkg/metrics"
+	"github.com/weaveworks-experiments/imagediff/pkg/packages"
 	imagediff_registry "github.com/weaveworks-experiments/imagediff/pkg/registry"
 	"github.com/weaveworks-experiments/imagediff/pkg/repository"
 	"golang.org/x/crypto/ssh/terminal"
@@ -29,60 +35,248 @@ import (
 type Options struct {
 	DockerConfigPath string
 	GitOptions       *repository.Options
+	// NonInteractive fails to pull images requiring credentials not found in
+	// Docker's configuration, instead of prompting for these on the standard
+	// input, e.g. when diffing concurrently, or without a terminal.
+	NonInteractive bool
+	// FirstParent only keeps the changes which landed on the mainline, i.e.
+	// the ones found by following first parents from the most recent change,
+	// like "git log --first-parent" would.
+	FirstParent bool
+	// NoMerges drops merge commits, like "git log --no-merges" would.
+	NoMerges bool
+	// Order determines the order changes are listed in, most recent first.
+	Order Order
+	// Reverse lists changes oldest first instead.
+	Reverse bool
+	// IssueTrackers are the trackers to extract references to issues for, on
+	// top of the source code repository's issues, referenced with closing
+	// keywords, e.g. "Fixes #12".
+	IssueTrackers []*issue.Tracker
+	// Stats computes the number of lines added and deleted in each file, for each change.
+	// This requires diffing each change's tree against its parent's, and is therefore expensive.
+	Stats bool
+	// Clones, if set, shares clones of repositories across diffs.
+	Clones *Clones
+	// ConfigDiff lists the changes to the images' configuration, e.g. to their environment variables.
+	ConfigDiff bool
+	// LayerDiff compares the images' layers.
+	LayerDiff bool
+	// FileDiff, along with LayerDiff, lists the files added, removed or
+	// modified by the layers which changed. This requires reading the images'
+	// layers, and is therefore expensive.
+	FileDiff bool
+	// PackageDiff lists the OS packages added, removed, or whose version
+	// changed. This requires reading the images' layers, and is therefore
+	// expensive.
+	PackageDiff bool
+	// DependencyDiff lists the dependencies added, removed, or whose version
+	// changed, between the source code's revisions, from manifests, e.g. go.mod.
+	DependencyDiff bool
+	// SubmoduleDiff also lists the changes between the revisions of the
+	// submodules bumped between the source code's revisions.
+	SubmoduleDiff bool
+	// BaseDiff also diffs the images' base images, if these changed, and so
+	// on, recursively.
+	BaseDiff bool
+	// BaseCandidates are the images to find the base images of images not
+	// labelled with these among, by comparing their layers.
+	BaseCandidates []string
+}
+
+// Result encapsulates the outcome of diffing two container images.
+type Result struct {
+	X          string                    `json:"x"`
+	Y          string                    `json:"y"`
+	Repository *repository.GitRepository `json:"repository"`
+	XRevision  string                    `json:"xRevision"`
+	YRevision  string                    `json:"yRevision"`
+	ChangeLog  []*Change                 `json:"changeLog"`
+	// Issues are the issues referenced by the above changes.
+	Issues []*issue.Issue `json:"issues"`
+	// Submodules lists the submodules bumped between the above revisions, with their changes, if diffed with Options.SubmoduleDiff.
+	Submodules []*Submodule `json:"submodules,omitempty"`
+	// Config lists the changes to the images' configuration, if diffed with Options.ConfigDiff.
+	Config []*ConfigChange `json:"config,omitempty"`
+	// Layers compares the images' layers, if diffed with Options.LayerDiff.
+	Layers *layers.Diff `json:"layers,omitempty"`
+	// Packages lists the changes to the images' OS packages, if diffed with
+	// Options.PackageDiff, and if both images' packages could be listed.
+	Packages []*packages.Change `json:"packages,omitempty"`
+	// Dependencies lists the changes to the source code's dependencies, if diffed with Options.DependencyDiff.
+	Dependencies []*dependencies.Change `json:"dependencies,omitempty"`
+	// Base diffs the images' base images, if diffed with Options.BaseDiff, and
+	// if these changed. Base images' source code repositories are optional,
+	// i.e. Repository may be nil, and ChangeLog empty.
+	Base *Result `json:"base,omitempty"`
 }
 
 // Diff diffs the provided images.
-func Diff(x, y string, options *Options) ([]*Change, error) {
+func Diff(x, y string, options *Options) (*Result, error) {
+	return DiffContext(context.Background(), x, y, options)
+}
+
+// DiffContext diffs the provided images, until the provided context is done,
+// e.g. as the request for the diff timed out.
+func DiffContext(ctx context.Context, x, y string, options *Options) (*Result, error) {
+	step := metrics.StartStep(metrics.Diff)
+	result, err := diff(ctx, x, y, options, 0)
+	return result, step.Done(err)
+}
+
+// diff diffs the provided images, depth being the number of base images
+// these are of, i.e. 0 for the images to diff.
+func diff(ctx context.Context, x, y string, options *Options, depth int) (*Result, error) {
+	step := metrics.StartStep(metrics.Docker)
 	docker, err := client.NewEnvClient()
-	if err != nil {
+	if err := step.Done(err); err != nil {
 		return nil, err
 	}
-	if err := pull(docker, x, options.DockerConfigPath); err != nil {
+	step = metrics.StartStep(metrics.Pull)
+	if err := step.Done(pull(ctx, docker, x, options)); err != nil {
 		return nil, err
 	}
-	if err := pull(docker, y, options.DockerConfigPath); err != nil {
+	step = metrics.StartStep(metrics.Pull)
+	if err := step.Done(pull(ctx, docker, y, options)); err != nil {
 		return nil, err
 	}
-	xLabels, err := imageLabels(docker, x)
-	if err != nil {
+	step = metrics.StartStep(metrics.Inspect)
+	xInspect, err := imageInspect(ctx, docker, x)
+	if err := step.Done(err); err != nil {
 		return nil, err
 	}
-	yLabels, err := imageLabels(docker, y)
-	if err != nil {
+	step = metrics.StartStep(metrics.Inspect)
+	yInspect, err := imageInspect(ctx, docker, y)
+	if err := step.Done(err); err != nil {
 		return nil, err
 	}
-	xRepo, xRev, err := repoAndRevision(xLabels)
-	if err != nil {
+	result := &Result{
+		X:         x,
+		Y:         y,
+		ChangeLog: []*Change{},
+		Issues:    []*issue.Issue{},
+	}
+	if err := sourceDiff(ctx, xInspect, yInspect, options, result); err != nil {
+		if depth == 0 {
+			return nil, err
+		}
+		// Base images, e.g. official ones, are often not labelled with their
+		// source code repositories, while their other changes, e.g. to their
+		// packages, are still worth listing, hence:
+		log.WithFields(log.Fields{"x": x, "y": y}).Warnf("failed to list changes between base images: %v", err)
+	}
+	if options.ConfigDiff {
+		result.Config = ConfigDiff(xInspect.Config, yInspect.Config)
+	}
+	if err := imageDiff(ctx, docker, xInspect, yInspect, options, result); err != nil {
 		return nil, err
 	}
-	yRepo, yRev, err := repoAndRevision(yLabels)
+	if options.BaseDiff && depth < maxBaseDepth {
+		result.Base = baseDiff(ctx, docker, xInspect, yInspect, options, depth+1)
+	}
+	return result, nil
+}
+
+// sourceDiff lists the changes between the revisions of the source code
+// repository the provided images were built from, into the provided result.
+func sourceDiff(ctx context.Context, xInspect, yInspect *types.ImageInspect, options *Options, result *Result) error {
+	step := metrics.StartStep(metrics.Labels)
+	xRepo, xRev, err := repoAndRevision(xInspect.Config.Labels)
+	if err := step.Done(err); err != nil {
+		return err
+	}
+	step = metrics.StartStep(metrics.Labels)
+	yRepo, yRev, err := repoAndRevision(yInspect.Config.Labels)
+	if err == nil {
+		err = validate(xRepo, yRepo)
+	}
+	if err := step.Done(err); err != nil {
+		return err
+	}
+	xCommit, yCommit, err := revisions(ctx, xRepo, xRev, yRev, options)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	if err := validate(xRepo, yRepo); err != nil {
-		return nil, err
+	step = metrics.StartStep(metrics.History)
+	changeLog, err := ChangeLog(xCommit, yCommit, options)
+	if err := step.Done(err); err != nil {
+		return err
+	}
+	result.Repository = xRepo
+	result.XRevision = xCommit.Hash.String()
+	result.YRevision = yCommit.Hash.String()
+	result.ChangeLog = changeLog
+	result.Issues = issues(changeLog, xRepo, options.IssueTrackers)
+	if options.SubmoduleDiff {
+		result.Submodules = submoduleDiff(ctx, xRepo, xCommit, yCommit, options)
+	}
+	if options.DependencyDiff {
+		step = metrics.StartStep(metrics.Dependencies)
+		result.Dependencies, err = dependencies.Diff(xCommit, yCommit)
+		return step.Done(err)
 	}
-	r, err := xRepo.Clone(options.GitOptions)
+	return nil
+}
+
+// revisions resolves the provided revisions of the provided repository to
+// commits, cloning it first.
+func revisions(ctx context.Context, repo *repository.GitRepository, xRev, yRev string, options *Options) (*object.Commit, *object.Commit, error) {
+	r, err := cloneRepository(ctx, repo, options)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	step := metrics.StartStep(metrics.Revision)
+	xCommit, yCommit, err := commits(r, xRev, yRev)
+	if err != nil && options.Clones != nil {
+		// Shared clones may predate the revisions to diff, e.g. in long-running servers, hence:
+		log.WithField("repository", repo).Info("revision not found in shared clone, fetching it")
+		if r, err = options.Clones.Refresh(ctx, repo, options.GitOptions, r); err != nil {
+			return nil, nil, err
+		}
+		step = metrics.StartStep(metrics.Revision)
+		xCommit, yCommit, err = commits(r, xRev, yRev)
+	}
+	if err := step.Done(err); err != nil {
+		return nil, nil, err
+	}
+	return xCommit, yCommit, nil
+}
+
+func commits(r *git.Repository, xRev, yRev string) (*object.Commit, *object.Commit, error) {
 	xCommit, err := commit(r, xRev)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	yCommit, err := commit(r, yRev)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return changeLog(xCommit, yCommit)
+	return xCommit, yCommit, nil
 }
 
-func pull(docker *client.Client, imageName, dockerConfigPath string) error {
+func cloneRepository(ctx context.Context, repo *repository.GitRepository, options *Options) (*git.Repository, error) {
+	if options.Clones != nil {
+		return options.Clones.Clone(ctx, repo, options.GitOptions)
+	}
+	step := metrics.StartStep(metrics.Clone)
+	r, err := repo.Clone(ctx, options.GitOptions)
+	return r, step.Done(err)
+}
+
+func issues(changeLog []*Change, repo *repository.GitRepository, trackers []*issue.Tracker) []*issue.Issue {
+	extractor := issue.NewExtractor(append([]*issue.Tracker{issue.ClosingKeywords(repo)}, trackers...)...)
+	for _, change := range changeLog {
+		extractor.Add(change.Revision, change.Message)
+	}
+	return extractor.Issues()
+}
+
+func pull(ctx context.Context, docker *client.Client, imageName string, options *Options) error {
 	logger := log.WithFields(log.Fields{"image": imageName})
 	// Pulling images is pretty slow (i.e. takes a few seconds), even if the
 	// image is already present locally. We therefore check if there are
 	// already present locally first.
-	exists, err := imageExistsLocally(docker, imageName)
+	exists, err := imageExistsLocally(ctx, docker, imageName)
 	if err != nil {
 		return err
 	}
@@ -91,10 +285,10 @@ func pull(docker *client.Client, imageName, dockerConfigPath string) error {
 		return nil
 	}
 	logger.Info("pulling image")
-	resp, err := docker.ImagePull(context.Background(), imageName, types.ImagePullOptions{
+	resp, err := docker.ImagePull(ctx, imageName, types.ImagePullOptions{
 		PrivilegeFunc: func() (string, error) {
 			logger.Errorf("failed to pull image")
-			return getDockerCredentials(dockerConfigPath, imageName)
+			return getDockerCredentials(imageName, options)
 		},
 	})
 	if err != nil {
@@ -103,11 +297,11 @@ func pull(docker *client.Client, imageName, dockerConfigPath string) error {
 		// hence the above PrivilegeFunc will not be called, and we need to
 		// provide credentials ourselves.
 		if strings.Contains(err.Error(), "unauthorized:") {
-			credentials, err := getDockerCredentials(dockerConfigPath, imageName)
+			credentials, err := getDockerCredentials(imageName, options)
 			if err != nil {
 				return err
 			}
-			resp, err = docker.ImagePull(context.Background(), imageName, types.ImagePullOptions{
+			resp, err = docker.ImagePull(ctx, imageName, types.ImagePullOptions{
 				RegistryAuth: credentials,
 			})
 			if err != nil {
@@ -122,25 +316,25 @@ func pull(docker *client.Client, imageName, dockerConfigPath string) error {
 	return jsonmessage.DisplayJSONMessagesStream(resp, ioutil.Discard, fd, isTerminal, nil)
 }
 
-func imageExistsLocally(docker *client.Client, imageName string) (bool, error) {
-	images, err := imageList(docker, imageName)
+func imageExistsLocally(ctx context.Context, docker *client.Client, imageName string) (bool, error) {
+	images, err := imageList(ctx, docker, imageName)
 	if err != nil {
 		return false, err
 	}
 	return len(images) > 0, nil
 }
 
-func imageList(docker *client.Client, imageName string) ([]types.ImageSummary, error) {
+func imageList(ctx context.Context, docker *client.Client, imageName string) ([]types.ImageSummary, error) {
 	args := filters.NewArgs()
 	args.Add("reference", imageName)
-	return docker.ImageList(context.Background(), types.ImageListOptions{
+	return docker.ImageList(ctx, types.ImageListOptions{
 		Filters: args,
 	})
 }
 
-func getDockerCredentials(dockerConfigPath, imageName string) (string, error) {
-	if dockerConfigPath != "" {
-		creds, err := getDockerCredentialsFrom(dockerConfigPath, imageName)
+func getDockerCredentials(imageName string, options *Options) (string, error) {
+	if options.DockerConfigPath != "" {
+		creds, err := getDockerCredentialsFrom(options.DockerConfigPath, imageName)
 		if err == nil {
 			return creds, nil
 		}
@@ -149,6 +343,9 @@ func getDockerCredentials(dockerConfigPath, imageName string) (string, error) {
 	if err == nil {
 		return creds, nil
 	}
+	if options.NonInteractive {
+		return "", fmt.Errorf("failed to authenticate to pull %v: no credentials for %v found in Docker's configuration", imageName, image.Image(imageName).Registry())
+	}
 	return askForCredentials(imageName)
 }
 
@@ -197,12 +394,15 @@ func encodeAuthConfig(authConfig types.AuthConfig) (string, error) {
 	return base64.URLEncoding.EncodeToString(bytes), nil
 }
 
-func imageLabels(docker *client.Client, imageName string) (map[string]string, error) {
-	inspect, _, err := docker.ImageInspectWithRaw(context.Background(), imageName)
+func imageInspect(ctx context.Context, docker *client.Client, imageName string) (*types.ImageInspect, error) {
+	inspect, _, err := docker.ImageInspectWithRaw(ctx, imageName)
 	if err != nil {
 		return nil, err
 	}
-	return inspect.Config.Labels, nil
+	if inspect.Config == nil {
+		inspect.Config = &container.Config{}
+	}
+	return &inspect, nil
 }
 
 func repoAndRevision(labels map[string]string) (*repository.GitRepository, string, error) {
@@ -264,24 +464,3 @@ func commit(r *git.Repository, shortHash string) (*object.Commit, error) {
 	}
 	return commit, nil
 }
-
-// Change encapsulates the revision number and commit message for a code change.
-type Change struct {
-	Revision string
-	Message  string
-}
-
-func changeLog(xCommit, yCommit *object.Commit) ([]*Change, error) {
-	changeLog := []*Change{}
-	err := object.NewCommitPostorderIter(yCommit, nil).ForEach(func(c *object.Commit) error {
-		if c.Hash == xCommit.Hash {
-			return errFound
-		}
-		changeLog = append(changeLog, &Change{Revision: c.Hash.String(), Message: c.Message})
-		return nil
-	})
-	if err == nil || err != errFound {
-		return nil, fmt.Errorf("commit with hash [%s] could not be found: %s", xCommit.Hash, err)
-	}
-	return changeLog, nil
-}
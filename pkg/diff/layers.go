@@ -0,0 +1,173 @@
+package diff
+
+import (
+	"context"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	log "github.com/sirupsen/logrus"
+	"github.com/weaveworks-experiments/imagediff/pkg/layers"
+	"github.com/weaveworks-experiments/imagediff/pkg/metrics"
+	"github.com/weaveworks-experiments/imagediff/pkg/packages"
+)
+
+// imageDiff compares the layers, files and packages of the provided images, as enabled by the provided options.
+func imageDiff(ctx context.Context, docker *client.Client, x, y *types.ImageInspect, options *Options, result *Result) error {
+	if options.LayerDiff {
+		step := metrics.StartStep(metrics.Layers)
+		diff, err := layerDiff(ctx, docker, x, y)
+		if err := step.Done(err); err != nil {
+			return err
+		}
+		result.Layers = diff
+	}
+	if !options.FileDiff && !options.PackageDiff {
+		return nil
+	}
+	// Images sharing all their layers have the same files, hence:
+	if sameLayers(x, y) {
+		if options.PackageDiff {
+			result.Packages = []*packages.Change{}
+		}
+		return nil
+	}
+	var keep func(string) bool
+	if options.PackageDiff {
+		keep = packages.IsDatabase
+	}
+	step := metrics.StartStep(metrics.Save)
+	xFiles, yFiles, err := filesystems(ctx, docker, x.ID, y.ID, keep)
+	if err := step.Done(err); err != nil {
+		return err
+	}
+	if options.FileDiff && result.Layers != nil {
+		result.Layers.Files = layers.CompareFiles(xFiles, yFiles)
+	}
+	if options.PackageDiff {
+		result.Packages = packageDiff(xFiles, yFiles, x.ID, y.ID)
+	}
+	return nil
+}
+
+// layerDiff compares the layers of the provided images.
+func layerDiff(ctx context.Context, docker *client.Client, x, y *types.ImageInspect) (*layers.Diff, error) {
+	xLayers, err := imageLayers(ctx, docker, x)
+	if err != nil {
+		return nil, err
+	}
+	yLayers, err := imageLayers(ctx, docker, y)
+	if err != nil {
+		return nil, err
+	}
+	return layers.Compare(xLayers, yLayers), nil
+}
+
+func sameLayers(x, y *types.ImageInspect) bool {
+	return len(x.RootFS.Layers) == len(y.RootFS.Layers) && isPrefix(x.RootFS.Layers, y.RootFS.Layers)
+}
+
+// imageLayers lists the layers of the provided image, from the bottom one,
+// along with their sizes and the instructions which created them, as
+// provided by the image's history.
+func imageLayers(ctx context.Context, docker *client.Client, inspect *types.ImageInspect) ([]*layers.Layer, error) {
+	imageLayers := make([]*layers.Layer, len(inspect.RootFS.Layers))
+	for i, digest := range inspect.RootFS.Layers {
+		imageLayers[i] = &layers.Layer{Digest: digest}
+	}
+	history, err := docker.ImageHistory(ctx, inspect.ID)
+	if err != nil {
+		return nil, err
+	}
+	entries := layerEntries(history)
+	if len(entries) != len(imageLayers) {
+		log.WithField("image", inspect.ID).Warn("failed to match the image's history with its layers, layers' sizes are unknown")
+		return imageLayers, nil
+	}
+	for i, entry := range entries {
+		imageLayers[i].Size = entry.Size
+		imageLayers[i].CreatedBy = strings.TrimSpace(strings.TrimPrefix(entry.CreatedBy, "/bin/sh -c #(nop) "))
+	}
+	return imageLayers, nil
+}
+
+// layerEntries picks the entries of the provided history, most recent first,
+// which created layers, from the bottom one. The history does not tell
+// these apart from the entries which only changed the image's
+// configuration, e.g. ENV, other than by their sizes, which are 0 for the
+// latter, but also for layers not changing any file, e.g. WORKDIR, hence
+// also relying on their instructions.
+func layerEntries(history []image.HistoryResponseItem) []image.HistoryResponseItem {
+	entries := []image.HistoryResponseItem{}
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Size > 0 || !configOnly(history[i].CreatedBy) {
+			entries = append(entries, history[i])
+		}
+	}
+	return entries
+}
+
+// configOnly tells whether the provided instruction only changes images' configuration.
+func configOnly(createdBy string) bool {
+	const nop = "#(nop) "
+	i := strings.Index(createdBy, nop)
+	if i == -1 {
+		// BuildKit records instructions without the shell:
+		for _, instruction := range []string{"ENV ", "LABEL ", "CMD ", "ENTRYPOINT ", "EXPOSE ", "USER ", "VOLUME ", "STOPSIGNAL ", "HEALTHCHECK ", "SHELL ", "ARG ", "ONBUILD ", "MAINTAINER "} {
+			if strings.HasPrefix(createdBy, instruction) {
+				return true
+			}
+		}
+		return false
+	}
+	instruction := strings.TrimSpace(createdBy[i+len(nop):])
+	for _, layered := range []string{"ADD ", "COPY ", "WORKDIR "} {
+		if strings.HasPrefix(instruction, layered) {
+			return false
+		}
+	}
+	return true
+}
+
+// filesystems reads the filesystems of the images with IDs x and y, keeping the content of the files to keep, if any.
+func filesystems(ctx context.Context, docker *client.Client, x, y string, keep func(string) bool) (layers.Filesystem, layers.Filesystem, error) {
+	// Saving both images at once reads their shared layers only once:
+	r, err := docker.ImageSave(ctx, []string{x, y})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer r.Close()
+	archive, err := layers.ReadArchive(r, keep)
+	if err != nil {
+		return nil, nil, err
+	}
+	xFiles, err := archive.Filesystem(x)
+	if err != nil {
+		return nil, nil, err
+	}
+	yFiles, err := archive.Filesystem(y)
+	if err != nil {
+		return nil, nil, err
+	}
+	return xFiles, yFiles, nil
+}
+
+// packageDiff compares the packages installed in the provided images'
+// filesystems, or returns nil if either's cannot be listed, e.g. for
+// unsupported databases, rather than report all the other's as added or
+// removed. This only warns on failures, as the rest of the diff is still
+// worth reporting.
+func packageDiff(xFiles, yFiles layers.Filesystem, xID, yID string) []*packages.Change {
+	xInstalled, err := packages.Read(xFiles)
+	if err != nil {
+		log.WithField("image", xID).Warnf("failed to list packages, not comparing these: %v", err)
+		return nil
+	}
+	yInstalled, err := packages.Read(yFiles)
+	if err != nil {
+		log.WithField("image", yID).Warnf("failed to list packages, not comparing these: %v", err)
+		return nil
+	}
+	return packages.Compare(xInstalled, yInstalled)
+}
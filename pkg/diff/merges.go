@@ -0,0 +1,93 @@
+package diff
+
+// MergeGroup ties a change which landed on the mainline to the changes it merged in, if any.
+type MergeGroup struct {
+	Change *Change
+	Merged []*Change
+}
+
+// GroupByMerge groups the provided change log by the changes which landed on
+// the mainline, i.e. the changes found by following first parents from the
+// most recent change. Merge commits are grouped with the changes they brought
+// in, e.g. the commits of a pull request. The order of the change log is
+// preserved, both across and within groups.
+func GroupByMerge(changeLog []*Change) []*MergeGroup {
+	changes := make(map[string]*Change, len(changeLog))
+	for _, change := range changeLog {
+		changes[change.Revision] = change
+	}
+	mainline := firstParents(head(changeLog), changes)
+	owners := map[string]string{}
+	for _, change := range changeLog {
+		if !mainline[change.Revision] || len(change.Parents) < 2 {
+			continue
+		}
+		base := reachable(change.Parents[:1], changes)
+		for revision := range reachable(change.Parents[1:], changes) {
+			if _, owned := owners[revision]; !owned && !base[revision] && !mainline[revision] {
+				owners[revision] = change.Revision
+			}
+		}
+	}
+	groups := []*MergeGroup{}
+	groupsByRevision := map[string]*MergeGroup{}
+	for _, change := range changeLog {
+		if _, owned := owners[change.Revision]; owned {
+			continue
+		}
+		group := &MergeGroup{Change: change, Merged: []*Change{}}
+		groupsByRevision[change.Revision] = group
+		groups = append(groups, group)
+	}
+	for _, change := range changeLog {
+		if owner, owned := owners[change.Revision]; owned {
+			group := groupsByRevision[owner]
+			group.Merged = append(group.Merged, change)
+		}
+	}
+	return groups
+}
+
+// head finds the most recent change, i.e. the one no other change descends from.
+func head(changeLog []*Change) *Change {
+	isParent := map[string]bool{}
+	for _, change := range changeLog {
+		for _, parent := range change.Parents {
+			isParent[parent] = true
+		}
+	}
+	for _, change := range changeLog {
+		if !isParent[change.Revision] {
+			return change
+		}
+	}
+	return nil
+}
+
+func firstParents(change *Change, changes map[string]*Change) map[string]bool {
+	revisions := map[string]bool{}
+	for change != nil && !revisions[change.Revision] {
+		revisions[change.Revision] = true
+		if len(change.Parents) == 0 {
+			break
+		}
+		change = changes[change.Parents[0]]
+	}
+	return revisions
+}
+
+func reachable(from []string, changes map[string]*Change) map[string]bool {
+	revisions := map[string]bool{}
+	stack := append([]string{}, from...)
+	for len(stack) > 0 {
+		revision := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		change, ok := changes[revision]
+		if !ok || revisions[revision] {
+			continue
+		}
+		revisions[revision] = true
+		stack = append(stack, change.Parents...)
+	}
+	return revisions
+}
@@ -0,0 +1,42 @@
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+)
+
+func TestGroupByMerge(t *testing.T) {
+	// History, from oldest to most recent:
+	//   a - b ----- m - e
+	//    \         /
+	//     c ---- d
+	e := &diff.Change{Revision: "e", Message: "Bump version", Parents: []string{"m"}}
+	m := &diff.Change{Revision: "m", Message: "Merge pull request #40 from foo/bar", Parents: []string{"b", "d"}}
+	d := &diff.Change{Revision: "d", Message: "Add bar", Parents: []string{"c"}}
+	c := &diff.Change{Revision: "c", Message: "Add foo", Parents: []string{"a"}}
+	b := &diff.Change{Revision: "b", Message: "Fix typo", Parents: []string{"a"}}
+
+	groups := diff.GroupByMerge([]*diff.Change{e, m, d, c, b})
+	assert.Equal(t, []*diff.MergeGroup{
+		{Change: e, Merged: []*diff.Change{}},
+		{Change: m, Merged: []*diff.Change{d, c}},
+		{Change: b, Merged: []*diff.Change{}},
+	}, groups)
+}
+
+func TestGroupByMergeWithoutMerges(t *testing.T) {
+	b := &diff.Change{Revision: "b", Message: "Fix typo", Parents: []string{"a"}}
+	c := &diff.Change{Revision: "c", Message: "Add foo", Parents: []string{"b"}}
+
+	groups := diff.GroupByMerge([]*diff.Change{b, c})
+	assert.Equal(t, []*diff.MergeGroup{
+		{Change: b, Merged: []*diff.Change{}},
+		{Change: c, Merged: []*diff.Change{}},
+	}, groups)
+}
+
+func TestGroupByMergeEmpty(t *testing.T) {
+	assert.Equal(t, []*diff.MergeGroup{}, diff.GroupByMerge([]*diff.Change{}))
+}
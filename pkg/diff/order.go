@@ -0,0 +1,123 @@
+package diff
+
+import (
+	"fmt"
+	"time"
+)
+
+// Order determines the order changes are listed in.
+type Order string
+
+const (
+	// DefaultOrder lists changes in the order the history is walked in, from
+	// the most recent change, visiting merged branches before the mainline.
+	DefaultOrder Order = ""
+	// TopoOrder lists changes like "git log --topo-order" would, i.e. no
+	// change before all its children, and without intermixing the changes of
+	// different branches.
+	TopoOrder Order = "topo"
+	// AuthorDateOrder lists changes like "git log --author-date-order" would,
+	// i.e. no change before all its children, and otherwise most recently
+	// authored first.
+	AuthorDateOrder Order = "author-date"
+	// CommitterDateOrder lists changes like "git log --date-order" would, i.e.
+	// no change before all its children, and otherwise most recently
+	// committed first.
+	CommitterDateOrder Order = "committer-date"
+)
+
+// Orders lists all supported orders, but the default one.
+var Orders = []Order{TopoOrder, AuthorDateOrder, CommitterDateOrder}
+
+// ParseOrder parses the provided order's name, the empty string being the default order.
+func ParseOrder(name string) (Order, error) {
+	order := Order(name)
+	if order == DefaultOrder {
+		return order, nil
+	}
+	for _, supported := range Orders {
+		if order == supported {
+			return order, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported order [%v], must be one of: %v", name, Orders)
+}
+
+// Sort sorts the provided change log in the provided order. Changes are
+// expected to be listed from the most recent one, as ChangeLog does, and ties
+// are broken by keeping their relative order, so that sorting is deterministic.
+func Sort(changeLog []*Change, order Order) ([]*Change, error) {
+	switch order {
+	case DefaultOrder:
+		return changeLog, nil
+	case TopoOrder:
+		return topoSort(changeLog, nil), nil
+	case AuthorDateOrder:
+		return topoSort(changeLog, func(c *Change) time.Time { return c.Author.When }), nil
+	case CommitterDateOrder:
+		return topoSort(changeLog, func(c *Change) time.Time { return c.Committer.When }), nil
+	default:
+		_, err := ParseOrder(string(order))
+		return nil, err
+	}
+}
+
+// Reverse reverses the provided change log, e.g. to list the oldest change first.
+func Reverse(changeLog []*Change) []*Change {
+	reversed := make([]*Change, len(changeLog))
+	for i, change := range changeLog {
+		reversed[len(changeLog)-1-i] = change
+	}
+	return reversed
+}
+
+// topoSort lists changes only once all their children have been listed.
+// Amongst the changes ready to be listed, if date is provided, the most recent
+// one is listed first, otherwise the last one to have become ready is, so that
+// branches are listed one after the other, like Git does.
+func topoSort(changeLog []*Change, date func(*Change) time.Time) []*Change {
+	changes := make(map[string]*Change, len(changeLog))
+	index := make(map[string]int, len(changeLog))
+	for i, change := range changeLog {
+		changes[change.Revision] = change
+		index[change.Revision] = i
+	}
+	children := map[string]int{}
+	for _, change := range changeLog {
+		for _, parent := range change.Parents {
+			if _, ok := changes[parent]; ok {
+				children[parent]++
+			}
+		}
+	}
+	ready := []*Change{}
+	for i := len(changeLog) - 1; i >= 0; i-- {
+		if children[changeLog[i].Revision] == 0 {
+			ready = append(ready, changeLog[i])
+		}
+	}
+	sorted := make([]*Change, 0, len(changeLog))
+	for len(ready) > 0 {
+		next := len(ready) - 1
+		if date != nil {
+			for i, change := range ready {
+				if date(change).After(date(ready[next])) || (date(change).Equal(date(ready[next])) && index[change.Revision] < index[ready[next].Revision]) {
+					next = i
+				}
+			}
+		}
+		change := ready[next]
+		ready = append(ready[:next], ready[next+1:]...)
+		sorted = append(sorted, change)
+		for _, parent := range change.Parents {
+			if _, ok := changes[parent]; !ok {
+				continue
+			}
+			children[parent]--
+			if children[parent] == 0 {
+				ready = append(ready, changes[parent])
+			}
+		}
+	}
+	return sorted
+}
@@ -0,0 +1,97 @@
+package diff_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+)
+
+// skewedChangeLog returns the change log for the following history, in the
+// default order, and where b was authored last but committed first:
+//
+//	a - b ----- m - e
+//	 \         /
+//	  c ---- d
+func skewedChangeLog() []*diff.Change {
+	at := func(hour int) diff.Signature {
+		return diff.Signature{When: time.Date(2018, time.June, 1, hour, 0, 0, 0, time.UTC)}
+	}
+	return []*diff.Change{
+		{Revision: "e", Parents: []string{"m"}, Author: at(9), Committer: at(9)},
+		{Revision: "m", Parents: []string{"b", "d"}, Author: at(8), Committer: at(8), Merge: true},
+		{Revision: "d", Parents: []string{"c"}, Author: at(5), Committer: at(5)},
+		{Revision: "c", Parents: []string{"a"}, Author: at(4), Committer: at(4)},
+		{Revision: "b", Parents: []string{"a"}, Author: at(7), Committer: at(3)},
+	}
+}
+
+func revisions(changeLog []*diff.Change) []string {
+	revisions := []string{}
+	for _, change := range changeLog {
+		revisions = append(revisions, change.Revision)
+	}
+	return revisions
+}
+
+func TestSortDefaultOrder(t *testing.T) {
+	changeLog, err := diff.Sort(skewedChangeLog(), diff.DefaultOrder)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e", "m", "d", "c", "b"}, revisions(changeLog))
+}
+
+func TestSortTopoOrder(t *testing.T) {
+	changeLog, err := diff.Sort(skewedChangeLog(), diff.TopoOrder)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e", "m", "d", "c", "b"}, revisions(changeLog))
+}
+
+func TestSortAuthorDateOrder(t *testing.T) {
+	changeLog, err := diff.Sort(skewedChangeLog(), diff.AuthorDateOrder)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e", "m", "b", "d", "c"}, revisions(changeLog))
+}
+
+func TestSortCommitterDateOrder(t *testing.T) {
+	changeLog, err := diff.Sort(skewedChangeLog(), diff.CommitterDateOrder)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e", "m", "d", "c", "b"}, revisions(changeLog))
+}
+
+func TestSortNeverListsParentsBeforeChildren(t *testing.T) {
+	changeLog := skewedChangeLog()
+	// Clock skew: d was committed after its descendants, m and e.
+	changeLog[2].Committer = diff.Signature{When: time.Date(2018, time.June, 1, 11, 0, 0, 0, time.UTC)}
+	changeLog, err := diff.Sort(changeLog, diff.CommitterDateOrder)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e", "m", "d", "c", "b"}, revisions(changeLog))
+}
+
+func TestSortUnsupportedOrder(t *testing.T) {
+	_, err := diff.Sort(skewedChangeLog(), diff.Order("random"))
+	assert.Error(t, err)
+}
+
+func TestReverse(t *testing.T) {
+	changeLog, err := diff.Sort(skewedChangeLog(), diff.AuthorDateOrder)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c", "d", "b", "m", "e"}, revisions(diff.Reverse(changeLog)))
+}
+
+func TestParseOrder(t *testing.T) {
+	for _, name := range []string{"", "topo", "author-date", "committer-date"} {
+		order, err := diff.ParseOrder(name)
+		assert.NoError(t, err)
+		assert.Equal(t, diff.Order(name), order)
+	}
+	_, err := diff.ParseOrder("date")
+	assert.Error(t, err)
+}
+
+func TestChangeLogReverse(t *testing.T) {
+	h := mergedHistory(t)
+	changeLog, err := diff.ChangeLog(h.commits["a"], h.commits["e"], &diff.Options{Order: diff.TopoOrder, Reverse: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b", "c", "d", "m", "e"}, messages(changeLog))
+}
@@ -0,0 +1,184 @@
+package diff
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/weaveworks-experiments/imagediff/pkg/issue"
+	"github.com/weaveworks-experiments/imagediff/pkg/metrics"
+	"github.com/weaveworks-experiments/imagediff/pkg/repository"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+const gitModulesFile = ".gitmodules"
+
+// Submodule is a submodule whose revision changed between two revisions of
+// its superproject, i.e. which was bumped.
+type Submodule struct {
+	// Path is the submodule's path in its superproject.
+	Path string `json:"path"`
+	// Repository is nil if the submodule's repository could not be found, or
+	// its changes listed.
+	Repository *repository.GitRepository `json:"repository"`
+	XRevision  string                    `json:"xRevision"`
+	YRevision  string                    `json:"yRevision"`
+	ChangeLog  []*Change                 `json:"changeLog"`
+	// Issues are the issues referenced by the above changes.
+	Issues []*issue.Issue `json:"issues"`
+}
+
+// Submodules lists the submodules bumped between the provided revisions of
+// the provided superproject, ordered by path, with their repositories, as
+// configured in .gitmodules, relative URLs being relative to the
+// superproject's, or nil if not found. Submodules added or removed are not
+// listed, as these have no changes to list.
+func Submodules(superproject *repository.GitRepository, x, y *object.Commit) ([]*Submodule, error) {
+	xTree, err := x.Tree()
+	if err != nil {
+		return nil, err
+	}
+	yTree, err := y.Tree()
+	if err != nil {
+		return nil, err
+	}
+	submodules := []*Submodule{}
+	if err := bumped(xTree, yTree, "", &submodules); err != nil {
+		return nil, err
+	}
+	if len(submodules) == 0 {
+		return submodules, nil
+	}
+	urls := map[string]string{}
+	for _, tree := range []*object.Tree{xTree, yTree} {
+		if err := gitModules(tree, urls); err != nil {
+			return nil, err
+		}
+	}
+	for _, submodule := range submodules {
+		url, ok := urls[submodule.Path]
+		if !ok {
+			log.WithField("submodule", submodule.Path).Warn("submodule not found in .gitmodules")
+			continue
+		}
+		repo, err := repository.New(resolveURL(superproject, url))
+		if err != nil {
+			log.WithField("submodule", submodule.Path).Warnf("failed to find submodule's repository: %v", err)
+			continue
+		}
+		submodule.Repository = repo
+	}
+	return submodules, nil
+}
+
+// bumped lists the gitlinks whose revision changed between the provided
+// trees, into the provided submodules, skipping identical subtrees.
+func bumped(x, y *object.Tree, dir string, submodules *[]*Submodule) error {
+	xEntries := map[string]object.TreeEntry{}
+	for _, entry := range x.Entries {
+		xEntries[entry.Name] = entry
+	}
+	for _, yEntry := range y.Entries {
+		xEntry, ok := xEntries[yEntry.Name]
+		if !ok || xEntry.Mode != yEntry.Mode || xEntry.Hash == yEntry.Hash {
+			continue
+		}
+		switch yEntry.Mode {
+		case filemode.Submodule:
+			*submodules = append(*submodules, &Submodule{
+				Path:      path.Join(dir, yEntry.Name),
+				XRevision: xEntry.Hash.String(),
+				YRevision: yEntry.Hash.String(),
+				ChangeLog: []*Change{},
+				Issues:    []*issue.Issue{},
+			})
+		case filemode.Dir:
+			xSubtree, err := x.Tree(xEntry.Name)
+			if err != nil {
+				return err
+			}
+			ySubtree, err := y.Tree(yEntry.Name)
+			if err != nil {
+				return err
+			}
+			if err := bumped(xSubtree, ySubtree, path.Join(dir, yEntry.Name), submodules); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// gitModules reads the URLs of the submodules configured in the provided
+// tree's .gitmodules, if any, by path, into the provided URLs.
+func gitModules(tree *object.Tree, urls map[string]string) error {
+	file, err := tree.File(gitModulesFile)
+	if err == object.ErrFileNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	contents, err := file.Contents()
+	if err != nil {
+		return err
+	}
+	modules := config.NewModules()
+	if err := modules.Unmarshal([]byte(contents)); err != nil {
+		return err
+	}
+	for _, module := range modules.Submodules {
+		urls[module.Path] = module.URL
+	}
+	return nil
+}
+
+// resolveURL resolves the provided submodule's URL against its
+// superproject's, if relative, e.g. "../library.git", as Git does.
+func resolveURL(superproject *repository.GitRepository, url string) string {
+	if !strings.HasPrefix(url, "./") && !strings.HasPrefix(url, "../") {
+		return url
+	}
+	return "https://" + path.Join(superproject.Host, superproject.Organization, superproject.Repository, url)
+}
+
+// submoduleDiff lists the changes between the revisions of the submodules
+// bumped between the provided revisions of the provided superproject. This
+// only warns on failures, as the superproject's changes are still worth
+// reporting.
+func submoduleDiff(ctx context.Context, superproject *repository.GitRepository, x, y *object.Commit, options *Options) []*Submodule {
+	step := metrics.StartStep(metrics.Submodules)
+	submodules, err := Submodules(superproject, x, y)
+	if err := step.Done(err); err != nil {
+		log.WithField("repository", superproject).Warnf("failed to list bumped submodules: %v", err)
+		return nil
+	}
+	for _, submodule := range submodules {
+		if submodule.Repository == nil {
+			continue
+		}
+		if err := submoduleChangeLog(ctx, submodule, options); err != nil {
+			log.WithFields(log.Fields{"submodule": submodule.Path, "repository": submodule.Repository}).Warnf("failed to list changes of submodule: %v", err)
+			submodule.Repository = nil
+		}
+	}
+	return submodules
+}
+
+func submoduleChangeLog(ctx context.Context, submodule *Submodule, options *Options) error {
+	xCommit, yCommit, err := revisions(ctx, submodule.Repository, submodule.XRevision, submodule.YRevision, options)
+	if err != nil {
+		return err
+	}
+	step := metrics.StartStep(metrics.History)
+	changeLog, err := ChangeLog(xCommit, yCommit, options)
+	if err := step.Done(err); err != nil {
+		return err
+	}
+	submodule.ChangeLog = changeLog
+	submodule.Issues = issues(changeLog, submodule.Repository, options.IssueTrackers)
+	return nil
+}
@@ -0,0 +1,98 @@
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/issue"
+	"github.com/weaveworks-experiments/imagediff/pkg/repository"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+const gitModules = `[submodule "lib"]
+	path = third_party/lib
+	url = ../lib.git
+[submodule "proto"]
+	path = proto
+	url = git@gitlab.com:group/subgroup/proto.git
+[submodule "unknown"]
+	path = unknown
+	url = file:///srv/git/unknown.git
+`
+
+var superproject = &repository.GitRepository{Host: "github.com", Organization: "weaveworks", Repository: "app"}
+
+func TestSubmodules(t *testing.T) {
+	h := newHistory(t)
+	revisions := map[string]plumbing.Hash{}
+	for _, name := range []string{"lib-x", "lib-y", "proto-x", "proto-y", "static", "unknown-x", "unknown-y"} {
+		revisions[name] = plumbing.ComputeHash(plumbing.CommitObject, []byte(name))
+	}
+	gitlink := func(name, revision string) object.TreeEntry {
+		return object.TreeEntry{Name: name, Mode: filemode.Submodule, Hash: revisions[revision]}
+	}
+	modules := object.TreeEntry{Name: ".gitmodules", Mode: filemode.Regular, Hash: h.blob(gitModules)}
+	x := h.commitTree("x", h.entries(
+		modules,
+		object.TreeEntry{Name: "README.md", Mode: filemode.Regular, Hash: h.blob("app\n")},
+		gitlink("proto", "proto-x"),
+		object.TreeEntry{Name: "third_party", Mode: filemode.Dir, Hash: h.entries(
+			gitlink("lib", "lib-x"),
+			gitlink("removed", "static"),
+			gitlink("static", "static"),
+		)},
+		gitlink("unknown", "unknown-x"),
+	))
+	y := h.commitTree("y", h.entries(
+		modules,
+		object.TreeEntry{Name: "README.md", Mode: filemode.Regular, Hash: h.blob("app\nSubmodules bumped.\n")},
+		gitlink("added", "static"),
+		gitlink("proto", "proto-y"),
+		object.TreeEntry{Name: "third_party", Mode: filemode.Dir, Hash: h.entries(
+			gitlink("lib", "lib-y"),
+			gitlink("static", "static"),
+		)},
+		gitlink("unknown", "unknown-y"),
+	), "x")
+
+	submodules, err := diff.Submodules(superproject, x, y)
+	assert.NoError(t, err)
+	assert.Equal(t, []*diff.Submodule{
+		{
+			Path:       "proto",
+			Repository: &repository.GitRepository{Host: "gitlab.com", Organization: "group/subgroup", Repository: "proto"},
+			XRevision:  revisions["proto-x"].String(),
+			YRevision:  revisions["proto-y"].String(),
+			ChangeLog:  []*diff.Change{},
+			Issues:     []*issue.Issue{},
+		},
+		{
+			Path:       "third_party/lib",
+			Repository: &repository.GitRepository{Host: "github.com", Organization: "weaveworks", Repository: "lib"},
+			XRevision:  revisions["lib-x"].String(),
+			YRevision:  revisions["lib-y"].String(),
+			ChangeLog:  []*diff.Change{},
+			Issues:     []*issue.Issue{},
+		},
+		{
+			Path:      "unknown",
+			XRevision: revisions["unknown-x"].String(),
+			YRevision: revisions["unknown-y"].String(),
+			ChangeLog: []*diff.Change{},
+			Issues:    []*issue.Issue{},
+		},
+	}, submodules)
+}
+
+func TestSubmodulesWithoutSubmodules(t *testing.T) {
+	h := newHistory(t)
+	x := h.commit("x", map[string]string{"README.md": "app\n"})
+	y := h.commit("y", map[string]string{"README.md": "app\nNo submodules.\n"}, "x")
+
+	submodules, err := diff.Submodules(superproject, x, y)
+	assert.NoError(t, err)
+	assert.Empty(t, submodules)
+}
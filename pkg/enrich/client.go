@@ -0,0 +1,202 @@
+package enrich
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/weaveworks-experiments/imagediff/pkg/metrics"
+)
+
+// Client sends GET requests to REST APIs, e.g. GitHub's or GitLab's, and
+// caches their responses, to be gentle with their rate limits.
+type Client struct {
+	// Name of the API, for logs and errors, e.g. "GitHub's API".
+	Name string
+	// Header is added to every request, e.g. to authenticate these.
+	Header http.Header
+	// HTTPClient sends requests. Defaults to a client timing out after 30 seconds.
+	HTTPClient *http.Client
+	// CacheTTL is how long responses are reused for without even asking the
+	// API. Past that, they are revalidated with conditional requests, which
+	// do not count against rate limits when nothing changed.
+	CacheTTL time.Duration
+	// MaxWait is the longest to wait for, when rate limited, before giving up.
+	MaxWait time.Duration
+
+	mutex sync.Mutex
+	cache map[string]*cachedResponse
+}
+
+type cachedResponse struct {
+	etag string
+	body []byte
+	// link is the response's Link header, pointing to the next page, if any.
+	link    string
+	fetched time.Time
+}
+
+// NewClient creates a new client of the provided API, sending the provided headers with every request.
+func NewClient(name string, header http.Header) *Client {
+	return &Client{
+		Name:     name,
+		Header:   header,
+		CacheTTL: 5 * time.Minute,
+		MaxWait:  time.Minute,
+	}
+}
+
+// Get fetches the provided URL and decodes its JSON response into v.
+func (c *Client) Get(url string, v interface{}) error {
+	response, err := c.fetch(url)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(response.body, v)
+}
+
+// GetPage fetches the provided URL and decodes its JSON response into v, and
+// returns the URL of the next page, as per its Link header, or "" if last.
+func (c *Client) GetPage(url string, v interface{}) (string, error) {
+	response, err := c.fetch(url)
+	if err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal(response.body, v); err != nil {
+		return "", err
+	}
+	return nextPage(url, response.link)
+}
+
+var linkRegex = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// nextPage resolves the URL of the next page, if any, from the provided Link header, e.g.:
+//
+//	<https://api.github.com/repositories/1/pulls/2/reviews?page=2>; rel="next", <...?page=3>; rel="last"
+func nextPage(current, link string) (string, error) {
+	matches := linkRegex.FindStringSubmatch(link)
+	if matches == nil {
+		return "", nil
+	}
+	base, err := url.Parse(current)
+	if err != nil {
+		return "", err
+	}
+	next, err := base.Parse(matches[1])
+	if err != nil {
+		return "", err
+	}
+	return next.String(), nil
+}
+
+// maxAttempts bounds how many times a request is retried when rate limited.
+const maxAttempts = 3
+
+func (c *Client) fetch(url string) (*cachedResponse, error) {
+	c.mutex.Lock()
+	cached := c.cache[url]
+	c.mutex.Unlock()
+	if cached != nil && time.Since(cached.fetched) < c.CacheTTL {
+		metrics.CacheRequests.WithLabelValues(metrics.API, metrics.Hit).Inc()
+		return cached, nil
+	}
+	logger := log.WithField("url", url)
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range c.Header {
+			req.Header[key] = values
+		}
+		if cached != nil && cached.etag != "" {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+		resp, err := c.httpClient().Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case resp.StatusCode == http.StatusNotModified && cached != nil:
+			metrics.CacheRequests.WithLabelValues(metrics.API, metrics.Revalidated).Inc()
+			return c.store(url, cached.etag, cached.body, cached.link), nil
+		case resp.StatusCode == http.StatusOK:
+			metrics.CacheRequests.WithLabelValues(metrics.API, metrics.Miss).Inc()
+			return c.store(url, resp.Header.Get("ETag"), body, resp.Header.Get("Link")), nil
+		case isRateLimited(resp):
+			wait := rateLimitWait(resp)
+			if wait > c.MaxWait || attempt >= maxAttempts {
+				return nil, fmt.Errorf("rate limited by %v for %v: %s", c.Name, wait, strings.TrimSpace(string(body)))
+			}
+			logger.WithField("wait", wait).Warnf("rate limited by %v, waiting before retrying", c.Name)
+			time.Sleep(wait)
+		default:
+			return nil, fmt.Errorf("unexpected response from %v for [%v]: %v: %s", c.Name, url, resp.Status, strings.TrimSpace(string(body)))
+		}
+	}
+}
+
+func (c *Client) store(url, etag string, body []byte, link string) *cachedResponse {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.cache == nil {
+		c.cache = map[string]*cachedResponse{}
+	}
+	response := &cachedResponse{etag: etag, body: body, link: link, fetched: time.Now()}
+	c.cache[url] = response
+	return response
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return defaultClient
+}
+
+var defaultClient = &http.Client{Timeout: 30 * time.Second}
+
+// isRateLimited detects both running out of requests for the current window,
+// i.e. GitHub's X-RateLimit-* and GitLab's RateLimit-* headers, and GitHub's
+// secondary rate limits, i.e. abuse detection, which come with a Retry-After
+// header.
+func isRateLimited(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+	return resp.Header.Get("Retry-After") != "" || remaining(resp) == "0"
+}
+
+func remaining(resp *http.Response) string {
+	if value := resp.Header.Get("X-RateLimit-Remaining"); value != "" {
+		return value
+	}
+	return resp.Header.Get("RateLimit-Remaining")
+}
+
+func rateLimitWait(resp *http.Response) time.Duration {
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	for _, header := range []string{"X-RateLimit-Reset", "RateLimit-Reset"} {
+		if reset, err := strconv.ParseInt(resp.Header.Get(header), 10, 64); err == nil {
+			if wait := time.Until(time.Unix(reset, 0)); wait > 0 {
+				return wait
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,26 @@
+package enrich
+
+import (
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/repository"
+)
+
+// Provider provides the metadata of the pull requests (or merge requests)
+// associated with changes, typically from the API of their repository's host,
+// e.g. GitHub or GitLab.
+type Provider interface {
+	// PullRequests lists the pull requests associated with the provided revision of the provided repository.
+	PullRequests(repo *repository.GitRepository, revision string) ([]*diff.PullRequest, error)
+}
+
+// Enrich populates the pull requests associated with each of the provided changes, using the provided provider.
+func Enrich(provider Provider, repo *repository.GitRepository, changeLog []*diff.Change) error {
+	for _, change := range changeLog {
+		pullRequests, err := provider.PullRequests(repo, change.Revision)
+		if err != nil {
+			return err
+		}
+		change.PullRequests = pullRequests
+	}
+	return nil
+}
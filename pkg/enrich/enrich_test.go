@@ -0,0 +1,35 @@
+package enrich_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/enrich"
+	"github.com/weaveworks-experiments/imagediff/pkg/repository"
+)
+
+type fakeProvider map[string][]*diff.PullRequest
+
+func (f fakeProvider) PullRequests(repo *repository.GitRepository, revision string) ([]*diff.PullRequest, error) {
+	pullRequests, ok := f[revision]
+	if !ok {
+		return nil, errors.New("unknown revision")
+	}
+	return pullRequests, nil
+}
+
+func TestEnrich(t *testing.T) {
+	provider := fakeProvider{
+		"91740fb": {{Number: 40, Title: "Kubernetes labels"}},
+		"aa0ff4c": {},
+	}
+	changeLog := []*diff.Change{{Revision: "91740fb"}, {Revision: "aa0ff4c"}}
+	assert.NoError(t, enrich.Enrich(provider, &repository.GitRepository{}, changeLog))
+	assert.Equal(t, provider["91740fb"], changeLog[0].PullRequests)
+	assert.Empty(t, changeLog[1].PullRequests)
+
+	err := enrich.Enrich(provider, &repository.GitRepository{}, []*diff.Change{{Revision: "4756fd6"}})
+	assert.EqualError(t, err, "unknown revision")
+}
@@ -0,0 +1,124 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/enrich"
+	"github.com/weaveworks-experiments/imagediff/pkg/repository"
+)
+
+// PublicAPIURL is the base URL of GitHub's public REST API.
+const PublicAPIURL = "https://api.github.com"
+
+// APIURL guesses the base URL of the REST API of the provided repository's
+// host: GitHub's public API for github.com, and the GitHub Enterprise
+// convention, i.e. "https://<host>/api/v3", otherwise.
+func APIURL(repo *repository.GitRepository) string {
+	if repo.Host == "github.com" {
+		return PublicAPIURL
+	}
+	return fmt.Sprintf("https://%v/api/v3", repo.Host)
+}
+
+// Client fetches the pull requests associated with commits from GitHub's REST API.
+type Client struct {
+	// BaseURL is the base URL of the API, e.g. PublicAPIURL, or "https://github.example.com/api/v3" for GitHub Enterprise.
+	BaseURL string
+	*enrich.Client
+}
+
+// NewClient creates a new client of the provided GitHub REST API. The token
+// authenticates requests, which raises rate limits and grants access to
+// private repositories, and is optional.
+func NewClient(baseURL, token string) *Client {
+	header := http.Header{}
+	header.Set("Accept", "application/vnd.github.groot-preview+json")
+	if token != "" {
+		header.Set("Authorization", "token "+token)
+	}
+	return &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Client:  enrich.NewClient("GitHub's API", header),
+	}
+}
+
+type pullRequest struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	HTMLURL string `json:"html_url"`
+	User    user   `json:"user"`
+	Labels  []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	RequestedReviewers []user `json:"requested_reviewers"`
+}
+
+type user struct {
+	Login string `json:"login"`
+}
+
+type review struct {
+	User user `json:"user"`
+}
+
+// PullRequests lists the pull requests associated with the provided revision of the provided repository.
+func (c *Client) PullRequests(repo *repository.GitRepository, revision string) ([]*diff.PullRequest, error) {
+	var pulls []*pullRequest
+	if err := c.Get(fmt.Sprintf("%v/repos/%v/%v/commits/%v/pulls", c.BaseURL, repo.Organization, repo.Repository, revision), &pulls); err != nil {
+		return nil, err
+	}
+	pullRequests := make([]*diff.PullRequest, len(pulls))
+	for i, pull := range pulls {
+		reviewers, err := c.reviewers(repo, pull)
+		if err != nil {
+			return nil, err
+		}
+		labels := make([]string, len(pull.Labels))
+		for j, label := range pull.Labels {
+			labels[j] = label.Name
+		}
+		pullRequests[i] = &diff.PullRequest{
+			Number:    pull.Number,
+			Title:     pull.Title,
+			URL:       pull.HTMLURL,
+			Author:    pull.User.Login,
+			Labels:    labels,
+			Reviewers: reviewers,
+		}
+	}
+	return pullRequests, nil
+}
+
+// reviewers lists the users who reviewed, or were requested to review, the provided pull request, once each.
+func (c *Client) reviewers(repo *repository.GitRepository, pull *pullRequest) ([]string, error) {
+	var reviews []*review
+	// Reviews are paginated, 30 per page by default:
+	for next := fmt.Sprintf("%v/repos/%v/%v/pulls/%v/reviews", c.BaseURL, repo.Organization, repo.Repository, pull.Number); next != ""; {
+		var page []*review
+		var err error
+		if next, err = c.GetPage(next, &page); err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, page...)
+	}
+	reviewers := []string{}
+	seen := map[string]bool{}
+	for _, u := range append(reviewersOf(reviews), pull.RequestedReviewers...) {
+		if !seen[u.Login] {
+			seen[u.Login] = true
+			reviewers = append(reviewers, u.Login)
+		}
+	}
+	return reviewers, nil
+}
+
+func reviewersOf(reviews []*review) []user {
+	users := make([]user, len(reviews))
+	for i, review := range reviews {
+		users[i] = review.User
+	}
+	return users
+}
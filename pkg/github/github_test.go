@@ -0,0 +1,197 @@
+package github_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/enrich"
+	"github.com/weaveworks-experiments/imagediff/pkg/github"
+	"github.com/weaveworks-experiments/imagediff/pkg/repository"
+)
+
+// fakeGitHub emulates the subset of GitHub's REST API imagediff uses.
+type fakeGitHub struct {
+	mutex    sync.Mutex
+	requests []*http.Request
+	// rateLimited is the number of requests to reject before serving any.
+	rateLimited int
+	reset       time.Time
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	f.requests = append(f.requests, r)
+	rateLimited := f.rateLimited > 0
+	f.rateLimited--
+	f.mutex.Unlock()
+	if rateLimited {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(f.reset.Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message": "API rate limit exceeded"}`)
+		return
+	}
+	etag := `"` + r.URL.RequestURI() + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	switch r.URL.Path {
+	case "/repos/microscaling/microscaling/commits/91740fb/pulls":
+		fmt.Fprint(w, `[{
+			"number": 40,
+			"title": "Kubernetes labels",
+			"html_url": "https://github.com/microscaling/microscaling/pull/40",
+			"user": {"login": "octocat"},
+			"labels": [{"name": "enhancement"}, {"name": "k8s"}],
+			"requested_reviewers": [{"login": "hubot"}]
+		}]`)
+	case "/repos/microscaling/microscaling/pulls/40/reviews":
+		fmt.Fprint(w, `[{"user": {"login": "monalisa"}, "state": "APPROVED"}, {"user": {"login": "monalisa"}, "state": "COMMENTED"}]`)
+	case "/repos/microscaling/microscaling/commits/5d6e7f8/pulls":
+		fmt.Fprint(w, `[{"number": 41, "title": "Widely reviewed", "user": {"login": "octocat"}}]`)
+	case "/repos/microscaling/microscaling/pulls/41/reviews":
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprint(w, `[{"user": {"login": "defunkt"}, "state": "APPROVED"}]`)
+			return
+		}
+		w.Header().Set("Link", `<`+r.URL.Path+`?page=2>; rel="next", <`+r.URL.Path+`?page=2>; rel="last"`)
+		fmt.Fprint(w, `[{"user": {"login": "monalisa"}, "state": "COMMENTED"}]`)
+	case "/repos/microscaling/microscaling/commits/aa0ff4c/pulls":
+		fmt.Fprint(w, `[]`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "Not Found"}`)
+	}
+}
+
+func (f *fakeGitHub) numRequests() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return len(f.requests)
+}
+
+func sampleRepository(t *testing.T) *repository.GitRepository {
+	repo, err := repository.New("https://github.com/microscaling/microscaling")
+	assert.NoError(t, err)
+	return repo
+}
+
+func TestEnrich(t *testing.T) {
+	fake := &fakeGitHub{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := github.NewClient(server.URL, "s3cr3t")
+
+	changeLog := []*diff.Change{{Revision: "91740fb"}, {Revision: "aa0ff4c"}}
+	err := enrich.Enrich(client, sampleRepository(t), changeLog)
+	assert.NoError(t, err)
+	assert.Equal(t, []*diff.PullRequest{{
+		Number:    40,
+		Title:     "Kubernetes labels",
+		URL:       "https://github.com/microscaling/microscaling/pull/40",
+		Author:    "octocat",
+		Labels:    []string{"enhancement", "k8s"},
+		Reviewers: []string{"monalisa", "hubot"},
+	}}, changeLog[0].PullRequests)
+	assert.Equal(t, []*diff.PullRequest{}, changeLog[1].PullRequests)
+	for _, r := range fake.requests {
+		assert.Equal(t, "token s3cr3t", r.Header.Get("Authorization"))
+	}
+}
+
+func TestCaching(t *testing.T) {
+	fake := &fakeGitHub{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := github.NewClient(server.URL, "")
+
+	_, err := client.PullRequests(sampleRepository(t), "91740fb")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fake.numRequests())
+	assert.Empty(t, fake.requests[0].Header.Get("Authorization"))
+
+	// Fresh responses are reused without asking the API:
+	pullRequests, err := client.PullRequests(sampleRepository(t), "91740fb")
+	assert.NoError(t, err)
+	assert.Len(t, pullRequests, 1)
+	assert.Equal(t, 2, fake.numRequests())
+
+	// Stale responses are revalidated:
+	client.CacheTTL = 0
+	pullRequests, err = client.PullRequests(sampleRepository(t), "91740fb")
+	assert.NoError(t, err)
+	assert.Len(t, pullRequests, 1)
+	assert.Equal(t, "Kubernetes labels", pullRequests[0].Title)
+	assert.Equal(t, 4, fake.numRequests())
+	assert.Equal(t, `"/repos/microscaling/microscaling/commits/91740fb/pulls"`, fake.requests[2].Header.Get("If-None-Match"))
+}
+
+func TestPaginatedReviews(t *testing.T) {
+	fake := &fakeGitHub{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := github.NewClient(server.URL, "")
+
+	pullRequests, err := client.PullRequests(sampleRepository(t), "5d6e7f8")
+	assert.NoError(t, err)
+	assert.Len(t, pullRequests, 1)
+	assert.Equal(t, []string{"monalisa", "defunkt"}, pullRequests[0].Reviewers)
+	assert.Equal(t, 3, fake.numRequests())
+
+	// Revalidated pages still link to the next ones:
+	client.CacheTTL = 0
+	pullRequests, err = client.PullRequests(sampleRepository(t), "5d6e7f8")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"monalisa", "defunkt"}, pullRequests[0].Reviewers)
+	assert.Equal(t, 6, fake.numRequests())
+}
+
+func TestRateLimitRetries(t *testing.T) {
+	fake := &fakeGitHub{rateLimited: 1, reset: time.Now().Add(-time.Second)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := github.NewClient(server.URL, "")
+
+	pullRequests, err := client.PullRequests(sampleRepository(t), "aa0ff4c")
+	assert.NoError(t, err)
+	assert.Empty(t, pullRequests)
+	assert.Equal(t, 2, fake.numRequests())
+}
+
+func TestRateLimitGivesUpPastMaxWait(t *testing.T) {
+	fake := &fakeGitHub{rateLimited: 1, reset: time.Now().Add(time.Hour)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := github.NewClient(server.URL, "")
+
+	_, err := client.PullRequests(sampleRepository(t), "aa0ff4c")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "rate limited")
+	assert.Equal(t, 1, fake.numRequests())
+}
+
+func TestUnexpectedResponse(t *testing.T) {
+	server := httptest.NewServer(&fakeGitHub{})
+	defer server.Close()
+	client := github.NewClient(server.URL, "")
+
+	_, err := client.PullRequests(sampleRepository(t), "unknown")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "404 Not Found")
+}
+
+func TestAPIURL(t *testing.T) {
+	assert.Equal(t, "https://api.github.com", github.APIURL(sampleRepository(t)))
+	repo, err := repository.New("git@github.example.com:foo/bar.git")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://github.example.com/api/v3", github.APIURL(repo))
+}
@@ -0,0 +1,105 @@
+package gitlab
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/enrich"
+	"github.com/weaveworks-experiments/imagediff/pkg/repository"
+)
+
+// APIURL is the base URL of the REST API of the provided repository's host,
+// e.g. "https://gitlab.com/api/v4", for both gitlab.com and self-managed
+// instances.
+func APIURL(repo *repository.GitRepository) string {
+	return fmt.Sprintf("https://%v/api/v4", repo.Host)
+}
+
+// Client fetches the merge requests associated with commits from GitLab's REST API.
+type Client struct {
+	// BaseURL is the base URL of the API, e.g. "https://gitlab.example.com/api/v4" for self-managed GitLab.
+	BaseURL string
+	*enrich.Client
+}
+
+// NewClient creates a new client of the provided GitLab REST API. The token,
+// e.g. a personal access token, grants access to private projects, and is
+// optional.
+func NewClient(baseURL, token string) *Client {
+	header := http.Header{}
+	if token != "" {
+		header.Set("Private-Token", token)
+	}
+	return &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Client:  enrich.NewClient("GitLab's API", header),
+	}
+}
+
+type mergeRequest struct {
+	IID       int      `json:"iid"`
+	Title     string   `json:"title"`
+	WebURL    string   `json:"web_url"`
+	Author    user     `json:"author"`
+	Labels    []string `json:"labels"`
+	Reviewers []user   `json:"reviewers"`
+	Milestone *struct {
+		Title string `json:"title"`
+	} `json:"milestone"`
+	HeadPipeline *struct {
+		Status string `json:"status"`
+	} `json:"head_pipeline"`
+}
+
+type user struct {
+	Username string `json:"username"`
+}
+
+// PullRequests lists the merge requests associated with the provided revision of the provided repository.
+func (c *Client) PullRequests(repo *repository.GitRepository, revision string) ([]*diff.PullRequest, error) {
+	// Projects are identified by their URL-encoded path, which may include subgroups:
+	project := fmt.Sprintf("%v/projects/%v", c.BaseURL, url.PathEscape(repo.Path()))
+	var mergeRequests []*mergeRequest
+	if err := c.Get(fmt.Sprintf("%v/repository/commits/%v/merge_requests", project, revision), &mergeRequests); err != nil {
+		return nil, err
+	}
+	pullRequests := make([]*diff.PullRequest, len(mergeRequests))
+	for i, mr := range mergeRequests {
+		// Only single merge requests come with their latest pipeline:
+		var details mergeRequest
+		if err := c.Get(fmt.Sprintf("%v/merge_requests/%v", project, mr.IID), &details); err != nil {
+			return nil, err
+		}
+		pullRequests[i] = toPullRequest(mr)
+		if details.HeadPipeline != nil {
+			pullRequests[i].PipelineStatus = details.HeadPipeline.Status
+		}
+	}
+	return pullRequests, nil
+}
+
+func toPullRequest(mr *mergeRequest) *diff.PullRequest {
+	labels := mr.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	reviewers := make([]string, len(mr.Reviewers))
+	for i, reviewer := range mr.Reviewers {
+		reviewers[i] = reviewer.Username
+	}
+	pullRequest := &diff.PullRequest{
+		Number:    mr.IID,
+		Title:     mr.Title,
+		URL:       mr.WebURL,
+		Author:    mr.Author.Username,
+		Labels:    labels,
+		Reviewers: reviewers,
+	}
+	if mr.Milestone != nil {
+		pullRequest.Milestone = mr.Milestone.Title
+	}
+	return pullRequest
+}
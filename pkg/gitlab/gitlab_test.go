@@ -0,0 +1,89 @@
+package gitlab_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/enrich"
+	"github.com/weaveworks-experiments/imagediff/pkg/gitlab"
+	"github.com/weaveworks-experiments/imagediff/pkg/repository"
+)
+
+// fakeGitLab emulates the subset of GitLab's REST API imagediff uses.
+type fakeGitLab struct {
+	requests []*http.Request
+}
+
+func (f *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests = append(f.requests, r)
+	switch r.URL.EscapedPath() {
+	case "/api/v4/projects/group%2Fsubgroup%2Fproject/repository/commits/91740fb/merge_requests":
+		fmt.Fprint(w, `[{
+			"iid": 40,
+			"title": "Kubernetes labels",
+			"web_url": "https://gitlab.example.com/group/subgroup/project/-/merge_requests/40",
+			"author": {"username": "jdoe"},
+			"labels": ["enhancement"],
+			"reviewers": [{"username": "jsmith"}],
+			"milestone": {"title": "v1.2"}
+		}]`)
+	case "/api/v4/projects/group%2Fsubgroup%2Fproject/merge_requests/40":
+		fmt.Fprint(w, `{"iid": 40, "head_pipeline": {"status": "success"}}`)
+	case "/api/v4/projects/group%2Fsubgroup%2Fproject/repository/commits/aa0ff4c/merge_requests":
+		fmt.Fprint(w, `[]`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "404 Project Not Found"}`)
+	}
+}
+
+func sampleRepository(t *testing.T) *repository.GitRepository {
+	repo, err := repository.New("https://gitlab.example.com/group/subgroup/project.git")
+	assert.NoError(t, err)
+	return repo
+}
+
+func TestEnrich(t *testing.T) {
+	fake := &fakeGitLab{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := gitlab.NewClient(server.URL+"/api/v4/", "s3cr3t")
+
+	changeLog := []*diff.Change{{Revision: "91740fb"}, {Revision: "aa0ff4c"}}
+	err := enrich.Enrich(client, sampleRepository(t), changeLog)
+	assert.NoError(t, err)
+	assert.Equal(t, []*diff.PullRequest{{
+		Number:         40,
+		Title:          "Kubernetes labels",
+		URL:            "https://gitlab.example.com/group/subgroup/project/-/merge_requests/40",
+		Author:         "jdoe",
+		Labels:         []string{"enhancement"},
+		Reviewers:      []string{"jsmith"},
+		Milestone:      "v1.2",
+		PipelineStatus: "success",
+	}}, changeLog[0].PullRequests)
+	assert.Equal(t, []*diff.PullRequest{}, changeLog[1].PullRequests)
+	assert.Len(t, fake.requests, 3)
+	for _, r := range fake.requests {
+		assert.Equal(t, "s3cr3t", r.Header.Get("Private-Token"))
+	}
+}
+
+func TestUnknownProject(t *testing.T) {
+	server := httptest.NewServer(&fakeGitLab{})
+	defer server.Close()
+	repo, err := repository.New("https://gitlab.example.com/group/unknown")
+	assert.NoError(t, err)
+
+	_, err = gitlab.NewClient(server.URL+"/api/v4", "").PullRequests(repo, "91740fb")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "404 Not Found")
+}
+
+func TestAPIURL(t *testing.T) {
+	assert.Equal(t, "https://gitlab.example.com/api/v4", gitlab.APIURL(sampleRepository(t)))
+}
@@ -1,6 +1,8 @@
 package image
 
 import (
+	"strings"
+
 	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/registry"
 )
@@ -20,3 +22,16 @@ func (image Image) Registry() string {
 	}
 	return repoInfo.Index.Name
 }
+
+// WithTag replaces the tag, or digest, of this Docker image with the provided tag.
+func (image Image) WithTag(tag string) Image {
+	name := string(image)
+	if idx := strings.Index(name, "@"); idx != -1 {
+		name = name[:idx]
+	}
+	// Colons before the last slash separate registries' hosts from their ports:
+	if idx := strings.LastIndex(name, ":"); idx > strings.LastIndex(name, "/") {
+		name = name[:idx]
+	}
+	return Image(name + ":" + tag)
+}
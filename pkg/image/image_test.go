@@ -11,3 +11,11 @@ func TestRegistry(t *testing.T) {
 	assert.Equal(t, "docker.io", image.Image("owner/image:tag").Registry())
 	assert.Equal(t, "quay.io", image.Image("quay.io/owner/image:tag").Registry())
 }
+
+func TestWithTag(t *testing.T) {
+	assert.Equal(t, image.Image("microscaling/microscaling:v1.0.0"), image.Image("microscaling/microscaling:v0.9.0").WithTag("v1.0.0"))
+	assert.Equal(t, image.Image("microscaling/microscaling:v1.0.0"), image.Image("microscaling/microscaling").WithTag("v1.0.0"))
+	assert.Equal(t, image.Image("localhost:5000/foo:v1.0.0"), image.Image("localhost:5000/foo").WithTag("v1.0.0"))
+	assert.Equal(t, image.Image("localhost:5000/foo:v1.0.0"), image.Image("localhost:5000/foo:latest").WithTag("v1.0.0"))
+	assert.Equal(t, image.Image("quay.io/example/report:v1.0.0"), image.Image("quay.io/example/report@sha256:4c1f2a").WithTag("v1.0.0"))
+}
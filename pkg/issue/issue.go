@@ -0,0 +1,128 @@
+package issue
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/repository"
+)
+
+// Tracker describes an issue tracker, e.g. Jira or GitHub issues: how commit
+// messages reference its issues, and where to find these.
+type Tracker struct {
+	// Pattern matches references to issues. Its first capturing group, if
+	// any, otherwise the whole match, is the issue's ID.
+	Pattern *regexp.Regexp
+	// Key formats an issue's ID into its key, with "{id}" replaced by the ID,
+	// e.g. "#{id}" for GitHub issues. Defaults to the ID itself.
+	Key string
+	// URL formats an issue's ID into its URL, with "{id}" replaced by the ID,
+	// or, if there is no "{id}", the ID appended.
+	URL string
+}
+
+const placeholder = "{id}"
+
+// NewTracker creates a new issue tracker from the provided pattern and URL.
+func NewTracker(pattern, url string) (*Tracker, error) {
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid issue pattern [%v]: %v", pattern, err)
+	}
+	return &Tracker{Pattern: regex, URL: url}, nil
+}
+
+// ParseTracker creates a new issue tracker from the provided pattern and URL,
+// separated by a space, e.g. "ABC-[0-9]+ https://jira.example.com/browse/".
+func ParseTracker(spec string) (*Tracker, error) {
+	spec = strings.TrimSpace(spec)
+	idx := strings.LastIndex(spec, " ")
+	if idx == -1 {
+		return nil, fmt.Errorf("invalid issue tracker [%v], expected: <pattern> <URL>", spec)
+	}
+	return NewTracker(strings.TrimSpace(spec[:idx]), spec[idx+1:])
+}
+
+// closingKeywordsRegex matches GitHub's and GitLab's keywords to close issues, e.g. "Fixes #12".
+var closingKeywordsRegex = regexp.MustCompile(`(?i)\b(?:close[sd]?|closing|fix(?:e[sd])?|fixing|resolve[sd]?|resolving|implement(?:s|ed)?|implementing)\s*:?\s+#(\d+)\b`)
+
+// ClosingKeywords is the tracker for the issues of the provided repository,
+// as referenced by closing keywords in commit messages, e.g. "Fixes #12".
+func ClosingKeywords(repo *repository.GitRepository) *Tracker {
+	return &Tracker{
+		Pattern: closingKeywordsRegex,
+		Key:     "#" + placeholder,
+		URL:     repo.IssueURL(placeholder),
+	}
+}
+
+func (t *Tracker) format(format, id string) string {
+	if format == "" {
+		return id
+	}
+	if strings.Contains(format, placeholder) {
+		return strings.Replace(format, placeholder, id, -1)
+	}
+	return format + id
+}
+
+// Issue encapsulates an issue referenced by changes.
+type Issue struct {
+	Key string `json:"key"`
+	URL string `json:"url"`
+	// Revisions are the revisions of the changes referencing this issue.
+	Revisions []string `json:"revisions"`
+}
+
+// Extractor extracts, and deduplicates, references to issues from commit messages.
+type Extractor struct {
+	trackers []*Tracker
+	issues   []*Issue
+	byURL    map[string]*Issue
+}
+
+// NewExtractor creates a new extractor of references to issues of the provided trackers.
+func NewExtractor(trackers ...*Tracker) *Extractor {
+	return &Extractor{
+		trackers: trackers,
+		issues:   []*Issue{},
+		byURL:    map[string]*Issue{},
+	}
+}
+
+// Add extracts references to issues from the provided change's commit message.
+func (e *Extractor) Add(revision, message string) {
+	for _, tracker := range e.trackers {
+		for _, matches := range tracker.Pattern.FindAllStringSubmatch(message, -1) {
+			id := matches[0]
+			if len(matches) > 1 {
+				id = matches[1]
+			}
+			url := tracker.format(tracker.URL, id)
+			issue, ok := e.byURL[url]
+			if !ok {
+				issue = &Issue{Key: tracker.format(tracker.Key, id), URL: url, Revisions: []string{}}
+				e.byURL[url] = issue
+				e.issues = append(e.issues, issue)
+			}
+			if !contains(issue.Revisions, revision) {
+				issue.Revisions = append(issue.Revisions, revision)
+			}
+		}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Issues lists the issues referenced so far, each only once, in the order they were first referenced in.
+func (e *Extractor) Issues() []*Issue {
+	return e.issues
+}
@@ -0,0 +1,59 @@
+package issue_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/issue"
+	"github.com/weaveworks-experiments/imagediff/pkg/repository"
+)
+
+func TestExtractJiraKeys(t *testing.T) {
+	jira, err := issue.ParseTracker(`\b(?:ABC|OPS)-[0-9]+\b https://jira.example.com/browse/`)
+	assert.NoError(t, err)
+	extractor := issue.NewExtractor(jira)
+	extractor.Add("1", "ABC-123: add foo\n\nSee also OPS-7.")
+	extractor.Add("2", "Fix ABC-123 regression")
+	extractor.Add("3", "Bump version to 1.2-3, XYZ-1 is not ours")
+	assert.Equal(t, []*issue.Issue{
+		{Key: "ABC-123", URL: "https://jira.example.com/browse/ABC-123", Revisions: []string{"1", "2"}},
+		{Key: "OPS-7", URL: "https://jira.example.com/browse/OPS-7", Revisions: []string{"1"}},
+	}, extractor.Issues())
+}
+
+func TestExtractWithCapturingGroupAndPlaceholder(t *testing.T) {
+	tracker, err := issue.NewTracker(`\bbug ([0-9]+)\b`, "https://bugs.example.com/show_bug.cgi?id={id}&format=full")
+	assert.NoError(t, err)
+	extractor := issue.NewExtractor(tracker)
+	extractor.Add("1", "Work around bug 42, and bug 42 again")
+	assert.Equal(t, []*issue.Issue{
+		{Key: "42", URL: "https://bugs.example.com/show_bug.cgi?id=42&format=full", Revisions: []string{"1"}},
+	}, extractor.Issues())
+}
+
+func TestExtractClosingKeywords(t *testing.T) {
+	repo, err := repository.New("https://github.com/microscaling/microscaling")
+	assert.NoError(t, err)
+	extractor := issue.NewExtractor(issue.ClosingKeywords(repo))
+	extractor.Add("1", "Use a separate type\n\nFixes #12, closes #13.\nResolved: #14")
+	extractor.Add("2", "Merge pull request #40 from microscaling/k8s-labels")
+	extractor.Add("3", "fix #12")
+	assert.Equal(t, []*issue.Issue{
+		{Key: "#12", URL: "https://github.com/microscaling/microscaling/issues/12", Revisions: []string{"1", "3"}},
+		{Key: "#13", URL: "https://github.com/microscaling/microscaling/issues/13", Revisions: []string{"1"}},
+		{Key: "#14", URL: "https://github.com/microscaling/microscaling/issues/14", Revisions: []string{"1"}},
+	}, extractor.Issues())
+}
+
+func TestExtractNothing(t *testing.T) {
+	extractor := issue.NewExtractor()
+	extractor.Add("1", "ABC-123")
+	assert.Equal(t, []*issue.Issue{}, extractor.Issues())
+}
+
+func TestParseInvalidTracker(t *testing.T) {
+	_, err := issue.ParseTracker("ABC-[0-9]+")
+	assert.Error(t, err)
+	_, err = issue.ParseTracker("ABC-[0-9+ https://jira.example.com/browse/")
+	assert.Error(t, err)
+}
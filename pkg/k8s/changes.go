@@ -0,0 +1,70 @@
+package k8s
+
+import (
+	"github.com/weaveworks-experiments/imagediff/pkg/image"
+)
+
+// ImageChange encapsulates the change of the image of one of a workload's containers.
+type ImageChange struct {
+	Workload  *Workload `json:"workload"`
+	Container string    `json:"container"`
+	X         string    `json:"x"`
+	Y         string    `json:"y"`
+}
+
+// ChangesToTag lists the changes of images deploying the provided tag
+// would make, for the provided workloads' containers, or only for the
+// containers with the provided names, if any.
+func ChangesToTag(workloads []*Workload, tag string, containers ...string) []*ImageChange {
+	changes := []*ImageChange{}
+	for _, workload := range workloads {
+		for _, container := range workload.Containers {
+			if len(containers) > 0 && !contains(containers, container.Name) {
+				continue
+			}
+			if y := string(image.Image(container.Image).WithTag(tag)); y != container.Image {
+				changes = append(changes, &ImageChange{Workload: workload, Container: container.Name, X: container.Image, Y: y})
+			}
+		}
+	}
+	return changes
+}
+
+// Changes lists the changes of images deploying the provided workloads, to
+// replace the provided current ones, would make. Workloads and containers are
+// matched by their kind, name, and namespace, unless unset, e.g. in manifests
+// applied to the current namespace. Those only in either are ignored.
+func Changes(from, to []*Workload) []*ImageChange {
+	changes := []*ImageChange{}
+	for _, x := range from {
+		y, ok := find(to, x)
+		if !ok {
+			continue
+		}
+		for _, xContainer := range x.Containers {
+			yContainer, ok := y.Container(xContainer.Name)
+			if ok && yContainer.Image != xContainer.Image {
+				changes = append(changes, &ImageChange{Workload: x, Container: xContainer.Name, X: xContainer.Image, Y: yContainer.Image})
+			}
+		}
+	}
+	return changes
+}
+
+func find(workloads []*Workload, workload *Workload) (*Workload, bool) {
+	for _, w := range workloads {
+		if w.Kind == workload.Kind && w.Name == workload.Name && (w.Namespace == workload.Namespace || w.Namespace == "" || workload.Namespace == "") {
+			return w, true
+		}
+	}
+	return nil, false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
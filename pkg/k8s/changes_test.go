@@ -0,0 +1,43 @@
+package k8s_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/k8s"
+)
+
+func TestChangesToTag(t *testing.T) {
+	workloads, err := k8s.ParseManifests([]byte(sampleManifests))
+	assert.NoError(t, err)
+
+	changes := k8s.ChangesToTag(workloads, "v1.0.0", "migrate", "microscaling")
+	assert.Equal(t, []*k8s.ImageChange{
+		{Workload: workloads[0], Container: "migrate", X: "microscaling/microscaling:v0.9.0", Y: "microscaling/microscaling:v1.0.0"},
+		{Workload: workloads[0], Container: "microscaling", X: "microscaling/microscaling:v0.9.0", Y: "microscaling/microscaling:v1.0.0"},
+	}, changes)
+
+	assert.Len(t, k8s.ChangesToTag(workloads, "v1.0.0"), 4)
+	assert.Empty(t, k8s.ChangesToTag(workloads, "v0.9.0", "microscaling"))
+}
+
+func TestChanges(t *testing.T) {
+	from := []*k8s.Workload{
+		{Kind: "Deployment", Namespace: "demo", Name: "microscaling", Containers: []*k8s.Container{
+			{Name: "microscaling", Image: "microscaling/microscaling:v0.9.0"},
+			{Name: "proxy", Image: "envoyproxy/envoy:v1.10.0"},
+		}},
+		{Kind: "Deployment", Name: "removed", Containers: []*k8s.Container{{Name: "removed", Image: "example/removed:1.0"}}},
+	}
+	to := []*k8s.Workload{
+		{Kind: "Deployment", Name: "microscaling", Containers: []*k8s.Container{
+			{Name: "microscaling", Image: "microscaling/microscaling:v1.0.0"},
+			{Name: "proxy", Image: "envoyproxy/envoy:v1.10.0"},
+			{Name: "added", Image: "example/added:1.0"},
+		}},
+		{Kind: "StatefulSet", Name: "removed", Containers: []*k8s.Container{{Name: "removed", Image: "example/removed:2.0"}}},
+	}
+	assert.Equal(t, []*k8s.ImageChange{
+		{Workload: from[0], Container: "microscaling", X: "microscaling/microscaling:v0.9.0", Y: "microscaling/microscaling:v1.0.0"},
+	}, k8s.Changes(from, to))
+}
@@ -0,0 +1,138 @@
+package k8s
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// kind describes how to access a kind of workloads in Kubernetes' API.
+type kind struct {
+	name       string
+	apiVersion string
+	resource   string
+	aliases    []string
+}
+
+var kinds = []*kind{
+	{name: "Deployment", apiVersion: "apis/apps/v1", resource: "deployments", aliases: []string{"deploy"}},
+	{name: "StatefulSet", apiVersion: "apis/apps/v1", resource: "statefulsets", aliases: []string{"sts"}},
+	{name: "DaemonSet", apiVersion: "apis/apps/v1", resource: "daemonsets", aliases: []string{"ds"}},
+	{name: "ReplicaSet", apiVersion: "apis/apps/v1", resource: "replicasets", aliases: []string{"rs"}},
+	{name: "CronJob", apiVersion: "apis/batch/v1", resource: "cronjobs", aliases: []string{"cj"}},
+	{name: "Job", apiVersion: "apis/batch/v1", resource: "jobs"},
+	{name: "Pod", apiVersion: "api/v1", resource: "pods", aliases: []string{"po"}},
+}
+
+func findKind(name string) (*kind, error) {
+	name = strings.ToLower(name)
+	for _, k := range kinds {
+		if name == strings.ToLower(k.name) || name == k.resource || contains(k.aliases, name) {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported kind of workload: %v", name)
+}
+
+// ParseReference parses references to workloads, like kubectl's, i.e.
+// "<kind>/<name>", e.g. "deployment/foo", "deploy/foo" or "sts/bar".
+func ParseReference(reference string) (string, string, error) {
+	parts := strings.Split(reference, "/")
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid workload [%v], expected: <kind>/<name>, e.g. deployment/foo", reference)
+	}
+	k, err := findKind(parts[0])
+	if err != nil {
+		return "", "", err
+	}
+	return k.name, parts[1], nil
+}
+
+// Client reads workloads from a Kubernetes cluster's API.
+type Client struct {
+	config     *Config
+	httpClient *http.Client
+}
+
+// NewClient creates a new client of the Kubernetes API described by the provided configuration.
+func NewClient(config *Config) (*Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.InsecureSkipTLSVerify}
+	if len(config.CertificateAuthority) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(config.CertificateAuthority) {
+			return nil, errors.New("invalid certificate authority: no PEM-encoded certificate found")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if len(config.ClientCertificate) > 0 {
+		certificate, err := tls.X509KeyPair(config.ClientCertificate, config.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return &Client{
+		config: config,
+		httpClient: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment},
+			Timeout:   30 * time.Second,
+		},
+	}, nil
+}
+
+// Workload reads the workload of the provided kind and name, in the provided
+// namespace, or, if empty, the configuration's namespace, or "default".
+func (c *Client) Workload(kindName, namespace, name string) (*Workload, error) {
+	k, err := findKind(kindName)
+	if err != nil {
+		return nil, err
+	}
+	if namespace == "" {
+		namespace = c.config.Namespace
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	url := fmt.Sprintf("%v/%v/namespaces/%v/%v/%v", strings.TrimSuffix(c.config.Server, "/"), k.apiVersion, namespace, k.resource, name)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	} else if c.config.Username != "" {
+		req.SetBasicAuth(c.config.Username, c.config.Password)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get %v/%v in namespace [%v] from Kubernetes' API: %v: %s", k.resource, name, namespace, resp.Status, strings.TrimSpace(string(body)))
+	}
+	// JSON being a subset of YAML, the API's responses are decoded like manifests:
+	var o object
+	if err := yaml.Unmarshal(body, &o); err != nil {
+		return nil, err
+	}
+	// The kind is known from the request, whether or not the response includes it:
+	o.Kind = k.name
+	workloads := o.workloads()
+	if len(workloads) != 1 {
+		return nil, fmt.Errorf("unexpected response from Kubernetes' API for %v/%v in namespace [%v]", k.resource, name, namespace)
+	}
+	return workloads[0], nil
+}
@@ -0,0 +1,171 @@
+package k8s_test
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/k8s"
+)
+
+// fakeAPIServer emulates the subset of Kubernetes' API imagediff uses.
+func fakeAPIServer(t *testing.T) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"kind": "Status", "message": "Unauthorized"}`)
+			return
+		}
+		switch r.URL.Path {
+		case "/apis/apps/v1/namespaces/demo/deployments/microscaling":
+			fmt.Fprint(w, `{
+				"kind": "Deployment",
+				"metadata": {"name": "microscaling", "namespace": "demo"},
+				"spec": {"template": {"spec": {"containers": [{"name": "microscaling", "image": "microscaling/microscaling:v0.9.0"}]}}}
+			}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"kind": "Status", "message": "not found"}`)
+		}
+	}))
+}
+
+func kubeconfig(t *testing.T, server *httptest.Server) string {
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	return tempFile(t, fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: fake
+contexts:
+- name: fake
+  context:
+    cluster: fake
+    user: admin
+    namespace: demo
+- name: other
+  context:
+    cluster: other
+    user: admin
+clusters:
+- name: fake
+  cluster:
+    server: %v
+    certificate-authority-data: %v
+users:
+- name: admin
+  user:
+    token: s3cr3t
+`, server.URL, base64.StdEncoding.EncodeToString(ca)))
+}
+
+func TestWorkloadFromAPI(t *testing.T) {
+	server := fakeAPIServer(t)
+	defer server.Close()
+	path := kubeconfig(t, server)
+	defer os.Remove(path)
+
+	config, err := k8s.LoadConfig(path, "")
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL, config.Server)
+	assert.Equal(t, "demo", config.Namespace)
+	assert.Equal(t, "s3cr3t", config.Token)
+	client, err := k8s.NewClient(config)
+	assert.NoError(t, err)
+
+	workload, err := client.Workload("deploy", "", "microscaling")
+	assert.NoError(t, err)
+	assert.Equal(t, &k8s.Workload{
+		Kind:       "Deployment",
+		Namespace:  "demo",
+		Name:       "microscaling",
+		Containers: []*k8s.Container{{Name: "microscaling", Image: "microscaling/microscaling:v0.9.0"}},
+	}, workload)
+
+	_, err = client.Workload("sts", "", "microscaling")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "404 Not Found")
+}
+
+func TestUnauthorized(t *testing.T) {
+	server := fakeAPIServer(t)
+	defer server.Close()
+	client, err := k8s.NewClient(&k8s.Config{Server: server.URL, InsecureSkipTLSVerify: true})
+	assert.NoError(t, err)
+	_, err = client.Workload("deployment", "demo", "microscaling")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "401 Unauthorized")
+}
+
+func TestLoadConfigWithUnknownContext(t *testing.T) {
+	server := fakeAPIServer(t)
+	defer server.Close()
+	path := kubeconfig(t, server)
+	defer os.Remove(path)
+
+	_, err := k8s.LoadConfig(path, "unknown")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "context [unknown] not found")
+	_, err = k8s.LoadConfig(path, "other")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cluster [other] of context [other] not found")
+}
+
+func TestParseReference(t *testing.T) {
+	for reference, expected := range map[string][2]string{
+		"deployment/foo":  {"Deployment", "foo"},
+		"deploy/foo":      {"Deployment", "foo"},
+		"statefulsets/db": {"StatefulSet", "db"},
+		"ds/agent":        {"DaemonSet", "agent"},
+		"CronJob/report":  {"CronJob", "report"},
+	} {
+		kind, name, err := k8s.ParseReference(reference)
+		assert.NoError(t, err, reference)
+		assert.Equal(t, expected, [2]string{kind, name}, reference)
+	}
+	for _, reference := range []string{"foo", "deployment/", "service/foo", "a/b/c"} {
+		_, _, err := k8s.ParseReference(reference)
+		assert.Error(t, err, reference)
+	}
+}
+
+func TestLoadConfigWithUnsupportedAuth(t *testing.T) {
+	for _, test := range []struct {
+		user     string
+		expected string
+	}{
+		{
+			user:     "exec:\n      apiVersion: client.authentication.k8s.io/v1beta1\n      command: aws\n      args: [eks, get-token, --cluster-name, demo]",
+			expected: "user [cloud] of context [cloud] authenticates with an exec credential plugin [aws], which is not supported",
+		},
+		{
+			user:     "auth-provider:\n      name: gcp",
+			expected: "user [cloud] of context [cloud] authenticates with auth provider [gcp], which is not supported",
+		},
+	} {
+		path := tempFile(t, fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: cloud
+contexts:
+- name: cloud
+  context:
+    cluster: cloud
+    user: cloud
+clusters:
+- name: cloud
+  cluster:
+    server: https://cloud.example.com
+users:
+- name: cloud
+  user:
+    %v
+`, test.user))
+		_, err := k8s.LoadConfig(path, "")
+		os.Remove(path)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), test.expected)
+	}
+}
@@ -0,0 +1,167 @@
+package k8s
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Config encapsulates how to access a Kubernetes cluster's API, as found in a kubeconfig file's context.
+type Config struct {
+	Server string
+	// Namespace is the context's namespace, if any.
+	Namespace string
+	// CertificateAuthority is the PEM-encoded certificate authority to verify the API's certificate with, if any.
+	CertificateAuthority  []byte
+	InsecureSkipTLSVerify bool
+	// ClientCertificate and ClientKey are the PEM-encoded certificate and key to authenticate with, if any.
+	ClientCertificate []byte
+	ClientKey         []byte
+	// Token is the bearer token to authenticate with, if any.
+	Token    string
+	Username string
+	Password string
+}
+
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Contexts       []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			User      string `yaml:"user"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+	Clusters []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			ClientCertificate     string `yaml:"client-certificate"`
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			ClientKey             string `yaml:"client-key"`
+			ClientKeyData         string `yaml:"client-key-data"`
+			Token                 string `yaml:"token"`
+			TokenFile             string `yaml:"tokenFile"`
+			Username              string `yaml:"username"`
+			Password              string `yaml:"password"`
+			// Exec and AuthProvider are not supported, but detected, to fail clearly.
+			Exec *struct {
+				Command string `yaml:"command"`
+			} `yaml:"exec"`
+			AuthProvider *struct {
+				Name string `yaml:"name"`
+			} `yaml:"auth-provider"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+}
+
+// DefaultKubeconfigPath is the path of the kubeconfig file kubectl would use:
+// the first of $KUBECONFIG's, if set, or ~/.kube/config.
+func DefaultKubeconfigPath() string {
+	if paths := filepath.SplitList(os.Getenv("KUBECONFIG")); len(paths) > 0 && paths[0] != "" {
+		return paths[0]
+	}
+	user, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(user.HomeDir, ".kube", "config")
+}
+
+// LoadConfig reads the provided context, or the current one if empty, from
+// the provided kubeconfig file. Files referenced by the kubeconfig file are
+// resolved relatively to it, like kubectl does.
+func LoadConfig(path, context string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var kc kubeconfig
+	if err := yaml.Unmarshal(data, &kc); err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig [%v]: %v", path, err)
+	}
+	if context == "" {
+		context = kc.CurrentContext
+	}
+	r := &resolver{dir: filepath.Dir(path)}
+	for _, c := range kc.Contexts {
+		if c.Name != context {
+			continue
+		}
+		config := &Config{Namespace: c.Context.Namespace}
+		for _, cluster := range kc.Clusters {
+			if cluster.Name == c.Context.Cluster {
+				config.Server = cluster.Cluster.Server
+				config.InsecureSkipTLSVerify = cluster.Cluster.InsecureSkipTLSVerify
+				config.CertificateAuthority = r.read(cluster.Cluster.CertificateAuthorityData, cluster.Cluster.CertificateAuthority)
+			}
+		}
+		if config.Server == "" {
+			return nil, fmt.Errorf("cluster [%v] of context [%v] not found in kubeconfig [%v]", c.Context.Cluster, context, path)
+		}
+		for _, u := range kc.Users {
+			if u.Name == c.Context.User {
+				// Credentials plugins, e.g. EKS', GKE's and AKS', would otherwise
+				// be silently ignored, and requests rejected as unauthorized:
+				if u.User.Exec != nil {
+					return nil, fmt.Errorf("user [%v] of context [%v] authenticates with an exec credential plugin [%v], which is not supported: use a token or client certificate instead", u.Name, context, u.User.Exec.Command)
+				}
+				if u.User.AuthProvider != nil {
+					return nil, fmt.Errorf("user [%v] of context [%v] authenticates with auth provider [%v], which is not supported: use a token or client certificate instead", u.Name, context, u.User.AuthProvider.Name)
+				}
+				config.ClientCertificate = r.read(u.User.ClientCertificateData, u.User.ClientCertificate)
+				config.ClientKey = r.read(u.User.ClientKeyData, u.User.ClientKey)
+				config.Token = u.User.Token
+				if token := r.read("", u.User.TokenFile); token != nil {
+					config.Token = strings.TrimSpace(string(token))
+				}
+				config.Username = u.User.Username
+				config.Password = u.User.Password
+			}
+		}
+		return config, r.err
+	}
+	return nil, fmt.Errorf("context [%v] not found in kubeconfig [%v]", context, path)
+}
+
+// resolver reads kubeconfig's fields which are either base64-encoded data, or
+// paths to files, and remembers the first error, if any.
+type resolver struct {
+	dir string
+	err error
+}
+
+func (r *resolver) read(data, path string) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if data != "" {
+		decoded, err := base64.StdEncoding.DecodeString(data)
+		r.err = err
+		return decoded
+	}
+	if path == "" {
+		return nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(r.dir, path)
+	}
+	contents, err := ioutil.ReadFile(path)
+	r.err = err
+	return contents
+}
@@ -0,0 +1,202 @@
+package k8s
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Workload encapsulates a Kubernetes workload, e.g. a Deployment, and the containers it runs.
+type Workload struct {
+	Kind       string       `json:"kind"`
+	Namespace  string       `json:"namespace,omitempty"`
+	Name       string       `json:"name"`
+	Containers []*Container `json:"containers"`
+}
+
+// Container encapsulates one of the containers of a workload's pods.
+type Container struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+	// Init is true for init containers.
+	Init bool `json:"init,omitempty"`
+}
+
+func (w *Workload) String() string {
+	if w.Namespace == "" {
+		return fmt.Sprintf("%v/%v", strings.ToLower(w.Kind), w.Name)
+	}
+	return fmt.Sprintf("%v/%v/%v", w.Namespace, strings.ToLower(w.Kind), w.Name)
+}
+
+// Container returns this workload's container with the provided name, if any.
+func (w *Workload) Container(name string) (*Container, bool) {
+	for _, container := range w.Containers {
+		if container.Name == name {
+			return container, true
+		}
+	}
+	return nil, false
+}
+
+// object is the subset of Kubernetes objects needed to find the containers of workloads.
+type object struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Spec  spec     `yaml:"spec"`
+	Items []object `yaml:"items"`
+}
+
+// spec is the union of the specs of the supported kinds of workloads, and of pods.
+type spec struct {
+	// Template is the pod template of Deployments, StatefulSets, DaemonSets and Jobs.
+	Template *template `yaml:"template"`
+	// JobTemplate is the job template of CronJobs.
+	JobTemplate *template `yaml:"jobTemplate"`
+	// Containers and InitContainers are the containers of pods.
+	Containers     []container `yaml:"containers"`
+	InitContainers []container `yaml:"initContainers"`
+}
+
+type template struct {
+	Spec spec `yaml:"spec"`
+}
+
+type container struct {
+	Name  string `yaml:"name"`
+	Image string `yaml:"image"`
+}
+
+// podSpec finds the pod spec of the provided workload's spec, if the workload's kind is supported.
+func podSpec(kind string, s spec) (*spec, bool) {
+	switch kind {
+	case "Pod":
+		return &s, true
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job":
+		if s.Template != nil {
+			return &s.Template.Spec, true
+		}
+	case "CronJob":
+		if s.JobTemplate != nil && s.JobTemplate.Spec.Template != nil {
+			return &s.JobTemplate.Spec.Template.Spec, true
+		}
+	}
+	return nil, false
+}
+
+func (o *object) workloads() []*Workload {
+	// Lists are either "List"s, in manifests, or e.g. "DeploymentList"s, from the API:
+	if strings.HasSuffix(o.Kind, "List") {
+		workloads := []*Workload{}
+		for i := range o.Items {
+			workloads = append(workloads, o.Items[i].workloads()...)
+		}
+		return workloads
+	}
+	pod, ok := podSpec(o.Kind, o.Spec)
+	if !ok {
+		return nil
+	}
+	workload := &Workload{
+		Kind:       o.Kind,
+		Namespace:  o.Metadata.Namespace,
+		Name:       o.Metadata.Name,
+		Containers: []*Container{},
+	}
+	for _, c := range pod.InitContainers {
+		workload.Containers = append(workload.Containers, &Container{Name: c.Name, Image: c.Image, Init: true})
+	}
+	for _, c := range pod.Containers {
+		workload.Containers = append(workload.Containers, &Container{Name: c.Name, Image: c.Image})
+	}
+	return []*Workload{workload}
+}
+
+// ParseManifests finds the workloads in the provided Kubernetes manifests,
+// i.e. YAML or JSON documents, possibly several, separated by "---", and
+// possibly lists of objects. Objects which are not workloads, e.g. Services,
+// are ignored.
+func ParseManifests(data []byte) ([]*Workload, error) {
+	workloads := []*Workload{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var o object
+		if err := decoder.Decode(&o); err == io.EOF {
+			return workloads, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid Kubernetes manifest: %v", err)
+		}
+		workloads = append(workloads, o.workloads()...)
+	}
+}
+
+// ReadManifests finds the workloads in the Kubernetes manifests in the
+// provided file, in the YAML and JSON files of the provided directory and its
+// subdirectories, or, for "-", in the standard input, e.g. piped from
+// "helm template" or "kustomize build".
+func ReadManifests(path string) ([]*Workload, error) {
+	if path == "-" {
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		return ParseManifests(data)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return readManifestsFile(path)
+	}
+	workloads := []*Workload{}
+	err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			// Skip hidden directories, e.g. .git:
+			if p != path && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isManifest(p) {
+			return nil
+		}
+		fileWorkloads, err := readManifestsFile(p)
+		workloads = append(workloads, fileWorkloads...)
+		return err
+	})
+	return workloads, err
+}
+
+func isManifest(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	default:
+		return false
+	}
+}
+
+func readManifestsFile(path string) ([]*Workload, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	workloads, err := ParseManifests(data)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	return workloads, nil
+}
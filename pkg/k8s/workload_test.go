@@ -0,0 +1,162 @@
+package k8s_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/k8s"
+)
+
+const sampleManifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: microscaling
+  namespace: demo
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: migrate
+        image: microscaling/microscaling:v0.9.0
+      containers:
+      - name: microscaling
+        image: microscaling/microscaling:v0.9.0
+      - name: proxy
+        image: envoyproxy/envoy:v1.10.0
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: microscaling
+spec:
+  ports:
+  - port: 80
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: report
+spec:
+  schedule: "0 * * * *"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: report
+            image: quay.io/example/report@sha256:4c1f2a
+`
+
+func TestParseManifests(t *testing.T) {
+	workloads, err := k8s.ParseManifests([]byte(sampleManifests))
+	assert.NoError(t, err)
+	assert.Equal(t, []*k8s.Workload{
+		{
+			Kind:      "Deployment",
+			Namespace: "demo",
+			Name:      "microscaling",
+			Containers: []*k8s.Container{
+				{Name: "migrate", Image: "microscaling/microscaling:v0.9.0", Init: true},
+				{Name: "microscaling", Image: "microscaling/microscaling:v0.9.0"},
+				{Name: "proxy", Image: "envoyproxy/envoy:v1.10.0"},
+			},
+		},
+		{
+			Kind:       "CronJob",
+			Name:       "report",
+			Containers: []*k8s.Container{{Name: "report", Image: "quay.io/example/report@sha256:4c1f2a"}},
+		},
+	}, workloads)
+	assert.Equal(t, "demo/deployment/microscaling", workloads[0].String())
+	assert.Equal(t, "cronjob/report", workloads[1].String())
+}
+
+func TestParseManifestsWithLists(t *testing.T) {
+	workloads, err := k8s.ParseManifests([]byte(`{
+		"apiVersion": "v1",
+		"kind": "List",
+		"items": [
+			{"kind": "StatefulSet", "metadata": {"name": "db"}, "spec": {"template": {"spec": {"containers": [{"name": "db", "image": "postgres:11"}]}}}},
+			{"kind": "DaemonSet", "metadata": {"name": "agent"}, "spec": {"template": {"spec": {"containers": [{"name": "agent", "image": "example/agent:1.0"}]}}}}
+		]
+	}`))
+	assert.NoError(t, err)
+	assert.Len(t, workloads, 2)
+	assert.Equal(t, "StatefulSet", workloads[0].Kind)
+	assert.Equal(t, "DaemonSet", workloads[1].Kind)
+}
+
+func TestParseInvalidManifests(t *testing.T) {
+	_, err := k8s.ParseManifests([]byte("kind: [Deployment"))
+	assert.Error(t, err)
+}
+
+func TestReadManifests(t *testing.T) {
+	path := tempFile(t, sampleManifests)
+	defer os.Remove(path)
+	workloads, err := k8s.ReadManifests(path)
+	assert.NoError(t, err)
+	assert.Len(t, workloads, 2)
+}
+
+func tempFile(t *testing.T, data string) string {
+	file, err := ioutil.TempFile("", "imagediff")
+	assert.NoError(t, err)
+	defer file.Close()
+	_, err = file.WriteString(data)
+	assert.NoError(t, err)
+	return file.Name()
+}
+
+func TestParseHelmTemplateOutput(t *testing.T) {
+	workloads, err := k8s.ParseManifests([]byte(`---
+# Source: microscaling/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: microscaling
+---
+---
+# Source: microscaling/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: microscaling
+spec:
+  template:
+    spec:
+      containers:
+      - name: microscaling
+        image: "microscaling/microscaling:v0.9.1"
+`))
+	assert.NoError(t, err)
+	assert.Len(t, workloads, 1)
+	assert.Equal(t, "microscaling/microscaling:v0.9.1", workloads[0].Containers[0].Image)
+}
+
+func TestReadManifestsFromDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "imagediff")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	for path, data := range map[string]string{
+		"app.yaml":             sampleManifests,
+		"db/statefulset.yml":   "kind: StatefulSet\nmetadata:\n  name: db\nspec:\n  template:\n    spec:\n      containers:\n      - name: db\n        image: postgres:11\n",
+		"README.md":            "kind: [not a manifest",
+		".git/config.yaml":     "kind: [not a manifest",
+		"db/values.schema.txt": "kind: [not a manifest",
+	} {
+		path = filepath.Join(dir, path)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, ioutil.WriteFile(path, []byte(data), 0644))
+	}
+	workloads, err := k8s.ReadManifests(dir)
+	assert.NoError(t, err)
+	names := []string{}
+	for _, workload := range workloads {
+		names = append(names, workload.String())
+	}
+	assert.Equal(t, []string{"demo/deployment/microscaling", "cronjob/report", "statefulset/db"}, names)
+}
@@ -0,0 +1,252 @@
+package layers
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Kinds of FileChange.
+const (
+	FileAdded    = "added"
+	FileRemoved  = "removed"
+	FileModified = "modified"
+)
+
+// FileChange is a change to a file of images' filesystems.
+type FileChange struct {
+	// Path is the file's absolute path, e.g. "/usr/bin/app".
+	Path string `json:"path"`
+	// Change is one of FileAdded, FileRemoved or FileModified.
+	Change string `json:"change"`
+	// XSize and YSize are the file's sizes in both images, in bytes, if any.
+	XSize int64 `json:"xSize,omitempty"`
+	YSize int64 `json:"ySize,omitempty"`
+}
+
+// File is an entry of an image's filesystem.
+type File struct {
+	Path     string
+	Type     byte
+	Mode     int64
+	Size     int64
+	Linkname string
+	// Digest is the SHA-256 of regular files' content.
+	Digest string
+	// Content is the content of the regular files kept when reading archives, if any.
+	Content []byte
+}
+
+// Filesystem maps the absolute paths of an image's files to these.
+type Filesystem map[string]*File
+
+// whiteout prefixes the names of files removed by a layer, and opaque marks
+// directories whose content in lower layers is hidden, as per the OCI image
+// specification.
+const (
+	whiteout = ".wh."
+	opaque   = ".wh..wh..opq"
+)
+
+// layerFiles are the entries of a layer, including whiteouts.
+type layerFiles []*File
+
+// Archive is the content of a "docker save" archive, i.e. the files of the
+// layers of one or more images.
+type Archive struct {
+	// Images maps the IDs of the archive's images, without their algorithm, to the paths of their layers, from the bottom one.
+	Images map[string][]string
+	// layers maps the paths of layers in the archive to their files.
+	layers map[string]layerFiles
+}
+
+// manifestEntry describes one of the images of a "docker save" archive.
+type manifestEntry struct {
+	Config string
+	Layers []string
+}
+
+// ReadArchive reads a "docker save" archive, in the legacy format, or in the
+// OCI one. Layers are read as they are streamed, without keeping their
+// content, but only the digests of their files, and the content of the
+// regular files to keep, if any, e.g. packages' databases.
+func ReadArchive(r io.Reader, keep func(path string) bool) (*Archive, error) {
+	archive := &Archive{Images: map[string][]string{}, layers: map[string]layerFiles{}}
+	var manifest []manifestEntry
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+		name := path.Clean(header.Name)
+		if name == "manifest.json" {
+			if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+				return nil, fmt.Errorf("invalid manifest.json: %v", err)
+			}
+			continue
+		}
+		// Layers are named <ID>/layer.tar in the legacy format, or
+		// blobs/sha256/<digest> in the OCI one, like configurations and
+		// manifests, hence reading all blobs as layers, ignoring the ones
+		// which are not tar archives:
+		if strings.HasSuffix(name, "/layer.tar") || strings.HasPrefix(name, "blobs/") {
+			files, err := readLayer(tr, keep)
+			if err != nil && !strings.HasPrefix(name, "blobs/") {
+				return nil, fmt.Errorf("invalid layer %v: %v", name, err)
+			}
+			if err == nil {
+				archive.layers[name] = files
+			}
+		}
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("invalid archive: missing manifest.json")
+	}
+	for _, entry := range manifest {
+		for _, layer := range entry.Layers {
+			if _, ok := archive.layers[path.Clean(layer)]; !ok {
+				return nil, fmt.Errorf("invalid archive: missing layer %v", layer)
+			}
+		}
+		id := strings.TrimSuffix(path.Base(entry.Config), ".json")
+		archive.Images[id] = entry.Layers
+	}
+	return archive, nil
+}
+
+func readLayer(r io.Reader, keep func(path string) bool) (layerFiles, error) {
+	files := layerFiles{}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag == tar.TypeRegA {
+			header.Typeflag = tar.TypeReg
+		}
+		file := &File{
+			Path:     path.Join("/", header.Name),
+			Type:     header.Typeflag,
+			Mode:     header.Mode,
+			Size:     header.Size,
+			Linkname: header.Linkname,
+		}
+		if header.Typeflag == tar.TypeReg {
+			hash := sha256.New()
+			var content io.Writer = hash
+			var buf bytes.Buffer
+			kept := keep != nil && keep(file.Path)
+			if kept {
+				content = io.MultiWriter(hash, &buf)
+			}
+			if _, err := io.Copy(content, tr); err != nil {
+				return nil, err
+			}
+			file.Digest = hex.EncodeToString(hash.Sum(nil))
+			if kept {
+				file.Content = buf.Bytes()
+			}
+		}
+		files = append(files, file)
+	}
+}
+
+// Filesystem assembles the filesystem of the image with the provided ID,
+// with or without its algorithm, e.g. "sha256:", by applying its layers one
+// on top of the other, honouring whiteouts.
+func (a *Archive) Filesystem(id string) (Filesystem, error) {
+	if i := strings.Index(id, ":"); i != -1 {
+		id = id[i+1:]
+	}
+	layers, ok := a.Images[id]
+	if !ok {
+		return nil, fmt.Errorf("image %v not found in archive", id)
+	}
+	fs := Filesystem{}
+	for _, layer := range layers {
+		fs.apply(a.layers[path.Clean(layer)])
+	}
+	return fs, nil
+}
+
+// apply applies the provided layer on top of this filesystem: whiteouts
+// remove files of lower layers, and the layer's other files are added, or
+// replace those of lower layers.
+func (fs Filesystem) apply(files layerFiles) {
+	for _, file := range files {
+		dir, name := path.Split(file.Path)
+		switch {
+		case name == opaque:
+			fs.removeChildren(path.Clean(dir))
+		case strings.HasPrefix(name, whiteout):
+			removed := path.Join(dir, strings.TrimPrefix(name, whiteout))
+			delete(fs, removed)
+			fs.removeChildren(removed)
+		}
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(path.Base(file.Path), whiteout) {
+			fs[file.Path] = file
+		}
+	}
+}
+
+func (fs Filesystem) removeChildren(dir string) {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	for p := range fs {
+		if strings.HasPrefix(p, prefix) {
+			delete(fs, p)
+		}
+	}
+}
+
+// CompareFiles lists the files added, removed or modified from x to y,
+// ordered by path. Files are modified if their type, permissions, size,
+// content, or link's target changed. Directories are only listed when added
+// or removed.
+func CompareFiles(x, y Filesystem) []*FileChange {
+	paths := []string{}
+	for p := range x {
+		paths = append(paths, p)
+	}
+	for p := range y {
+		if _, ok := x[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	changes := []*FileChange{}
+	for _, p := range paths {
+		xFile, inX := x[p]
+		yFile, inY := y[p]
+		switch {
+		case !inX:
+			changes = append(changes, &FileChange{Path: p, Change: FileAdded, YSize: yFile.Size})
+		case !inY:
+			changes = append(changes, &FileChange{Path: p, Change: FileRemoved, XSize: xFile.Size})
+		case xFile.Type == tar.TypeDir && yFile.Type == tar.TypeDir:
+			continue
+		case xFile.Type != yFile.Type || xFile.Mode != yFile.Mode || xFile.Size != yFile.Size || xFile.Digest != yFile.Digest || xFile.Linkname != yFile.Linkname:
+			changes = append(changes, &FileChange{Path: p, Change: FileModified, XSize: xFile.Size, YSize: yFile.Size})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,121 @@
+package layers_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/layers"
+)
+
+// entry is an entry of a tar archive: a directory if its name ends with "/", a regular file otherwise.
+type entry struct {
+	name    string
+	content string
+}
+
+func tarball(t *testing.T, entries ...entry) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.content)), Typeflag: tar.TypeReg}
+		if e.name[len(e.name)-1] == '/' {
+			header.Mode, header.Typeflag = 0755, tar.TypeDir
+		}
+		assert.NoError(t, tw.WriteHeader(header))
+		_, err := tw.Write([]byte(e.content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+// saved creates a "docker save" archive of two images, x and y, sharing their base layer, with manifest.json last, like Docker does.
+func saved(t *testing.T, layersDir func(name string) string, config func(id string) string) []byte {
+	base := tarball(t,
+		entry{"etc/", ""},
+		entry{"etc/os-release", "ID=alpine"},
+		entry{"etc/motd", "Welcome!"},
+		entry{"usr/", ""},
+		entry{"usr/share/", ""},
+		entry{"usr/share/doc/", ""},
+		entry{"usr/share/doc/README", "docs"},
+		entry{"usr/share/doc/LICENSE", "license"},
+	)
+	xApp := tarball(t,
+		entry{"app/", ""},
+		entry{"app/server", "v1"},
+		entry{"app/config.yaml", "port: 8080"},
+	)
+	yApp := tarball(t,
+		entry{"app/", ""},
+		entry{"app/server", "v2!"},
+		entry{"app/assets/", ""},
+		entry{"app/assets/logo.png", "png"},
+		// Whiteouts hide the base layer's files:
+		entry{"etc/.wh.motd", ""},
+		entry{"usr/share/doc/.wh..wh..opq", ""},
+		entry{"usr/share/doc/NOTICE", "notice"},
+	)
+	manifest, err := json.Marshal([]map[string]interface{}{
+		{"Config": config("xid"), "Layers": []string{layersDir("base"), layersDir("x")}},
+		{"Config": config("yid"), "Layers": []string{layersDir("base"), layersDir("y")}},
+	})
+	assert.NoError(t, err)
+	return tarball(t,
+		entry{layersDir("base"), string(base)},
+		entry{layersDir("x"), string(xApp)},
+		entry{layersDir("y"), string(yApp)},
+		entry{config("xid"), `{"architecture": "amd64"}`},
+		entry{config("yid"), `{"architecture": "amd64"}`},
+		entry{"manifest.json", string(manifest)},
+	)
+}
+
+func TestReadArchive(t *testing.T) {
+	for name, archive := range map[string][]byte{
+		"legacy": saved(t, func(name string) string { return name + "/layer.tar" }, func(id string) string { return id + ".json" }),
+		"oci":    saved(t, func(name string) string { return "blobs/sha256/" + name }, func(id string) string { return "blobs/sha256/" + id }),
+	} {
+		t.Run(name, func(t *testing.T) {
+			a, err := layers.ReadArchive(bytes.NewReader(archive), func(path string) bool { return path == "/etc/os-release" })
+			assert.NoError(t, err)
+			x, err := a.Filesystem("sha256:xid")
+			assert.NoError(t, err)
+			y, err := a.Filesystem("yid")
+			assert.NoError(t, err)
+
+			assert.Equal(t, []*layers.FileChange{
+				{Path: "/app/assets", Change: layers.FileAdded},
+				{Path: "/app/assets/logo.png", Change: layers.FileAdded, YSize: 3},
+				{Path: "/app/config.yaml", Change: layers.FileRemoved, XSize: 10},
+				{Path: "/app/server", Change: layers.FileModified, XSize: 2, YSize: 3},
+				{Path: "/etc/motd", Change: layers.FileRemoved, XSize: 8},
+				{Path: "/usr/share/doc/LICENSE", Change: layers.FileRemoved, XSize: 7},
+				{Path: "/usr/share/doc/NOTICE", Change: layers.FileAdded, YSize: 6},
+				{Path: "/usr/share/doc/README", Change: layers.FileRemoved, XSize: 4},
+			}, layers.CompareFiles(x, y))
+			assert.Empty(t, layers.CompareFiles(x, x))
+			assert.Equal(t, "ID=alpine", string(y["/etc/os-release"].Content))
+			assert.Nil(t, y["/app/server"].Content)
+
+			_, err = a.Filesystem("sha256:unknown")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCompareFilesDetectsContentChanges(t *testing.T) {
+	x := layers.Filesystem{"/app": {Path: "/app", Type: tar.TypeReg, Mode: 0755, Size: 2, Digest: "aa"}}
+	y := layers.Filesystem{"/app": {Path: "/app", Type: tar.TypeReg, Mode: 0755, Size: 2, Digest: "bb"}}
+	assert.Equal(t, []*layers.FileChange{{Path: "/app", Change: layers.FileModified, XSize: 2, YSize: 2}}, layers.CompareFiles(x, y))
+	y["/app"] = &layers.File{Path: "/app", Type: tar.TypeReg, Mode: 0700, Size: 2, Digest: "aa"}
+	assert.Len(t, layers.CompareFiles(x, y), 1)
+}
+
+func TestReadArchiveWithoutManifest(t *testing.T) {
+	_, err := layers.ReadArchive(bytes.NewReader(tarball(t, entry{"base/layer.tar", string(tarball(t, entry{"etc/", ""}))})), nil)
+	assert.EqualError(t, err, "invalid archive: missing manifest.json")
+}
@@ -0,0 +1,79 @@
+package layers
+
+// Layer is one of the layers of an image.
+type Layer struct {
+	// Digest is the layer's diff ID, i.e. the digest of its uncompressed
+	// content, which identifies it across images, e.g.
+	// "sha256:8a9e9863dbb6e10edb5adfe917c00da84e1700fa76e7ed02476aa6e6fb8ee0d8".
+	Digest string `json:"digest"`
+	// Size is the size of the layer's content, in bytes.
+	Size int64 `json:"size"`
+	// CreatedBy is the instruction which created the layer, if known, e.g.
+	// "/bin/sh -c apk add --no-cache ca-certificates".
+	CreatedBy string `json:"createdBy,omitempty"`
+}
+
+// Diff compares the layers of two images, x and y.
+type Diff struct {
+	// Shared are y's layers also found in x.
+	Shared []*Layer `json:"shared"`
+	// Added are y's layers not found in x.
+	Added []*Layer `json:"added"`
+	// Removed are x's layers not found in y.
+	Removed []*Layer `json:"removed"`
+	// XSize and YSize are the total sizes of the layers of x and y, in bytes.
+	XSize int64 `json:"xSize"`
+	YSize int64 `json:"ySize"`
+	// Files lists the files added, removed or modified by the added and
+	// removed layers, if diffed with these.
+	Files []*FileChange `json:"files,omitempty"`
+}
+
+// SharedSize is the total size of the shared layers, in bytes.
+func (d *Diff) SharedSize() int64 {
+	return size(d.Shared)
+}
+
+// AddedSize is the total size of the added layers, in bytes.
+func (d *Diff) AddedSize() int64 {
+	return size(d.Added)
+}
+
+// RemovedSize is the total size of the removed layers, in bytes.
+func (d *Diff) RemovedSize() int64 {
+	return size(d.Removed)
+}
+
+func size(layers []*Layer) int64 {
+	total := int64(0)
+	for _, layer := range layers {
+		total += layer.Size
+	}
+	return total
+}
+
+// Compare compares the provided layers of two images, x and y, ordered from
+// the bottom, i.e. base, layer to the top one, by digest.
+func Compare(x, y []*Layer) *Diff {
+	inX, inY := map[string]bool{}, map[string]bool{}
+	for _, layer := range x {
+		inX[layer.Digest] = true
+	}
+	for _, layer := range y {
+		inY[layer.Digest] = true
+	}
+	diff := &Diff{Shared: []*Layer{}, Added: []*Layer{}, Removed: []*Layer{}, XSize: size(x), YSize: size(y)}
+	for _, layer := range y {
+		if inX[layer.Digest] {
+			diff.Shared = append(diff.Shared, layer)
+		} else {
+			diff.Added = append(diff.Added, layer)
+		}
+	}
+	for _, layer := range x {
+		if !inY[layer.Digest] {
+			diff.Removed = append(diff.Removed, layer)
+		}
+	}
+	return diff
+}
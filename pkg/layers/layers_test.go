@@ -0,0 +1,31 @@
+package layers_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/layers"
+)
+
+func TestCompare(t *testing.T) {
+	base := &layers.Layer{Digest: "sha256:base", Size: 5000000, CreatedBy: "ADD file:1234 in /"}
+	packages := &layers.Layer{Digest: "sha256:packages", Size: 2000000, CreatedBy: "RUN apk add --no-cache ca-certificates"}
+	xApp := &layers.Layer{Digest: "sha256:app-1", Size: 10000000, CreatedBy: "COPY app /app"}
+	yApp := &layers.Layer{Digest: "sha256:app-2", Size: 12000000, CreatedBy: "COPY app /app"}
+	assets := &layers.Layer{Digest: "sha256:assets", Size: 1000000, CreatedBy: "COPY assets /assets"}
+
+	diff := layers.Compare([]*layers.Layer{base, packages, xApp}, []*layers.Layer{base, packages, yApp, assets})
+	assert.Equal(t, []*layers.Layer{base, packages}, diff.Shared)
+	assert.Equal(t, []*layers.Layer{yApp, assets}, diff.Added)
+	assert.Equal(t, []*layers.Layer{xApp}, diff.Removed)
+	assert.Equal(t, int64(17000000), diff.XSize)
+	assert.Equal(t, int64(20000000), diff.YSize)
+	assert.Equal(t, int64(7000000), diff.SharedSize())
+	assert.Equal(t, int64(13000000), diff.AddedSize())
+	assert.Equal(t, int64(10000000), diff.RemovedSize())
+
+	diff = layers.Compare([]*layers.Layer{base}, []*layers.Layer{base})
+	assert.Equal(t, []*layers.Layer{base}, diff.Shared)
+	assert.Empty(t, diff.Added)
+	assert.Empty(t, diff.Removed)
+}
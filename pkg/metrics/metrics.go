@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "imagediff"
+
+// Steps of diffs, as timed by StepDuration, and counted by Errors when failing.
+const (
+	// Diff is the whole diff, from pulling images to walking the history.
+	Diff = "diff"
+	// Docker is connecting to the Docker daemon.
+	Docker = "docker"
+	// Pull is pulling an image, if not present locally.
+	Pull = "pull"
+	// Inspect is reading an image's labels.
+	Inspect = "inspect"
+	// Labels is finding the source code repository and revision in an image's labels.
+	Labels = "labels"
+	// Clone is cloning a repository.
+	Clone = "clone"
+	// Refresh is fetching into a shared clone the revisions missing from it.
+	Refresh = "refresh"
+	// Revision is resolving revisions to commits.
+	Revision = "revision"
+	// History is walking the history between two commits, including computing stats, if enabled.
+	History = "history"
+	// Submodules is finding the submodules bumped between two commits, and their repositories.
+	Submodules = "submodules"
+	// Dependencies is comparing the dependencies listed by manifests, e.g. go.mod, at both revisions.
+	Dependencies = "dependencies"
+	// Layers is comparing images' layers.
+	Layers = "layers"
+	// Save is reading images' files, to compare these, or their packages.
+	Save = "save"
+	// Enrich is enriching changes with their host's API.
+	Enrich = "enrich"
+	// Notify is sending a diff's result to a notifier, e.g. a webhook.
+	Notify = "notify"
+)
+
+// Caches, as counted by CacheRequests.
+const (
+	// Clones is the cache of clones of repositories.
+	Clones = "clones"
+	// API is the cache of responses of hosts' APIs, e.g. GitHub's.
+	API = "api"
+)
+
+// Outcomes of cache requests, as counted by CacheRequests.
+const (
+	Hit = "hit"
+	// Revalidated is a stale response the API confirmed was still valid.
+	Revalidated = "revalidated"
+	Miss        = "miss"
+)
+
+var (
+	// StepDuration observes how long each step of diffs takes, by step.
+	StepDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "step_duration_seconds",
+		Help:      "Duration of the steps of diffs, e.g. pulling images, cloning repositories and walking their history.",
+		// From 10ms to ~5.5min:
+		Buckets: prometheus.ExponentialBuckets(0.01, 2, 16),
+	}, []string{"step"})
+	// Errors counts failed steps of diffs, by step.
+	Errors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "errors_total",
+		Help:      "Number of failed steps of diffs, by step.",
+	}, []string{"step"})
+	// CacheRequests counts requests to caches, by cache and outcome, e.g. to
+	// compute their hit ratios.
+	CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Number of requests to caches, by cache and outcome: hit, revalidated or miss.",
+	}, []string{"cache", "result"})
+	// RequestDuration observes how long requests to the server's diffs take, by status code.
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests for diffs, by status code.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 16),
+	}, []string{"code"})
+	// DiffsInFlight counts the diffs running, including those outliving their requests.
+	DiffsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "diffs_in_flight",
+		Help:      "Number of diffs running.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(StepDuration, Errors, CacheRequests, RequestDuration, DiffsInFlight)
+}
+
+// Step times a step of a diff.
+type Step struct {
+	name  string
+	start time.Time
+}
+
+// StartStep starts timing the provided step.
+func StartStep(name string) *Step {
+	return &Step{name: name, start: time.Now()}
+}
+
+// Done records the duration of this step, and its failure, if the provided
+// error is not nil, which it returns as is, for convenience.
+func (s *Step) Done(err error) error {
+	StepDuration.WithLabelValues(s.name).Observe(time.Since(s.start).Seconds())
+	if err != nil {
+		Errors.WithLabelValues(s.name).Inc()
+	}
+	return err
+}
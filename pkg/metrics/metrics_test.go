@@ -0,0 +1,31 @@
+package metrics_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/metrics"
+)
+
+func count(t *testing.T, step string) (uint64, float64) {
+	var histogram, counter dto.Metric
+	assert.NoError(t, metrics.StepDuration.WithLabelValues(step).(prometheus.Histogram).Write(&histogram))
+	assert.NoError(t, metrics.Errors.WithLabelValues(step).Write(&counter))
+	return histogram.GetHistogram().GetSampleCount(), counter.GetCounter().GetValue()
+}
+
+func TestStep(t *testing.T) {
+	assert.NoError(t, metrics.StartStep("test").Done(nil))
+	observations, errs := count(t, "test")
+	assert.Equal(t, uint64(1), observations)
+	assert.Equal(t, float64(0), errs)
+
+	err := errors.New("manifest unknown")
+	assert.Equal(t, err, metrics.StartStep("test").Done(err))
+	observations, errs = count(t, "test")
+	assert.Equal(t, uint64(2), observations)
+	assert.Equal(t, float64(1), errs)
+}
@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/render"
+)
+
+// defaultMaxChanges bounds how many changes are listed in chat messages, which
+// would otherwise get unwieldy, if not rejected, for long changelogs. The
+// remaining changes are summarised, and linked to.
+const defaultMaxChanges = 20
+
+// maxMessageLength bounds the length of the first line of changes' messages listed in chat messages.
+const maxMessageLength = 120
+
+// title summarises the provided result, e.g. "org/app:v1.0.0 → org/app:v1.1.0".
+func title(result *diff.Result) string {
+	return fmt.Sprintf("%v → %v", result.X, result.Y)
+}
+
+// change is a change, as listed in chat messages.
+type change struct {
+	hash    string
+	url     string
+	message string
+	author  string
+}
+
+// changes lists the first max changes of the provided result, and how many were left out.
+func changes(result *diff.Result, max int) ([]change, int) {
+	if max <= 0 {
+		max = defaultMaxChanges
+	}
+	listed := []change{}
+	for _, c := range result.ChangeLog {
+		if len(listed) == max {
+			break
+		}
+		listed = append(listed, change{
+			hash:    render.ShortHash(c.Revision),
+			url:     result.Repository.CommitURL(c.Revision),
+			message: truncate(render.FirstLine(c.Message), maxMessageLength),
+			author:  c.Author.Name,
+		})
+	}
+	return listed, len(result.ChangeLog) - len(listed)
+}
+
+// truncate shortens the provided text to at most max characters, ellipsis included.
+func truncate(text string, max int) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:max-1]) + "…"
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return fmt.Sprintf("%v %v", n, word)
+	}
+	return fmt.Sprintf("%v %vs", n, word)
+}
@@ -0,0 +1,58 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/render"
+)
+
+// Notifier sends diffs' results somewhere, e.g. to a chat or another service.
+type Notifier interface {
+	Notify(result *diff.Result) error
+}
+
+// Webhook POSTs diffs' results, as JSON, to a URL.
+type Webhook struct {
+	URL string
+	// HTTPClient sends requests. Defaults to a client timing out after 30s.
+	HTTPClient *http.Client
+}
+
+// NewWebhook creates a new notifier POSTing diffs' results, as JSON, to the provided URL.
+func NewWebhook(url string) *Webhook {
+	return &Webhook{URL: url}
+}
+
+// Notify POSTs the provided result.
+func (w *Webhook) Notify(result *diff.Result) error {
+	var body bytes.Buffer
+	if err := render.JSON(&body, result); err != nil {
+		return err
+	}
+	return post(w.HTTPClient, w.URL, "application/json", &body)
+}
+
+var defaultClient = &http.Client{Timeout: 30 * time.Second}
+
+// post sends the provided body to the provided URL, and fails unless the response is successful.
+func post(client *http.Client, url, contentType string, body io.Reader) error {
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Post(url, contentType, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to notify [%v]: %v: %s", url, resp.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package notify_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/notify"
+)
+
+func TestWebhook(t *testing.T) {
+	var received diff.Result
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer sink.Close()
+
+	result := &diff.Result{X: "org/app:v1.0.0", Y: "org/app:v1.1.0", XRevision: "abc", YRevision: "def"}
+	assert.NoError(t, notify.NewWebhook(sink.URL).Notify(result))
+	assert.Equal(t, *result, received)
+}
+
+func TestWebhookFailure(t *testing.T) {
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadGateway)
+	}))
+	defer sink.Close()
+
+	err := notify.NewWebhook(sink.URL).Notify(&diff.Result{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "502 Bad Gateway: nope")
+}
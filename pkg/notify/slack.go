@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/render"
+)
+
+// Slack's limits, see also: https://api.slack.com/reference/block-kit/blocks
+const (
+	slackMaxHeaderLength  = 150
+	slackMaxSectionLength = 3000
+	slackMaxBlocks        = 50
+)
+
+// Slack posts diffs' results to a Slack incoming webhook, formatted with Block
+// Kit, see also: https://api.slack.com/messaging/webhooks
+type Slack struct {
+	URL string
+	// MaxChanges bounds how many changes are listed. Defaults to 20.
+	MaxChanges int
+	// HTTPClient sends requests. Defaults to a client timing out after 30s.
+	HTTPClient *http.Client
+}
+
+// NewSlack creates a new notifier posting diffs' results to the provided Slack incoming webhook.
+func NewSlack(url string) *Slack {
+	return &Slack{URL: url}
+}
+
+// Notify posts the provided result.
+func (s *Slack) Notify(result *diff.Result) error {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(SlackMessage(result, s.MaxChanges)); err != nil {
+		return err
+	}
+	return post(s.HTTPClient, s.URL, "application/json", &body)
+}
+
+// SlackMessage formats the provided result as a Slack message, listing at most maxChanges changes.
+func SlackMessage(result *diff.Result, maxChanges int) map[string]interface{} {
+	compareURL := result.Repository.CompareURL(result.XRevision, result.YRevision)
+	blocks := []map[string]interface{}{
+		{"type": "header", "text": slackText("plain_text", truncate(title(result), slackMaxHeaderLength))},
+		{"type": "context", "elements": []interface{}{slackText("mrkdwn", fmt.Sprintf("<%v|%v>: <%v|`%v...%v`>",
+			result.Repository.URL(), slackEscape(result.Repository.Path()),
+			compareURL, render.ShortHash(result.XRevision), render.ShortHash(result.YRevision)))}},
+	}
+	listed, left := changes(result, maxChanges)
+	if len(listed) == 0 {
+		blocks = append(blocks, slackSection("No changes."))
+	}
+	// Changes are packed in as few sections as their length allows, leaving room for the summary of those left out:
+	var lines []string
+	length := 0
+	for i, c := range listed {
+		line := fmt.Sprintf("• <%v|`%v`> %v — %v", c.url, c.hash, slackEscape(c.message), slackEscape(c.author))
+		if length+len(line)+1 > slackMaxSectionLength {
+			if len(blocks)+2 == slackMaxBlocks {
+				left += len(listed) - i
+				break
+			}
+			blocks = append(blocks, slackSection(strings.Join(lines, "\n")))
+			lines, length = nil, 0
+		}
+		lines = append(lines, line)
+		length += len(line) + 1
+	}
+	if len(lines) > 0 {
+		blocks = append(blocks, slackSection(strings.Join(lines, "\n")))
+	}
+	if left > 0 {
+		blocks = append(blocks, map[string]interface{}{"type": "context", "elements": []interface{}{
+			slackText("mrkdwn", fmt.Sprintf("…and <%v|%v>.", compareURL, plural(left, "more change"))),
+		}})
+	}
+	return map[string]interface{}{
+		// Shown in notifications, and by clients not supporting blocks:
+		"text":   fmt.Sprintf("%v: %v", title(result), plural(len(result.ChangeLog), "change")),
+		"blocks": blocks,
+	}
+}
+
+func slackSection(text string) map[string]interface{} {
+	return map[string]interface{}{"type": "section", "text": slackText("mrkdwn", text)}
+}
+
+func slackText(kind, text string) map[string]interface{} {
+	return map[string]interface{}{"type": kind, "text": text}
+}
+
+// slackEscaper escapes the characters Slack's mrkdwn uses for links and mentions.
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func slackEscape(text string) string {
+	return slackEscaper.Replace(text)
+}
@@ -0,0 +1,108 @@
+package notify_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/notify"
+	"github.com/weaveworks-experiments/imagediff/pkg/repository"
+)
+
+func newResult(changes int) *diff.Result {
+	result := &diff.Result{
+		X:          "org/app:v1.0.0",
+		Y:          "org/app:v1.1.0",
+		Repository: &repository.GitRepository{Host: "github.com", Organization: "org", Repository: "app"},
+		XRevision:  "1111111111111111111111111111111111111111",
+		YRevision:  "2222222222222222222222222222222222222222",
+		ChangeLog:  []*diff.Change{},
+	}
+	for i := 0; i < changes; i++ {
+		result.ChangeLog = append(result.ChangeLog, &diff.Change{
+			Revision: fmt.Sprintf("%040x", i+1),
+			Message:  fmt.Sprintf("Fix <bug> & *issue* #%v\n\nDetails.", i+1),
+			Author:   diff.Signature{Name: "Jane Doe", Email: "jane@example.com", When: time.Unix(0, 0)},
+		})
+	}
+	return result
+}
+
+// receive starts a local HTTP server decoding the JSON POSTed to it into v.
+func receive(t *testing.T, v interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(v))
+	}))
+}
+
+type slackMessage struct {
+	Text   string `json:"text"`
+	Blocks []struct {
+		Type string `json:"type"`
+		Text struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"text"`
+		Elements []struct {
+			Text string `json:"text"`
+		} `json:"elements"`
+	} `json:"blocks"`
+}
+
+func TestSlack(t *testing.T) {
+	var message slackMessage
+	server := receive(t, &message)
+	defer server.Close()
+
+	assert.NoError(t, notify.NewSlack(server.URL).Notify(newResult(2)))
+	assert.Equal(t, "org/app:v1.0.0 → org/app:v1.1.0: 2 changes", message.Text)
+	assert.Len(t, message.Blocks, 3)
+	assert.Equal(t, "header", message.Blocks[0].Type)
+	assert.Equal(t, "org/app:v1.0.0 → org/app:v1.1.0", message.Blocks[0].Text.Text)
+	assert.Equal(t, "<https://github.com/org/app|org/app>: <https://github.com/org/app/compare/1111111111111111111111111111111111111111...2222222222222222222222222222222222222222|`1111111...2222222`>", message.Blocks[1].Elements[0].Text)
+	assert.Equal(t, "section", message.Blocks[2].Type)
+	assert.Equal(t, "mrkdwn", message.Blocks[2].Text.Type)
+	assert.Equal(t, strings.Join([]string{
+		"• <https://github.com/org/app/commit/0000000000000000000000000000000000000001|`0000000`> Fix &lt;bug&gt; &amp; *issue* #1 — Jane Doe",
+		"• <https://github.com/org/app/commit/0000000000000000000000000000000000000002|`0000000`> Fix &lt;bug&gt; &amp; *issue* #2 — Jane Doe",
+	}, "\n"), message.Blocks[2].Text.Text)
+}
+
+func TestSlackTruncation(t *testing.T) {
+	var message slackMessage
+	server := receive(t, &message)
+	defer server.Close()
+
+	assert.NoError(t, notify.NewSlack(server.URL).Notify(newResult(25)))
+	last := message.Blocks[len(message.Blocks)-1]
+	assert.Equal(t, "context", last.Type)
+	assert.Equal(t, "…and <https://github.com/org/app/compare/1111111111111111111111111111111111111111...2222222222222222222222222222222222222222|5 more changes>.", last.Elements[0].Text)
+	assert.Equal(t, 20, strings.Count(fmt.Sprint(message.Blocks), "•"))
+
+	// Sections and blocks stay within Slack's limits:
+	result := newResult(1000)
+	for _, change := range result.ChangeLog {
+		change.Message = strings.Repeat("a", 500)
+	}
+	assert.NoError(t, (&notify.Slack{URL: server.URL, MaxChanges: 1000}).Notify(result))
+	assert.Len(t, message.Blocks, 50)
+	for _, block := range message.Blocks {
+		assert.True(t, len(block.Text.Text) <= 3000)
+	}
+	assert.Contains(t, message.Blocks[49].Elements[0].Text, "more changes")
+}
+
+func TestSlackNoChanges(t *testing.T) {
+	message := notify.SlackMessage(newResult(0), 0)
+	blocks := message["blocks"].([]map[string]interface{})
+	assert.Len(t, blocks, 3)
+	assert.Equal(t, map[string]interface{}{"type": "mrkdwn", "text": "No changes."}, blocks[2]["text"])
+}
@@ -0,0 +1,87 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/render"
+)
+
+// Teams posts diffs' results to a Microsoft Teams incoming webhook, formatted
+// as an adaptive card, see also:
+// https://learn.microsoft.com/en-us/microsoftteams/platform/webhooks-and-connectors/how-to/connectors-using
+type Teams struct {
+	URL string
+	// MaxChanges bounds how many changes are listed. Defaults to 20.
+	MaxChanges int
+	// HTTPClient sends requests. Defaults to a client timing out after 30s.
+	HTTPClient *http.Client
+}
+
+// NewTeams creates a new notifier posting diffs' results to the provided Microsoft Teams incoming webhook.
+func NewTeams(url string) *Teams {
+	return &Teams{URL: url}
+}
+
+// Notify posts the provided result.
+func (t *Teams) Notify(result *diff.Result) error {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(TeamsMessage(result, t.MaxChanges)); err != nil {
+		return err
+	}
+	return post(t.HTTPClient, t.URL, "application/json", &body)
+}
+
+// TeamsMessage formats the provided result as a Microsoft Teams message,
+// made of an adaptive card listing at most maxChanges changes.
+func TeamsMessage(result *diff.Result, maxChanges int) map[string]interface{} {
+	compareURL := result.Repository.CompareURL(result.XRevision, result.YRevision)
+	body := []map[string]interface{}{
+		{"type": "TextBlock", "text": teamsEscape(title(result)), "size": "Medium", "weight": "Bolder", "wrap": true},
+		{"type": "TextBlock", "text": fmt.Sprintf("[%v](%v): [%v...%v](%v)",
+			teamsEscape(result.Repository.Path()), result.Repository.URL(),
+			render.ShortHash(result.XRevision), render.ShortHash(result.YRevision), compareURL),
+			"isSubtle": true, "spacing": "None", "wrap": true},
+	}
+	listed, left := changes(result, maxChanges)
+	if len(listed) == 0 {
+		body = append(body, map[string]interface{}{"type": "TextBlock", "text": "No changes.", "wrap": true})
+	}
+	for _, c := range listed {
+		body = append(body, map[string]interface{}{
+			"type":    "TextBlock",
+			"text":    fmt.Sprintf("[%v](%v) %v — %v", c.hash, c.url, teamsEscape(c.message), teamsEscape(c.author)),
+			"spacing": "Small",
+			"wrap":    true,
+		})
+	}
+	if left > 0 {
+		body = append(body, map[string]interface{}{"type": "TextBlock", "text": fmt.Sprintf("…and %v.", plural(left, "more change")), "isSubtle": true, "wrap": true})
+	}
+	return map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]interface{}{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.2",
+				"body":    body,
+				"actions": []map[string]interface{}{
+					{"type": "Action.OpenUrl", "title": "View all changes", "url": compareURL},
+				},
+			},
+		}},
+	}
+}
+
+// teamsEscaper escapes the characters adaptive cards' Markdown uses for emphasis and links.
+var teamsEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`)
+
+func teamsEscape(text string) string {
+	return teamsEscaper.Replace(text)
+}
@@ -0,0 +1,66 @@
+package notify_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/notify"
+)
+
+type teamsMessage struct {
+	Type        string `json:"type"`
+	Attachments []struct {
+		ContentType string `json:"contentType"`
+		Content     struct {
+			Type    string `json:"type"`
+			Version string `json:"version"`
+			Body    []struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"body"`
+			Actions []struct {
+				Type  string `json:"type"`
+				Title string `json:"title"`
+				URL   string `json:"url"`
+			} `json:"actions"`
+		} `json:"content"`
+	} `json:"attachments"`
+}
+
+func TestTeams(t *testing.T) {
+	var message teamsMessage
+	server := receive(t, &message)
+	defer server.Close()
+
+	assert.NoError(t, notify.NewTeams(server.URL).Notify(newResult(2)))
+	assert.Equal(t, "message", message.Type)
+	assert.Len(t, message.Attachments, 1)
+	card := message.Attachments[0].Content
+	assert.Equal(t, "application/vnd.microsoft.card.adaptive", message.Attachments[0].ContentType)
+	assert.Equal(t, "AdaptiveCard", card.Type)
+	texts := []string{}
+	for _, block := range card.Body {
+		assert.Equal(t, "TextBlock", block.Type)
+		texts = append(texts, block.Text)
+	}
+	assert.Equal(t, []string{
+		"org/app:v1.0.0 → org/app:v1.1.0",
+		"[org/app](https://github.com/org/app): [1111111...2222222](https://github.com/org/app/compare/1111111111111111111111111111111111111111...2222222222222222222222222222222222222222)",
+		"[0000000](https://github.com/org/app/commit/0000000000000000000000000000000000000001) Fix <bug> & \\*issue\\* #1 — Jane Doe",
+		"[0000000](https://github.com/org/app/commit/0000000000000000000000000000000000000002) Fix <bug> & \\*issue\\* #2 — Jane Doe",
+	}, texts)
+	assert.Len(t, card.Actions, 1)
+	assert.Equal(t, "Action.OpenUrl", card.Actions[0].Type)
+	assert.Equal(t, "https://github.com/org/app/compare/1111111111111111111111111111111111111111...2222222222222222222222222222222222222222", card.Actions[0].URL)
+}
+
+func TestTeamsTruncation(t *testing.T) {
+	var message teamsMessage
+	server := receive(t, &message)
+	defer server.Close()
+
+	assert.NoError(t, (&notify.Teams{URL: server.URL, MaxChanges: 3}).Notify(newResult(10)))
+	body := message.Attachments[0].Content.Body
+	assert.Len(t, body, 2+3+1)
+	assert.Equal(t, "…and 7 more changes.", body[len(body)-1].Text)
+}
@@ -0,0 +1,14 @@
+package packages
+
+// parseApk parses apk's database of installed packages, see also:
+// https://wiki.alpinelinux.org/wiki/Apk_spec
+func parseApk(data []byte) []*Package {
+	packages := []*Package{}
+	for _, p := range paragraphs(data, ":") {
+		if p["P"] == "" {
+			continue
+		}
+		packages = append(packages, &Package{Name: p["P"], Version: p["V"], Arch: p["A"], Manager: Apk})
+	}
+	return packages
+}
@@ -0,0 +1,19 @@
+package packages
+
+import "strings"
+
+// parseDpkg parses dpkg's status database, see also: dpkg(1), and deb-control(5).
+func parseDpkg(data []byte) []*Package {
+	packages := []*Package{}
+	for _, p := range paragraphs(data, ":") {
+		// Removed packages may still be listed, e.g. as "deinstall ok config-files":
+		if status, ok := p["Status"]; ok && !strings.HasSuffix(status, " installed") {
+			continue
+		}
+		if p["Package"] == "" {
+			continue
+		}
+		packages = append(packages, &Package{Name: p["Package"], Version: p["Version"], Arch: p["Architecture"], Manager: Dpkg})
+	}
+	return packages
+}
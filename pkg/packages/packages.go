@@ -0,0 +1,206 @@
+package packages
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/layers"
+)
+
+// Package managers.
+const (
+	Dpkg = "dpkg"
+	Apk  = "apk"
+	RPM  = "rpm"
+)
+
+// Package is an OS package installed in an image.
+type Package struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Arch    string `json:"arch,omitempty"`
+	// Manager is the package manager which installed the package, one of Dpkg, Apk or RPM.
+	Manager string `json:"manager"`
+}
+
+// Kinds of Change.
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// Change is a package added, removed, or whose version changed, between two images.
+type Change struct {
+	Name string `json:"name"`
+	// Arch is the package's architecture, only set for packages installed for
+	// several architectures, e.g. multi-arch dpkg installs, to tell these apart.
+	Arch    string `json:"arch,omitempty"`
+	Manager string `json:"manager"`
+	// Change is one of Added, Removed or Changed.
+	Change string `json:"change"`
+	// X and Y are the package's versions in both images, if any.
+	X string `json:"x,omitempty"`
+	Y string `json:"y,omitempty"`
+}
+
+// Package is the package's name, qualified with its architecture if set, e.g. libc6:i386.
+func (c *Change) Package() string {
+	if c.Arch == "" {
+		return c.Name
+	}
+	return c.Name + ":" + c.Arch
+}
+
+func (c *Change) String() string {
+	switch c.Change {
+	case Added:
+		return fmt.Sprintf("%v: added %v", c.Package(), c.Y)
+	case Removed:
+		return fmt.Sprintf("%v: removed %v", c.Package(), c.X)
+	default:
+		return fmt.Sprintf("%v: %v → %v", c.Package(), c.X, c.Y)
+	}
+}
+
+// Paths of packages' databases.
+const (
+	dpkgStatus    = "/var/lib/dpkg/status"
+	dpkgStatusDir = "/var/lib/dpkg/status.d/"
+	apkInstalled  = "/lib/apk/db/installed"
+	rpmPackages   = "/var/lib/rpm/Packages"
+	rpmSQLite     = "/var/lib/rpm/rpmdb.sqlite"
+)
+
+// IsDatabase tells whether the provided path is that of a packages'
+// database, i.e. whether to keep its content when reading images' layers.
+func IsDatabase(path string) bool {
+	switch path {
+	case dpkgStatus, apkInstalled, rpmPackages:
+		return true
+	}
+	// Distroless images have one file per package:
+	return strings.HasPrefix(path, dpkgStatusDir)
+}
+
+// Read lists the packages installed in the provided filesystem, by any of
+// the supported package managers: dpkg (Debian, Ubuntu, distroless), apk
+// (Alpine) and rpm (Red Hat, CentOS, Fedora, SUSE), as long as its database is
+// in the Berkeley DB format, as opposed to the SQLite one used by recent
+// versions, which are not supported.
+func Read(fs layers.Filesystem) ([]*Package, error) {
+	packages := []*Package{}
+	if file, ok := fs[dpkgStatus]; ok {
+		packages = append(packages, parseDpkg(file.Content)...)
+	}
+	for path, file := range fs {
+		if strings.HasPrefix(path, dpkgStatusDir) && file.Content != nil {
+			packages = append(packages, parseDpkg(file.Content)...)
+		}
+	}
+	if file, ok := fs[apkInstalled]; ok {
+		packages = append(packages, parseApk(file.Content)...)
+	}
+	if file, ok := fs[rpmPackages]; ok {
+		rpms, err := parseRPM(file.Content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %v: %v", rpmPackages, err)
+		}
+		packages = append(packages, rpms...)
+	} else if _, ok := fs[rpmSQLite]; ok {
+		return nil, fmt.Errorf("unsupported rpm database: %v", rpmSQLite)
+	}
+	sort.SliceStable(packages, func(i, j int) bool {
+		if packages[i].Manager != packages[j].Manager {
+			return packages[i].Manager < packages[j].Manager
+		}
+		return packages[i].Name < packages[j].Name
+	})
+	return packages, nil
+}
+
+// Compare lists the packages added, removed, or whose version changed, from
+// x to y, ordered by package manager, name and architecture.
+func Compare(x, y []*Package) []*Change {
+	// Packages installed for several architectures in either image, e.g.
+	// libc6:amd64 and libc6:i386, are told apart by their architectures, but
+	// others are not, so that changing architectures, e.g. from all to amd64,
+	// is still a change.
+	name := func(p *Package) string { return p.Manager + "\x00" + p.Name }
+	multiArch := map[string]bool{}
+	for _, packages := range [][]*Package{x, y} {
+		archs := map[string]string{}
+		for _, p := range packages {
+			if arch, ok := archs[name(p)]; ok && arch != p.Arch {
+				multiArch[name(p)] = true
+			}
+			archs[name(p)] = p.Arch
+		}
+	}
+	arch := func(p *Package) string {
+		if multiArch[name(p)] {
+			return p.Arch
+		}
+		return ""
+	}
+	key := func(p *Package) string { return name(p) + "\x00" + arch(p) }
+	xPackages, yPackages := map[string]*Package{}, map[string]*Package{}
+	keys := []string{}
+	for _, p := range x {
+		xPackages[key(p)] = p
+		keys = append(keys, key(p))
+	}
+	for _, p := range y {
+		if _, ok := xPackages[key(p)]; !ok {
+			keys = append(keys, key(p))
+		}
+		yPackages[key(p)] = p
+	}
+	sort.Strings(keys)
+	changes := []*Change{}
+	for i, k := range keys {
+		if i > 0 && keys[i-1] == k {
+			continue
+		}
+		xPackage, inX := xPackages[k]
+		yPackage, inY := yPackages[k]
+		switch {
+		case !inX:
+			changes = append(changes, &Change{Name: yPackage.Name, Arch: arch(yPackage), Manager: yPackage.Manager, Change: Added, Y: yPackage.Version})
+		case !inY:
+			changes = append(changes, &Change{Name: xPackage.Name, Arch: arch(xPackage), Manager: xPackage.Manager, Change: Removed, X: xPackage.Version})
+		case xPackage.Version != yPackage.Version:
+			changes = append(changes, &Change{Name: xPackage.Name, Arch: arch(xPackage), Manager: xPackage.Manager, Change: Changed, X: xPackage.Version, Y: yPackage.Version})
+		}
+	}
+	return changes
+}
+
+// paragraphs splits the provided database into its paragraphs, i.e. blocks
+// of "key: value" lines, separated by blank lines, as used by dpkg and apk.
+// Continuation lines, starting with a space, are ignored.
+func paragraphs(data []byte, separator string) []map[string]string {
+	paragraphs := []map[string]string{}
+	current := map[string]string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			if len(current) > 0 {
+				paragraphs = append(paragraphs, current)
+				current = map[string]string{}
+			}
+			continue
+		}
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		if parts := strings.SplitN(line, separator, 2); len(parts) == 2 {
+			current[parts[0]] = strings.TrimSpace(parts[1])
+		}
+	}
+	if len(current) > 0 {
+		paragraphs = append(paragraphs, current)
+	}
+	return paragraphs
+}
@@ -0,0 +1,212 @@
+package packages_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/layers"
+	"github.com/weaveworks-experiments/imagediff/pkg/packages"
+)
+
+const dpkgStatus = `Package: base-files
+Status: install ok installed
+Priority: required
+Architecture: amd64
+Version: 10.3+deb10u4
+Description: Debian base system miscellaneous files
+ This package contains the basic filesystem hierarchy of a Debian system.
+
+Package: wget
+Status: deinstall ok config-files
+Architecture: amd64
+Version: 1.20.1-1.1
+
+Package: libc6
+Status: install ok installed
+Architecture: amd64
+Version: 2.28-10
+`
+
+const distrolessStatus = `Package: tzdata
+Version: 2020a-0+deb10u1
+Architecture: all
+`
+
+const apkInstalled = `C:Q1ZlSpK4sSkDTpEvzcyDPBx0VCsgI=
+P:musl
+V:1.1.24-r2
+A:x86_64
+T:the musl c library (libc) implementation
+
+C:Q1m2M1O8V4rCjNhBdq1kgfVyFFZLo=
+P:ca-certificates-cacert
+V:20191127-r2
+A:x86_64
+`
+
+// bdb builds rpm's Packages database, in Berkeley DB's hash format, with
+// tiny pages, so that packages' headers overflow across several pages.
+func bdb(headers ...[]byte) []byte {
+	const pageSize = 64
+	order := binary.LittleEndian
+	pages := [][]byte{make([]byte, pageSize), make([]byte, pageSize)}
+	meta, hash := pages[0], pages[1]
+	order.PutUint32(meta[12:], 0x061561)
+	order.PutUint32(meta[20:], pageSize)
+	hash[25] = 13
+	order.PutUint16(hash[20:], uint16(2*len(headers)))
+	for i, header := range headers {
+		// Keys are ignored, and values are off-page:
+		offset := 40 + 12*i
+		order.PutUint16(hash[26+4*i:], 0)
+		order.PutUint16(hash[28+4*i:], uint16(offset))
+		hash[offset] = 3
+		order.PutUint32(hash[offset+4:], uint32(len(pages)))
+		order.PutUint32(hash[offset+8:], uint32(len(header)))
+		for len(header) > 0 {
+			page := make([]byte, pageSize)
+			page[25] = 7
+			n := copy(page[26:], header)
+			header = header[n:]
+			if len(header) > 0 {
+				order.PutUint32(page[16:], uint32(len(pages)+1))
+			} else {
+				order.PutUint16(page[22:], uint16(n))
+			}
+			pages = append(pages, page)
+		}
+	}
+	order.PutUint32(meta[32:], uint32(len(pages)-1))
+	data := []byte{}
+	for _, page := range pages {
+		data = append(data, page...)
+	}
+	return data
+}
+
+// rpmHeader builds a package's header, as stored in rpm's database.
+func rpmHeader(name, version, release string, epoch uint32, arch string) []byte {
+	index, store := []byte{}, []byte{}
+	add := func(tag, kind uint32, value []byte) {
+		entry := make([]byte, 16)
+		binary.BigEndian.PutUint32(entry, tag)
+		binary.BigEndian.PutUint32(entry[4:], kind)
+		binary.BigEndian.PutUint32(entry[8:], uint32(len(store)))
+		binary.BigEndian.PutUint32(entry[12:], 1)
+		index = append(index, entry...)
+		store = append(store, value...)
+	}
+	add(1000, 6, append([]byte(name), 0))
+	add(1001, 6, append([]byte(version), 0))
+	add(1002, 6, append([]byte(release), 0))
+	if epoch != 0 {
+		value := make([]byte, 4)
+		binary.BigEndian.PutUint32(value, epoch)
+		add(1003, 4, value)
+	}
+	add(1022, 6, append([]byte(arch), 0))
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(len(index)/16))
+	binary.BigEndian.PutUint32(header[4:], uint32(len(store)))
+	return append(append(header, index...), store...)
+}
+
+func TestRead(t *testing.T) {
+	fs := layers.Filesystem{
+		"/var/lib/dpkg/status":          {Path: "/var/lib/dpkg/status", Content: []byte(dpkgStatus)},
+		"/var/lib/dpkg/status.d/tzdata": {Path: "/var/lib/dpkg/status.d/tzdata", Content: []byte(distrolessStatus)},
+		"/lib/apk/db/installed":         {Path: "/lib/apk/db/installed", Content: []byte(apkInstalled)},
+		"/var/lib/rpm/Packages": {Path: "/var/lib/rpm/Packages", Content: bdb(
+			rpmHeader("openssl-libs", "1.1.1g", "15.el8_3", 1, "x86_64"),
+			rpmHeader("gpg-pubkey", "8483c65d", "5ccc5b19", 0, ""),
+		)},
+	}
+	installed, err := packages.Read(fs)
+	assert.NoError(t, err)
+	assert.Equal(t, []*packages.Package{
+		{Name: "ca-certificates-cacert", Version: "20191127-r2", Arch: "x86_64", Manager: packages.Apk},
+		{Name: "musl", Version: "1.1.24-r2", Arch: "x86_64", Manager: packages.Apk},
+		{Name: "base-files", Version: "10.3+deb10u4", Arch: "amd64", Manager: packages.Dpkg},
+		{Name: "libc6", Version: "2.28-10", Arch: "amd64", Manager: packages.Dpkg},
+		{Name: "tzdata", Version: "2020a-0+deb10u1", Arch: "all", Manager: packages.Dpkg},
+		{Name: "openssl-libs", Version: "1:1.1.1g-15.el8_3", Arch: "x86_64", Manager: packages.RPM},
+	}, installed)
+
+	installed, err = packages.Read(layers.Filesystem{})
+	assert.NoError(t, err)
+	assert.Empty(t, installed)
+}
+
+func TestReadFailsOnInvalidDatabases(t *testing.T) {
+	_, err := packages.Read(layers.Filesystem{"/var/lib/rpm/Packages": {Path: "/var/lib/rpm/Packages", Content: []byte("SQLite format 3")}})
+	assert.Error(t, err)
+	// A hash page claiming more entries than it can hold:
+	corrupt := bdb(rpmHeader("openssl-libs", "1.1.1g", "15.el8_3", 1, "x86_64"))
+	binary.LittleEndian.PutUint16(corrupt[64+20:], 0xffff)
+	_, err = packages.Read(layers.Filesystem{"/var/lib/rpm/Packages": {Path: "/var/lib/rpm/Packages", Content: corrupt}})
+	assert.EqualError(t, err, "failed to read /var/lib/rpm/Packages: invalid Berkeley DB hash page: 1, with 65535 entries")
+	// A database claiming more pages, of more bytes, than would fit in memory:
+	corrupt = bdb(rpmHeader("openssl-libs", "1.1.1g", "15.el8_3", 1, "x86_64"))
+	binary.LittleEndian.PutUint32(corrupt[20:], 0xffffffff)
+	binary.LittleEndian.PutUint32(corrupt[32:], 0xffffffff)
+	_, err = packages.Read(layers.Filesystem{"/var/lib/rpm/Packages": {Path: "/var/lib/rpm/Packages", Content: corrupt}})
+	assert.EqualError(t, err, "failed to read /var/lib/rpm/Packages: invalid Berkeley DB database: 4294967296 pages of 4294967295 bytes, in 384 bytes")
+	_, err = packages.Read(layers.Filesystem{"/var/lib/rpm/rpmdb.sqlite": {Path: "/var/lib/rpm/rpmdb.sqlite"}})
+	assert.EqualError(t, err, "unsupported rpm database: /var/lib/rpm/rpmdb.sqlite")
+}
+
+func TestIsDatabase(t *testing.T) {
+	assert.True(t, packages.IsDatabase("/var/lib/dpkg/status"))
+	assert.True(t, packages.IsDatabase("/var/lib/dpkg/status.d/tzdata"))
+	assert.True(t, packages.IsDatabase("/lib/apk/db/installed"))
+	assert.True(t, packages.IsDatabase("/var/lib/rpm/Packages"))
+	assert.False(t, packages.IsDatabase("/var/lib/dpkg/status-old"))
+	assert.False(t, packages.IsDatabase("/etc/passwd"))
+}
+
+func TestCompare(t *testing.T) {
+	x := []*packages.Package{
+		{Name: "musl", Version: "1.1.24-r2", Manager: packages.Apk},
+		{Name: "openssl", Version: "1.1.1d-r3", Manager: packages.Apk},
+		{Name: "wget", Version: "1.20.3-r0", Manager: packages.Apk},
+	}
+	y := []*packages.Package{
+		{Name: "ca-certificates", Version: "20191127-r2", Manager: packages.Apk},
+		{Name: "musl", Version: "1.1.24-r2", Manager: packages.Apk},
+		{Name: "openssl", Version: "1.1.1g-r0", Manager: packages.Apk},
+		{Name: "wget", Version: "1.20.3-1", Manager: packages.Dpkg},
+	}
+	changes := packages.Compare(x, y)
+	assert.Equal(t, []*packages.Change{
+		{Name: "ca-certificates", Manager: packages.Apk, Change: packages.Added, Y: "20191127-r2"},
+		{Name: "openssl", Manager: packages.Apk, Change: packages.Changed, X: "1.1.1d-r3", Y: "1.1.1g-r0"},
+		{Name: "wget", Manager: packages.Apk, Change: packages.Removed, X: "1.20.3-r0"},
+		{Name: "wget", Manager: packages.Dpkg, Change: packages.Added, Y: "1.20.3-1"},
+	}, changes)
+	assert.Equal(t, "openssl: 1.1.1d-r3 → 1.1.1g-r0", changes[1].String())
+	assert.Empty(t, packages.Compare(x, x))
+}
+
+func TestCompareMultiArch(t *testing.T) {
+	x := []*packages.Package{
+		{Name: "libc6", Version: "2.28-10", Arch: "amd64", Manager: packages.Dpkg},
+		{Name: "libc6", Version: "2.28-10", Arch: "i386", Manager: packages.Dpkg},
+		{Name: "tzdata", Version: "2020a-0+deb10u1", Arch: "all", Manager: packages.Dpkg},
+		{Name: "zlib1g", Version: "1:1.2.11.dfsg-1", Arch: "amd64", Manager: packages.Dpkg},
+	}
+	y := []*packages.Package{
+		{Name: "libc6", Version: "2.28-10+deb10u1", Arch: "amd64", Manager: packages.Dpkg},
+		{Name: "tzdata", Version: "2021a-0+deb10u1", Arch: "all", Manager: packages.Dpkg},
+		{Name: "zlib1g", Version: "1:1.2.11.dfsg-1", Arch: "amd64", Manager: packages.Dpkg},
+		{Name: "zlib1g", Version: "1:1.2.11.dfsg-1", Arch: "i386", Manager: packages.Dpkg},
+	}
+	changes := packages.Compare(x, y)
+	assert.Equal(t, []*packages.Change{
+		{Name: "libc6", Arch: "amd64", Manager: packages.Dpkg, Change: packages.Changed, X: "2.28-10", Y: "2.28-10+deb10u1"},
+		{Name: "libc6", Arch: "i386", Manager: packages.Dpkg, Change: packages.Removed, X: "2.28-10"},
+		{Name: "tzdata", Manager: packages.Dpkg, Change: packages.Changed, X: "2020a-0+deb10u1", Y: "2021a-0+deb10u1"},
+		{Name: "zlib1g", Arch: "i386", Manager: packages.Dpkg, Change: packages.Added, Y: "1:1.2.11.dfsg-1"},
+	}, changes)
+	assert.Equal(t, "libc6:i386: removed 2.28-10", changes[1].String())
+}
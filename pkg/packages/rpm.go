@@ -0,0 +1,155 @@
+package packages
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Berkeley DB's hash databases, as used by rpm's Packages database, are made
+// of pages, the first of which describes the database. Other pages either
+// index key/value pairs, or, for values too large to fit, e.g. packages'
+// headers, contain their content, chained across as many pages as needed.
+const (
+	bdbHashMagic      = 0x061561
+	bdbPageHeaderSize = 26
+	// Types of pages:
+	bdbHashUnsortedPage = 2
+	bdbOverflowPage     = 7
+	bdbHashPage         = 13
+	// Types of entries of hash pages:
+	bdbOffPageEntry = 3
+)
+
+// parseRPM parses rpm's Packages database, in Berkeley DB's hash format.
+func parseRPM(data []byte) ([]*Package, error) {
+	if len(data) < 72 {
+		return nil, errors.New("not a Berkeley DB database")
+	}
+	// Databases are written with the byte order of the machine which created them:
+	var order binary.ByteOrder = binary.LittleEndian
+	if order.Uint32(data[12:]) != bdbHashMagic {
+		order = binary.BigEndian
+		if order.Uint32(data[12:]) != bdbHashMagic {
+			return nil, errors.New("not a Berkeley DB hash database")
+		}
+	}
+	pageSize := int(order.Uint32(data[20:]))
+	lastPage := int(order.Uint32(data[32:]))
+	// Both are read from the database, and could overflow if multiplied, hence:
+	if pageSize < bdbPageHeaderSize || lastPage >= len(data)/pageSize {
+		return nil, fmt.Errorf("invalid Berkeley DB database: %v pages of %v bytes, in %v bytes", lastPage+1, pageSize, len(data))
+	}
+	page := func(n int) []byte { return data[n*pageSize : (n+1)*pageSize] }
+	packages := []*Package{}
+	for n := 1; n <= lastPage; n++ {
+		p := page(n)
+		if p[25] != bdbHashPage && p[25] != bdbHashUnsortedPage {
+			continue
+		}
+		entries := int(order.Uint16(p[20:]))
+		// Entries are pairs of keys, i.e. packages' numbers, and values, i.e. their headers:
+		if bdbPageHeaderSize+2*entries > len(p) {
+			return nil, fmt.Errorf("invalid Berkeley DB hash page: %v, with %v entries", n, entries)
+		}
+		for i := 1; i < entries; i += 2 {
+			offset := int(order.Uint16(p[bdbPageHeaderSize+2*i:]))
+			if offset+12 > len(p) || p[offset] != bdbOffPageEntry {
+				continue
+			}
+			header, err := overflow(page, lastPage, int(order.Uint32(p[offset+4:])), order)
+			if err != nil {
+				return nil, err
+			}
+			pkg, err := parseRPMHeader(header)
+			if err != nil {
+				return nil, err
+			}
+			// rpm's database includes the public keys it trusts as pseudo-packages:
+			if pkg.Name != "gpg-pubkey" {
+				packages = append(packages, pkg)
+			}
+		}
+	}
+	return packages, nil
+}
+
+// overflow reads the value starting on the provided overflow page.
+func overflow(page func(int) []byte, lastPage, n int, order binary.ByteOrder) ([]byte, error) {
+	var value []byte
+	for visited := 0; n != 0; visited++ {
+		if n > lastPage || visited > lastPage {
+			return nil, fmt.Errorf("invalid Berkeley DB overflow page: %v", n)
+		}
+		p := page(n)
+		if p[25] != bdbOverflowPage {
+			return nil, fmt.Errorf("invalid Berkeley DB overflow page: %v, of type %v", n, p[25])
+		}
+		next := int(order.Uint32(p[16:]))
+		if next == 0 {
+			// The last page's "free area offset" is the length of the value's end:
+			length := int(order.Uint16(p[22:]))
+			if bdbPageHeaderSize+length > len(p) {
+				return nil, fmt.Errorf("invalid Berkeley DB overflow page: %v", n)
+			}
+			value = append(value, p[bdbPageHeaderSize:bdbPageHeaderSize+length]...)
+		} else {
+			value = append(value, p[bdbPageHeaderSize:]...)
+		}
+		n = next
+	}
+	return value, nil
+}
+
+// Tags of rpm's headers, see also: https://rpm-software-management.github.io/rpm/manual/tags.html
+const (
+	rpmTagName    = 1000
+	rpmTagVersion = 1001
+	rpmTagRelease = 1002
+	rpmTagEpoch   = 1003
+	rpmTagArch    = 1022
+	// Types of tags' values:
+	rpmInt32  = 4
+	rpmString = 6
+)
+
+// parseRPMHeader parses the header of a package, as stored in rpm's
+// database, i.e. an index of tags, followed by their values, in big endian.
+func parseRPMHeader(data []byte) (*Package, error) {
+	if len(data) < 8 {
+		return nil, errors.New("invalid rpm header")
+	}
+	tags := int(binary.BigEndian.Uint32(data))
+	length := int(binary.BigEndian.Uint32(data[4:]))
+	if tags < 0 || length < 0 || 8+16*tags+length > len(data) {
+		return nil, errors.New("invalid rpm header")
+	}
+	store := data[8+16*tags : 8+16*tags+length]
+	values := map[uint32]string{}
+	epoch := ""
+	for i := 0; i < tags; i++ {
+		entry := data[8+16*i:]
+		tag, kind, offset := binary.BigEndian.Uint32(entry), binary.BigEndian.Uint32(entry[4:]), int(binary.BigEndian.Uint32(entry[8:]))
+		if offset >= len(store) {
+			continue
+		}
+		switch {
+		case kind == rpmString && (tag == rpmTagName || tag == rpmTagVersion || tag == rpmTagRelease || tag == rpmTagArch):
+			if end := bytes.IndexByte(store[offset:], 0); end != -1 {
+				values[tag] = string(store[offset : offset+end])
+			}
+		case kind == rpmInt32 && tag == rpmTagEpoch && offset+4 <= len(store):
+			epoch = fmt.Sprint(binary.BigEndian.Uint32(store[offset:]))
+		}
+	}
+	if values[rpmTagName] == "" {
+		return nil, errors.New("invalid rpm header: missing name")
+	}
+	// Versions are formatted like rpm does, i.e. [epoch:]version-release:
+	version := values[rpmTagVersion] + "-" + values[rpmTagRelease]
+	if epoch != "" && epoch != "0" {
+		version = epoch + ":" + version
+	}
+	return &Package{Name: values[rpmTagName], Version: version, Arch: values[rpmTagArch], Manager: RPM}, nil
+}
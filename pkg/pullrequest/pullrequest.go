@@ -0,0 +1,120 @@
+package pullrequest
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+)
+
+// PullRequest encapsulates a pull request (GitHub) or merge request (GitLab), as found in a change log.
+type PullRequest struct {
+	Number int
+	Title  string
+	// Author and Labels are only known when changes were enriched with their host's API.
+	Author string
+	Labels []string
+	// Merge is the change which landed this pull request, i.e. its merge commit or squashed commit.
+	Merge *diff.Change
+	// Changes are the changes this pull request brought in, i.e. its commits, or its squashed commit.
+	Changes []*diff.Change
+}
+
+var (
+	gitHubMergeRegex = regexp.MustCompile(`^Merge pull request #(\d+) from (\S+)`)
+	squashRegex      = regexp.MustCompile(`^(.*?)\s*\(#(\d+)\)$`)
+	gitLabMergeRegex = regexp.MustCompile(`(?m)^See merge request (?:\S+)?!(\d+)\s*$`)
+)
+
+// Parse detects the pull request the provided change landed, from its commit message, i.e.:
+// - GitHub's merge commits: "Merge pull request #40 from owner/branch",
+// - GitHub's squashed commits: "Some title (#40)",
+// - GitLab's merge commits: "See merge request group/project!40".
+func Parse(change *diff.Change) (*PullRequest, bool) {
+	subject, body := split(change.Message)
+	if matches := gitHubMergeRegex.FindStringSubmatch(subject); matches != nil {
+		title := firstLine(body)
+		if title == "" {
+			title = matches[2]
+		}
+		return newPullRequest(matches[1], title, change), true
+	}
+	if matches := gitLabMergeRegex.FindStringSubmatch(body); matches != nil {
+		title := firstLine(body)
+		if strings.HasPrefix(title, "See merge request ") {
+			title = subject
+		}
+		return newPullRequest(matches[1], title, change), true
+	}
+	if matches := squashRegex.FindStringSubmatch(subject); matches != nil {
+		return newPullRequest(matches[2], matches[1], change), true
+	}
+	return nil, false
+}
+
+func newPullRequest(number, title string, merge *diff.Change) *PullRequest {
+	n, _ := strconv.Atoi(number)
+	return &PullRequest{Number: n, Title: title, Merge: merge}
+}
+
+func split(message string) (string, string) {
+	message = strings.TrimSpace(message)
+	if idx := strings.Index(message, "\n"); idx != -1 {
+		return strings.TrimSpace(message[:idx]), strings.TrimSpace(message[idx+1:])
+	}
+	return message, ""
+}
+
+func firstLine(text string) string {
+	line, _ := split(text)
+	return line
+}
+
+// FromChange detects the pull request the provided change landed, preferring
+// the metadata from its host's API, if the change was enriched with it, to
+// parsing its commit message.
+func FromChange(change *diff.Change) (*PullRequest, bool) {
+	pullRequest, ok := Parse(change)
+	if len(change.PullRequests) == 0 {
+		return pullRequest, ok
+	}
+	metadata := change.PullRequests[0]
+	if !ok || pullRequest.Number != metadata.Number {
+		pullRequest = &PullRequest{Number: metadata.Number, Merge: change}
+	}
+	pullRequest.Title = metadata.Title
+	pullRequest.Author = metadata.Author
+	pullRequest.Labels = metadata.Labels
+	return pullRequest, true
+}
+
+// Extract lists the pull requests landed in the provided change log, each
+// only once and along with its changes, in the order of the change log. The
+// changes which are not part of any pull request, e.g. pushed directly to the
+// mainline, are returned separately.
+func Extract(changeLog []*diff.Change) ([]*PullRequest, []*diff.Change) {
+	pullRequests := []*PullRequest{}
+	byNumber := map[int]*PullRequest{}
+	others := []*diff.Change{}
+	for _, group := range diff.GroupByMerge(changeLog) {
+		pullRequest, ok := FromChange(group.Change)
+		if !ok {
+			others = append(others, group.Change)
+			others = append(others, group.Merged...)
+			continue
+		}
+		if existing, ok := byNumber[pullRequest.Number]; ok {
+			pullRequest = existing
+		} else {
+			byNumber[pullRequest.Number] = pullRequest
+			pullRequests = append(pullRequests, pullRequest)
+		}
+		if group.Change.Merge {
+			pullRequest.Changes = append(pullRequest.Changes, group.Merged...)
+		} else {
+			pullRequest.Changes = append(pullRequest.Changes, group.Change)
+		}
+	}
+	return pullRequests, others
+}
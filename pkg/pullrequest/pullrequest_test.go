@@ -0,0 +1,104 @@
+package pullrequest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/pullrequest"
+)
+
+func TestParseGitHubMerge(t *testing.T) {
+	change := &diff.Change{Message: "Merge pull request #40 from microscaling/k8s-labels\n\nKubernetes labels\n", Merge: true}
+	pr, ok := pullrequest.Parse(change)
+	assert.True(t, ok)
+	assert.Equal(t, &pullrequest.PullRequest{Number: 40, Title: "Kubernetes labels", Merge: change}, pr)
+}
+
+func TestParseGitHubMergeWithoutBody(t *testing.T) {
+	change := &diff.Change{Message: "Merge pull request #40 from microscaling/k8s-labels\n", Merge: true}
+	pr, ok := pullrequest.Parse(change)
+	assert.True(t, ok)
+	assert.Equal(t, 40, pr.Number)
+	assert.Equal(t, "microscaling/k8s-labels", pr.Title)
+}
+
+func TestParseGitHubSquash(t *testing.T) {
+	change := &diff.Change{Message: "Use a separate KubeLabelConfig type (#123)\n\n* Add type\n* Fix tests\n"}
+	pr, ok := pullrequest.Parse(change)
+	assert.True(t, ok)
+	assert.Equal(t, &pullrequest.PullRequest{Number: 123, Title: "Use a separate KubeLabelConfig type", Merge: change}, pr)
+}
+
+func TestParseGitLabMerge(t *testing.T) {
+	change := &diff.Change{Message: "Merge branch 'k8s-labels' into 'master'\n\nKubernetes labels\n\nSee merge request group/subgroup/project!12\n", Merge: true}
+	pr, ok := pullrequest.Parse(change)
+	assert.True(t, ok)
+	assert.Equal(t, &pullrequest.PullRequest{Number: 12, Title: "Kubernetes labels", Merge: change}, pr)
+
+	change = &diff.Change{Message: "Merge branch 'k8s-labels' into 'master'\n\nSee merge request !12", Merge: true}
+	pr, ok = pullrequest.Parse(change)
+	assert.True(t, ok)
+	assert.Equal(t, 12, pr.Number)
+	assert.Equal(t, "Merge branch 'k8s-labels' into 'master'", pr.Title)
+}
+
+func TestParseNoPullRequest(t *testing.T) {
+	for _, message := range []string{
+		"Bump version",
+		"Fix #40",
+		"Merge branch 'master' into k8s-labels",
+		"Refer to (#40) in the middle",
+	} {
+		_, ok := pullrequest.Parse(&diff.Change{Message: message})
+		assert.False(t, ok, message)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	// History, from oldest to most recent:
+	//   a - b ----- m - s - e
+	//    \         /
+	//     c ---- d
+	e := &diff.Change{Revision: "e", Message: "Bump version", Parents: []string{"s"}}
+	s := &diff.Change{Revision: "s", Message: "Fix typo (#41)", Parents: []string{"m"}}
+	m := &diff.Change{Revision: "m", Message: "Merge pull request #40 from foo/bar\n\nAdd bar", Parents: []string{"b", "d"}, Merge: true}
+	d := &diff.Change{Revision: "d", Message: "Add bar", Parents: []string{"c"}}
+	c := &diff.Change{Revision: "c", Message: "Add foo", Parents: []string{"a"}}
+	b := &diff.Change{Revision: "b", Message: "Revert \"Fix typo (#41)\"", Parents: []string{"a"}}
+
+	pullRequests, others := pullrequest.Extract([]*diff.Change{e, s, m, d, c, b})
+	assert.Equal(t, []*pullrequest.PullRequest{
+		{Number: 41, Title: "Fix typo", Merge: s, Changes: []*diff.Change{s}},
+		{Number: 40, Title: "Add bar", Merge: m, Changes: []*diff.Change{d, c}},
+	}, pullRequests)
+	assert.Equal(t, []*diff.Change{e, b}, others)
+}
+
+func TestExtractListsEachPullRequestOnce(t *testing.T) {
+	b := &diff.Change{Revision: "b", Message: "Fix typo (#41)", Parents: []string{"a"}}
+	c := &diff.Change{Revision: "c", Message: "Fix another typo (#41)", Parents: []string{"b"}}
+	pullRequests, others := pullrequest.Extract([]*diff.Change{c, b})
+	assert.Equal(t, []*pullrequest.PullRequest{
+		{Number: 41, Title: "Fix another typo", Merge: c, Changes: []*diff.Change{c, b}},
+	}, pullRequests)
+	assert.Empty(t, others)
+}
+
+func TestFromChangePrefersAPIMetadata(t *testing.T) {
+	metadata := &diff.PullRequest{Number: 40, Title: "Kubernetes labels", Author: "octocat", Labels: []string{"k8s"}}
+	change := &diff.Change{Message: "Merge pull request #40 from microscaling/k8s-labels\n\nk8s labels", Merge: true, PullRequests: []*diff.PullRequest{metadata}}
+	pr, ok := pullrequest.FromChange(change)
+	assert.True(t, ok)
+	assert.Equal(t, &pullrequest.PullRequest{Number: 40, Title: "Kubernetes labels", Author: "octocat", Labels: []string{"k8s"}, Merge: change}, pr)
+
+	// e.g. rebased pull requests, which leave no trace in commit messages:
+	change = &diff.Change{Message: "Add labels", PullRequests: []*diff.PullRequest{metadata}}
+	pr, ok = pullrequest.FromChange(change)
+	assert.True(t, ok)
+	assert.Equal(t, 40, pr.Number)
+	assert.Equal(t, "Kubernetes labels", pr.Title)
+
+	_, ok = pullrequest.FromChange(&diff.Change{Message: "Add labels"})
+	assert.False(t, ok)
+}
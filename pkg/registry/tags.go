@@ -0,0 +1,197 @@
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+)
+
+// dockerHub is the domain of Docker Hub's images, whose registry's API is served from dockerHubAPI.
+const (
+	dockerHub    = "docker.io"
+	dockerHubAPI = "registry-1.docker.io"
+)
+
+// Client lists the tags of images from Docker registries' HTTP API V2.
+type Client struct {
+	// HTTPClient sends requests. Defaults to a client timing out after 30 seconds.
+	HTTPClient *http.Client
+	// AuthConfigs are the credentials to authenticate with, by registry, e.g.
+	// from ReadAuthConfigs. Optional, for public images.
+	AuthConfigs AuthConfigs
+}
+
+// Tags lists the tags of the provided image's repository, e.g. "quay.io/org/app".
+func (c *Client) Tags(image string) ([]string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return nil, err
+	}
+	domain, path := reference.Domain(named), reference.Path(named)
+	host := domain
+	if domain == dockerHub {
+		host = dockerHubAPI
+	}
+	tags := []string{}
+	next := fmt.Sprintf("https://%v/v2/%v/tags/list", host, path)
+	var token string
+	for next != "" {
+		resp, body, err := c.get(next, token, domain)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusUnauthorized && token == "" {
+			// Most registries require a token, even for public images:
+			if token, err = c.token(resp.Header.Get("WWW-Authenticate"), domain); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to list the tags of [%v]: %v: %s", image, resp.Status, strings.TrimSpace(string(body)))
+		}
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, err
+		}
+		tags = append(tags, page.Tags...)
+		if next, err = nextPage(next, resp.Header.Get("Link")); err != nil {
+			return nil, err
+		}
+	}
+	return tags, nil
+}
+
+func (c *Client) get(url, token, domain string) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if username, password, ok := c.credentials(domain); ok {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	return resp, body, err
+}
+
+var challengeRegex = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// token requests a bearer token, as per the provided challenge, e.g.:
+//
+//	Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:org/app:pull"
+func (c *Client) token(challenge, domain string) (string, error) {
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return "", fmt.Errorf("unsupported authentication challenge: [%v]", challenge)
+	}
+	params := url.Values{}
+	var realm string
+	for _, matches := range challengeRegex.FindAllStringSubmatch(challenge, -1) {
+		if matches[1] == "realm" {
+			realm = matches[2]
+		} else {
+			params.Set(matches[1], matches[2])
+		}
+	}
+	if realm == "" {
+		return "", fmt.Errorf("no realm in authentication challenge: [%v]", challenge)
+	}
+	resp, body, err := c.get(realm+"?"+params.Encode(), "", domain)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to authenticate against [%v]: %v: %s", realm, resp.Status, strings.TrimSpace(string(body)))
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", err
+	}
+	if token.Token != "" {
+		return token.Token, nil
+	}
+	return token.AccessToken, nil
+}
+
+// credentials finds the username and password for the provided registry, if any.
+func (c *Client) credentials(domain string) (string, string, bool) {
+	keys := []string{domain, "https://" + domain}
+	if domain == dockerHub {
+		keys = append(keys, "https://index.docker.io/v1/")
+	}
+	for _, key := range keys {
+		config, ok := c.AuthConfigs[key]
+		if !ok {
+			continue
+		}
+		if config.Username != "" {
+			return config.Username, config.Password, true
+		}
+		if username, password, ok := decodeAuth(config); ok {
+			return username, password, true
+		}
+	}
+	return "", "", false
+}
+
+func decodeAuth(config types.AuthConfig) (string, string, bool) {
+	decoded, err := base64.StdEncoding.DecodeString(config.Auth)
+	if err != nil {
+		return "", "", false
+	}
+	parts := strings.SplitN(strings.TrimSpace(string(decoded)), ":", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+var linkRegex = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// nextPage resolves the URL of the next page of tags, if any, from the provided Link header, e.g.:
+//
+//	</v2/org/app/tags/list?last=v1.2.3&n=100>; rel="next"
+func nextPage(current, link string) (string, error) {
+	matches := linkRegex.FindStringSubmatch(link)
+	if matches == nil {
+		return "", nil
+	}
+	base, err := url.Parse(current)
+	if err != nil {
+		return "", err
+	}
+	next, err := base.Parse(matches[1])
+	if err != nil {
+		return "", err
+	}
+	return next.String(), nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return defaultClient
+}
+
+var defaultClient = &http.Client{Timeout: 30 * time.Second}
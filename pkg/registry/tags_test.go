@@ -0,0 +1,77 @@
+package registry_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/registry"
+)
+
+// fakeRegistry emulates the subset of Docker registries' HTTP API V2
+// imagediff uses, with token authentication, like Docker Hub and Quay, and
+// paginated tags.
+func fakeRegistry(t *testing.T) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			username, password, ok := r.BasicAuth()
+			if !ok || username != "foo" || password != "bar" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			assert.Equal(t, "repository:org/app:pull", r.URL.Query().Get("scope"))
+			fmt.Fprint(w, `{"token": "t0k3n"}`)
+		case "/v2/org/app/tags/list":
+			if r.Header.Get("Authorization") != "Bearer t0k3n" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%v/token",service="registry",scope="repository:org/app:pull"`, server.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("last") == "" {
+				w.Header().Set("Link", `</v2/org/app/tags/list?last=v0.9.1&n=2>; rel="next"`)
+				fmt.Fprint(w, `{"name": "org/app", "tags": ["v0.9.0", "v0.9.1"]}`)
+				return
+			}
+			fmt.Fprint(w, `{"name": "org/app", "tags": ["v0.10.0"]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors": [{"code": "NAME_UNKNOWN"}]}`)
+		}
+	}))
+	return server
+}
+
+func TestTags(t *testing.T) {
+	server := fakeRegistry(t)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "https://")
+	client := &registry.Client{
+		HTTPClient:  server.Client(),
+		AuthConfigs: registry.AuthConfigs{host: types.AuthConfig{Auth: "Zm9vOmJhcg=="}},
+	}
+
+	tags, err := client.Tags(host + "/org/app")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"v0.9.0", "v0.9.1", "v0.10.0"}, tags)
+
+	_, err = client.Tags(host + "/org/unknown")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "404 Not Found")
+}
+
+func TestTagsWithoutCredentials(t *testing.T) {
+	server := fakeRegistry(t)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "https://")
+	client := &registry.Client{HTTPClient: server.Client()}
+
+	_, err := client.Tags(host + "/org/app")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to authenticate")
+}
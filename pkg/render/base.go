@@ -0,0 +1,42 @@
+package render
+
+// baseMarkdown lists the changes between the images' base images, if diffed
+// with these, and whatever else was diffed between these, in turn, e.g.:
+//
+//	#### Base Image Changes between `golang:1.13-alpine` and `golang:1.14-alpine`
+//
+//	[docker-library/golang](https://github.com/docker-library/golang): [`1a2b3c4...5d6e7f8`](https://github.com/docker-library/golang/compare/1a2b3c4...5d6e7f8)
+//
+//	- [`5d6e7f8`](https://github.com/docker-library/golang/commit/5d6e7f8) Update to 1.14
+const baseMarkdown = "#### Base Image Changes between `{{.X}}` and `{{.Y}}`" + `
+
+{{with .Repository -}}
+[{{.Organization}}/{{.Repository}}]({{.URL}}): ` + "[`{{shortHash $.XRevision}}...{{shortHash $.YRevision}}`]({{.CompareURL $.XRevision $.YRevision}})" + `
+
+{{range groups $.ChangeLog -}}
+- [` + "`{{shortHash .Change.Revision}}`" + `]({{$.Repository.CommitURL .Change.Revision}}) {{escape (firstLine .Change.Message)}}
+{{range .Merged -}}
+{{"  "}}- [` + "`{{shortHash .Revision}}`" + `]({{$.Repository.CommitURL .Revision}}) {{escape (firstLine .Message)}}
+{{end -}}
+{{else -}}
+No changes.
+{{end -}}
+{{else -}}
+No source code repository found.
+{{end -}}
+{{if extras .}}
+{{end -}}
+{{template "extras-markdown" .}}`
+
+// baseText lists the changes between the images' base images, if diffed with
+// these, and whatever else was diffed between these, in turn, at the end of
+// plain text templates.
+const baseText = `Base image changes between {{.X}} and {{.Y}}:
+{{range .ChangeLog -}}
+{{"    "}}{{shortHash .Revision}} {{firstLine .Message}}
+{{else -}}
+{{"    "}}{{if .Repository}}No changes.{{else}}No source code repository found.{{end}}
+{{end -}}
+{{if extras .}}
+{{end -}}
+{{template "extras-text" .}}`
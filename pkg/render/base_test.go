@@ -0,0 +1,104 @@
+package render_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/issue"
+	"github.com/weaveworks-experiments/imagediff/pkg/packages"
+	"github.com/weaveworks-experiments/imagediff/pkg/render"
+	"github.com/weaveworks-experiments/imagediff/pkg/repository"
+)
+
+// sampleResultWithBase diffs images whose base images changed, and whose base
+// images' base images changed too, without being labelled with their source
+// code repository.
+func sampleResultWithBase() *diff.Result {
+	repo, _ := repository.New("https://github.com/docker-library/golang")
+	result := sampleResult()
+	result.ChangeLog = result.ChangeLog[3:]
+	result.Base = &diff.Result{
+		X:          "golang:1.13-alpine",
+		Y:          "golang:1.14-alpine",
+		Repository: repo,
+		XRevision:  "1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d",
+		YRevision:  "5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f80",
+		ChangeLog: []*diff.Change{
+			{
+				Revision: "5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f80",
+				Message:  "Update to 1.14\n",
+				Author:   diff.Signature{Name: "Jane Doe", Email: "jane@example.com"},
+				Parents:  []string{"1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d"},
+			},
+		},
+		Issues: []*issue.Issue{},
+		Base: &diff.Result{
+			X:         "alpine:3.11",
+			Y:         "alpine:3.12",
+			ChangeLog: []*diff.Change{},
+			Issues:    []*issue.Issue{},
+			Packages: []*packages.Change{
+				{Name: "musl", Manager: packages.Apk, Change: packages.Changed, X: "1.1.24-r2", Y: "1.1.24-r8"},
+			},
+		},
+	}
+	return result
+}
+
+func TestBaseMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, render.Markdown(&buf, sampleResultWithBase()))
+	assert.Contains(t, buf.String(), "Fix typo\n"+
+		"\n"+
+		"#### Base Image Changes between `golang:1.13-alpine` and `golang:1.14-alpine`\n"+
+		"\n"+
+		"[docker-library/golang](https://github.com/docker-library/golang): [`1a2b3c4...5d6e7f8`](https://github.com/docker-library/golang/compare/1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d...5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f80)\n"+
+		"\n"+
+		"- [`5d6e7f8`](https://github.com/docker-library/golang/commit/5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f80) Update to 1.14\n"+
+		"\n"+
+		"#### Base Image Changes between `alpine:3.11` and `alpine:3.12`\n"+
+		"\n"+
+		"No source code repository found.\n"+
+		"\n"+
+		"#### Packages\n"+
+		"\n"+
+		"- `musl`: changed from `1.1.24-r2` to `1.1.24-r8`\n")
+	assert.NotContains(t, buf.String(), "\n\n\n")
+}
+
+func TestBaseText(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, render.Text(&buf, sampleResultWithBase()))
+	assert.Equal(t, "aa0ff4c Fix typo\n"+
+		"\n"+
+		"Base image changes between golang:1.13-alpine and golang:1.14-alpine:\n"+
+		"    5d6e7f8 Update to 1.14\n"+
+		"\n"+
+		"Base image changes between alpine:3.11 and alpine:3.12:\n"+
+		"    No source code repository found.\n"+
+		"\n"+
+		"Packages:\n"+
+		"    musl: 1.1.24-r2 → 1.1.24-r8\n", buf.String())
+}
+
+func TestBaseHTML(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, render.HTML(&buf, sampleResultWithBase()))
+	assert.Contains(t, buf.String(), "<h4>Base image changes between <code>golang:1.13-alpine</code> and <code>golang:1.14-alpine</code></h4>\n"+
+		"<p>\n"+
+		"<a href=\"https://github.com/docker-library/golang\">docker-library/golang</a>:\n"+
+		"<a href=\"https://github.com/docker-library/golang/compare/1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d...5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f80\"><code>1a2b3c4...5d6e7f8</code></a>\n"+
+		"</p>\n"+
+		"<ul>\n"+
+		"<li><a class=\"revision\" href=\"https://github.com/docker-library/golang/commit/5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f80\">5d6e7f8</a> Update to 1.14</li>\n"+
+		"</ul>\n"+
+		"<h4>Base image changes between <code>alpine:3.11</code> and <code>alpine:3.12</code></h4>\n"+
+		"<p>No source code repository found.</p>\n"+
+		"<h4>Packages</h4>\n"+
+		"<ul>\n"+
+		"<li><code>musl</code>: changed from <code>1.1.24-r2</code> to <code>1.1.24-r8</code></li>\n"+
+		"</ul>\n"+
+		"</body>")
+}
@@ -0,0 +1,26 @@
+package render
+
+// configMarkdown lists the changes to the images' configuration, if diffed
+// with these, at the end of Markdown templates, e.g.:
+//
+//	#### Configuration Changes
+//
+//	- `Env PATH`: changed from `/usr/bin` to `/app/bin:/usr/bin`
+//	- `ExposedPorts 9090/tcp`: added
+const configMarkdown = `{{with .Config -}}
+#### Configuration Changes
+
+{{range . -}}
+- {{code .Name}}: {{.Change}}{{if eq .Change "changed"}} from {{code .X}} to {{code .Y}}{{else}}{{with .X}} {{code .}}{{end}}{{with .Y}} {{code .}}{{end}}{{end}}
+{{end -}}
+{{end -}}
+`
+
+// configText lists the changes to the images' configuration, if diffed with these, at the end of plain text templates.
+const configText = `{{with .Config -}}
+Configuration changes:
+{{range . -}}
+{{"    "}}{{.}}
+{{end -}}
+{{end -}}
+`
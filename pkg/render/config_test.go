@@ -0,0 +1,96 @@
+package render_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/render"
+)
+
+func sampleResultWithConfig() *diff.Result {
+	result := sampleResult()
+	result.Config = []*diff.ConfigChange{
+		{Field: "Env", Key: "PATH", Change: diff.ConfigChanged, X: "/usr/bin", Y: "/app/bin:/usr/bin"},
+		{Field: "Cmd", Change: diff.ConfigAdded, Y: "[\"echo\", \"`date`\"]"},
+		{Field: "ExposedPorts", Key: "8080/tcp", Change: diff.ConfigRemoved},
+	}
+	return result
+}
+
+func TestConfigMarkdown(t *testing.T) {
+	for name, fn := range map[string]func(*bytes.Buffer, *diff.Result) error{
+		"markdown":     func(buf *bytes.Buffer, result *diff.Result) error { return render.Markdown(buf, result) },
+		"conventional": func(buf *bytes.Buffer, result *diff.Result) error { return render.Conventional(buf, result) },
+		"pullrequests": func(buf *bytes.Buffer, result *diff.Result) error { return render.PullRequestsMarkdown(buf, result) },
+	} {
+		var buf bytes.Buffer
+		assert.NoError(t, fn(&buf, sampleResultWithConfig()), name)
+		assert.Contains(t, buf.String(), "\n\n#### Configuration Changes\n"+
+			"\n"+
+			"- `Env PATH`: changed from `/usr/bin` to `/app/bin:/usr/bin`\n"+
+			"- `Cmd`: added `` [\"echo\", \"`date`\"] ``\n"+
+			"- `ExposedPorts 8080/tcp`: removed\n", name)
+		assert.NotContains(t, buf.String(), "\n\n\n", name)
+
+		buf.Reset()
+		assert.NoError(t, fn(&buf, sampleResult()), name)
+		assert.NotContains(t, buf.String(), "Configuration Changes", name)
+	}
+}
+
+func TestConfigText(t *testing.T) {
+	expected := "\n\nConfiguration changes:\n" +
+		"    Env PATH: changed from \"/usr/bin\" to \"/app/bin:/usr/bin\"\n" +
+		"    Cmd: added \"[\\\"echo\\\", \\\"`date`\\\"]\"\n" +
+		"    ExposedPorts 8080/tcp: removed\n"
+	for name, fn := range map[string]func(*bytes.Buffer, *diff.Result) error{
+		"text":         func(buf *bytes.Buffer, result *diff.Result) error { return render.Text(buf, result) },
+		"nested":       func(buf *bytes.Buffer, result *diff.Result) error { return render.NestedText(buf, result) },
+		"pullrequests": func(buf *bytes.Buffer, result *diff.Result) error { return render.PullRequestsText(buf, result) },
+	} {
+		var buf bytes.Buffer
+		assert.NoError(t, fn(&buf, sampleResultWithConfig()), name)
+		assert.Contains(t, buf.String(), expected, name)
+		assert.NotContains(t, buf.String(), "\n\n\n", name)
+	}
+
+	// Without a trailing newline in the last message, nor any change:
+	result := sampleResultWithConfig()
+	result.ChangeLog[len(result.ChangeLog)-1].Message = "Fix typo"
+	var buf bytes.Buffer
+	assert.NoError(t, render.Text(&buf, result))
+	assert.Contains(t, buf.String(), "aa0ff4c Fix typo\n\nConfiguration changes:\n")
+	result.ChangeLog = []*diff.Change{}
+	buf.Reset()
+	assert.NoError(t, render.Text(&buf, result))
+	assert.True(t, strings.HasPrefix(buf.String(), "Configuration changes:\n"))
+}
+
+func TestConfigHTML(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, render.HTML(&buf, sampleResultWithConfig()))
+	assert.Contains(t, buf.String(), "<h4>Configuration changes</h4>\n<ul>\n"+
+		"<li><code>Env PATH</code>: changed from <code>/usr/bin</code> to <code>/app/bin:/usr/bin</code></li>\n"+
+		"<li><code>Cmd</code>: added <code>[&#34;echo&#34;, &#34;`date`&#34;]</code></li>\n"+
+		"<li><code>ExposedPorts 8080/tcp</code>: removed</li>\n"+
+		"</ul>\n</body>")
+}
+
+func TestConfigEmptyValues(t *testing.T) {
+	result := sampleResult()
+	result.Config = []*diff.ConfigChange{
+		{Field: "Env", Key: "DEBUG", Change: diff.ConfigChanged, X: "1", Y: ""},
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, render.Markdown(&buf, result))
+	assert.Contains(t, buf.String(), "- `Env DEBUG`: changed from `1` to `\"\"`\n")
+	buf.Reset()
+	assert.NoError(t, render.Text(&buf, result))
+	assert.Contains(t, buf.String(), "    Env DEBUG: changed from \"1\" to \"\"\n")
+	buf.Reset()
+	assert.NoError(t, render.HTML(&buf, result))
+	assert.Contains(t, buf.String(), "<li><code>Env DEBUG</code>: changed from <code>1</code> to <code>&#34;&#34;</code></li>\n")
+}
@@ -0,0 +1,31 @@
+package render
+
+import (
+	"io"
+	"text/template"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/conventional"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+)
+
+var conventionalTemplate = template.Must(template.New("conventional").Funcs(markdownFuncs).Parse(markdownHeader + `{{range $section := .Sections -}}
+#### {{$section.Title}}
+
+{{range .Entries -}}
+- {{if .Commit}}{{with .Commit.Scope}}**{{escape .}}:** {{end}}{{escape .Commit.Description}}{{else}}{{escape (firstLine .Message)}}{{end}} ([` + "`{{shortHash .Revision}}`" + `]({{$.Repository.CommitURL .Revision}}))
+{{if and (eq $section.Title "` + conventional.BreakingChanges + `") .Commit.BreakingChange}}{{"  "}}- {{escape .Commit.BreakingChange}}
+{{end -}}
+{{end}}
+{{else -}}
+No changes.
+{{if extras .Result}}
+{{end -}}
+{{end -}}
+` + extrasMarkdown))
+
+// Conventional renders the provided diff result as Markdown, with changes
+// grouped by type according to the Conventional Commits specification, e.g.
+// "Features", "Fixes", and first and foremost, "Breaking Changes".
+func Conventional(w io.Writer, result *diff.Result) error {
+	return conventionalTemplate.Execute(w, NewData(result))
+}
@@ -0,0 +1,45 @@
+package render_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/render"
+)
+
+func TestConventional(t *testing.T) {
+	result := sampleResult()
+	result.ChangeLog[0].Message = "Merge pull request #40 from microscaling/k8s-labels\n\nfeat(k8s): get labels from deployments\n"
+	result.ChangeLog[1].Message = "chore: bump version\n"
+	result.ChangeLog[2].Message = "refactor(k8s)!: use a separate KubeLabelConfig type\n\nBREAKING CHANGE: labels are now read from the deployment.\n"
+	var buf bytes.Buffer
+	err := render.Conventional(&buf, result)
+	assert.NoError(t, err)
+	assert.Equal(t, "### Changes between `microscaling/microscaling:0.9.0` and `microscaling/microscaling:0.9.1`\n"+
+		"\n"+
+		"[microscaling/microscaling](https://github.com/microscaling/microscaling): [`4756fd6...45b22cb`](https://github.com/microscaling/microscaling/compare/4756fd6e3d1b8a3d9b7e2a3f5c6d7e8f9a0b1c2d...45b22cbb0f6b8a5c4e4a8e6d2bbd0b9cde0a1f23)\n"+
+		"\n"+
+		"#### Breaking Changes\n"+
+		"\n"+
+		"- **k8s:** use a separate KubeLabelConfig type ([`309eece`](https://github.com/microscaling/microscaling/commit/309eece4d1a2b3c4d5e6f708192a3b4c5d6e7f80))\n"+
+		"  - labels are now read from the deployment.\n"+
+		"\n"+
+		"#### Features\n"+
+		"\n"+
+		"- **k8s:** get labels from deployments ([`45b22cb`](https://github.com/microscaling/microscaling/commit/45b22cbb0f6b8a5c4e4a8e6d2bbd0b9cde0a1f23))\n"+
+		"\n"+
+		"#### Refactoring\n"+
+		"\n"+
+		"- **k8s:** use a separate KubeLabelConfig type ([`309eece`](https://github.com/microscaling/microscaling/commit/309eece4d1a2b3c4d5e6f708192a3b4c5d6e7f80))\n"+
+		"\n"+
+		"#### Chores\n"+
+		"\n"+
+		"- bump version ([`91740fb`](https://github.com/microscaling/microscaling/commit/91740fb2c2c5b2a0fd1c8f2d7e4f0a3b6c9d8e7f))\n"+
+		"\n"+
+		"#### Other Changes\n"+
+		"\n"+
+		"- Fix typo ([`aa0ff4c`](https://github.com/microscaling/microscaling/commit/aa0ff4c1b2c3d4e5f60718293a4b5c6d7e8f9012))\n"+
+		"\n",
+		buf.String())
+}
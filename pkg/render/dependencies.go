@@ -0,0 +1,59 @@
+package render
+
+import "github.com/weaveworks-experiments/imagediff/pkg/dependencies"
+
+// dependenciesMarkdown lists the changes to the source code's dependencies,
+// if diffed with these, by manifest, at the end of Markdown templates, e.g.:
+//
+//	#### Dependency Changes
+//
+//	`go.mod`:
+//
+//	- `github.com/pkg/errors`: upgraded from `v0.8.1` to `v0.9.1` ([compare](https://github.com/pkg/errors/compare/v0.8.1...v0.9.1))
+//	- `gopkg.in/yaml.v2`: added `v2.3.0`
+const dependenciesMarkdown = `{{with .Dependencies -}}
+#### Dependency Changes
+{{range (byManifest .)}}
+{{code .Path}}:
+
+{{range .Changes -}}
+- {{code .Name}}: {{.Change}}{{if and .X .Y}} from {{code .X}} to {{code .Y}}{{else}}{{with .X}} {{code .}}{{end}}{{with .Y}} {{code .}}{{end}}{{end}}{{with .CompareURL}} ([compare]({{.}})){{end}}
+{{end -}}
+{{end -}}
+{{end -}}
+`
+
+// dependenciesText lists the changes to the source code's dependencies, if diffed with these, by manifest, at the end of plain text templates.
+const dependenciesText = `{{with .Dependencies -}}
+Dependency changes:
+{{range (byManifest .) -}}
+{{"    "}}{{.Path}}:
+{{range .Changes -}}
+{{"        "}}{{.}}
+{{end -}}
+{{end -}}
+{{end -}}
+`
+
+var dependenciesFuncs = map[string]interface{}{
+	"byManifest": byManifest,
+}
+
+// manifestChanges are the changes to the dependencies listed by a manifest.
+type manifestChanges struct {
+	Path    string
+	Changes []*dependencies.Change
+}
+
+// byManifest groups the provided changes by manifest, assuming these are ordered by manifest.
+func byManifest(changes []*dependencies.Change) []*manifestChanges {
+	manifests := []*manifestChanges{}
+	for _, change := range changes {
+		if len(manifests) == 0 || manifests[len(manifests)-1].Path != change.Path {
+			manifests = append(manifests, &manifestChanges{Path: change.Path})
+		}
+		last := manifests[len(manifests)-1]
+		last.Changes = append(last.Changes, change)
+	}
+	return manifests
+}
@@ -0,0 +1,72 @@
+package render_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/dependencies"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/render"
+)
+
+func sampleResultWithDependencies() *diff.Result {
+	result := sampleResultWithConfig()
+	result.Dependencies = []*dependencies.Change{
+		{Path: "go.mod", Ecosystem: dependencies.Go, Name: "github.com/pkg/errors", Change: dependencies.Upgraded, X: "v0.8.1", Y: "v0.9.1", CompareURL: "https://github.com/pkg/errors/compare/v0.8.1...v0.9.1"},
+		{Path: "go.mod", Ecosystem: dependencies.Go, Name: "gopkg.in/yaml.v2", Change: dependencies.Added, Y: "v2.3.0"},
+		{Path: "tools/go.mod", Ecosystem: dependencies.Go, Name: "golang.org/x/tools", Change: dependencies.Removed, X: "v0.0.1"},
+	}
+	return result
+}
+
+func TestDependenciesMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, render.Markdown(&buf, sampleResultWithDependencies()))
+	assert.Contains(t, buf.String(), "Fix typo\n"+
+		"\n"+
+		"#### Dependency Changes\n"+
+		"\n"+
+		"`go.mod`:\n"+
+		"\n"+
+		"- `github.com/pkg/errors`: upgraded from `v0.8.1` to `v0.9.1` ([compare](https://github.com/pkg/errors/compare/v0.8.1...v0.9.1))\n"+
+		"- `gopkg.in/yaml.v2`: added `v2.3.0`\n"+
+		"\n"+
+		"`tools/go.mod`:\n"+
+		"\n"+
+		"- `golang.org/x/tools`: removed `v0.0.1`\n"+
+		"\n"+
+		"#### Configuration Changes\n")
+	assert.NotContains(t, buf.String(), "\n\n\n")
+}
+
+func TestDependenciesText(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, render.Text(&buf, sampleResultWithDependencies()))
+	assert.Contains(t, buf.String(), "aa0ff4c Fix typo\n"+
+		"\n"+
+		"Dependency changes:\n"+
+		"    go.mod:\n"+
+		"        github.com/pkg/errors: v0.8.1 → v0.9.1\n"+
+		"        gopkg.in/yaml.v2: added v2.3.0\n"+
+		"    tools/go.mod:\n"+
+		"        golang.org/x/tools: removed v0.0.1\n"+
+		"\n"+
+		"Configuration changes:\n")
+}
+
+func TestDependenciesHTML(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, render.HTML(&buf, sampleResultWithDependencies()))
+	assert.Contains(t, buf.String(), "<h4>Dependency changes</h4>\n"+
+		"<p><code>go.mod</code>:</p>\n"+
+		"<ul>\n"+
+		"<li><code>github.com/pkg/errors</code>: upgraded from <code>v0.8.1</code> to <code>v0.9.1</code> (<a href=\"https://github.com/pkg/errors/compare/v0.8.1...v0.9.1\">compare</a>)</li>\n"+
+		"<li><code>gopkg.in/yaml.v2</code>: added <code>v2.3.0</code></li>\n"+
+		"</ul>\n"+
+		"<p><code>tools/go.mod</code>:</p>\n"+
+		"<ul>\n"+
+		"<li><code>golang.org/x/tools</code>: removed <code>v0.0.1</code></li>\n"+
+		"</ul>\n"+
+		"<h4>Configuration changes</h4>\n")
+}
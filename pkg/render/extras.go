@@ -0,0 +1,92 @@
+package render
+
+import (
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+)
+
+// extrasMarkdown lists what was diffed on top of the changes, if anything,
+// e.g. the source code's submodules and dependencies, the images'
+// configuration, layers, packages and base images, at the end of Markdown
+// templates, each in its own section. Sections are defined as templates, for
+// base images' to list their own.
+const extrasMarkdown = `{{template "extras-markdown" .}}
+{{- define "extras-markdown"}}` + submodulesMarkdown + `{{if and .Dependencies .Submodules}}
+{{end -}}
+` + dependenciesMarkdown + `{{if and .Config (or .Submodules .Dependencies)}}
+{{end -}}
+` + configMarkdown + `{{if and .Layers (or .Submodules .Dependencies .Config)}}
+{{end -}}
+` + layersMarkdown + `{{if and .Packages (or .Submodules .Dependencies .Config .Layers)}}
+{{end -}}
+` + packagesMarkdown + `{{if and .Base (or .Submodules .Dependencies .Config .Layers .Packages)}}
+{{end -}}
+{{with .Base}}{{template "base-markdown" .}}{{end -}}
+{{end}}
+{{- define "base-markdown"}}` + baseMarkdown + `{{end}}`
+
+// extrasText lists what was diffed on top of the changes, if anything, at the end of plain text templates.
+const extrasText = `{{template "extras-text" .}}
+{{- define "extras-text"}}` + submodulesText + `{{if and .Dependencies .Submodules}}
+{{end -}}
+` + dependenciesText + `{{if and .Config (or .Submodules .Dependencies)}}
+{{end -}}
+` + configText + `{{if and .Layers (or .Submodules .Dependencies .Config)}}
+{{end -}}
+` + layersText + `{{if and .Packages (or .Submodules .Dependencies .Config .Layers)}}
+{{end -}}
+` + packagesText + `{{if and .Base (or .Submodules .Dependencies .Config .Layers .Packages)}}
+{{end -}}
+{{with .Base}}{{template "base-text" .}}{{end -}}
+{{end}}
+{{- define "base-text"}}` + baseText + `{{end}}`
+
+var extrasTextTemplate = template.Must(template.New("text-extras").Funcs(markdownFuncs).Parse(extrasText))
+
+// extras tells whether anything was diffed on top of the changes.
+func extras(result *diff.Result) bool {
+	return len(result.Submodules) > 0 || len(result.Dependencies) > 0 || len(result.Config) > 0 || result.Layers != nil || len(result.Packages) > 0 || result.Base != nil
+}
+
+// textExtras lists what was diffed on top of the changes, if anything, after
+// the changes, last being the last change written, if any.
+func textExtras(w io.Writer, result *diff.Result, last *diff.Change) error {
+	if !extras(result) {
+		return nil
+	}
+	// Messages ending with a newline, as Git's do, are already followed by a blank line:
+	if last != nil && !strings.HasSuffix(last.Message, "\n") {
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return extrasTextTemplate.Execute(w, result)
+}
+
+// code formats the provided text as a Markdown code span, which, unlike the
+// rest of Markdown, cannot be escaped, hence delimiting it with more backticks
+// than it contains in a row, when it does. Empty text is rendered as "", as
+// an empty code span would show as stray backticks.
+func code(text string) string {
+	delimiter := "`"
+	text = value(text)
+	for strings.Contains(text, delimiter) {
+		delimiter += "`"
+	}
+	if delimiter != "`" || strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		return delimiter + " " + text + " " + delimiter
+	}
+	return delimiter + text + delimiter
+}
+
+// value shows the provided value, or "" if empty, e.g. for environment
+// variables set to the empty string, which would otherwise not show at all.
+func value(text string) string {
+	if text == "" {
+		return `""`
+	}
+	return text
+}
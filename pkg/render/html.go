@@ -0,0 +1,174 @@
+package render
+
+import (
+	"html/template"
+	"io"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+)
+
+var htmlTemplate = template.Must(template.New("html").Funcs(funcs(template.FuncMap{
+	"shortHash": ShortHash,
+	"firstLine": FirstLine,
+	"groups":    diff.GroupByMerge,
+	"value":     value,
+}, layersFuncs, dependenciesFuncs)).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Changes between {{.X}} and {{.Y}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #24292e; }
+code, .revision { font-family: SFMono-Regular, Consolas, Menlo, monospace; }
+a { color: #0366d6; text-decoration: none; }
+a:hover { text-decoration: underline; }
+ul { list-style: none; padding-left: 1em; }
+li { margin: 0.25em 0; }
+ul ul { border-left: 2px solid #e1e4e8; margin-left: 0.5em; }
+</style>
+</head>
+<body>
+<h3>Changes between <code>{{.X}}</code> and <code>{{.Y}}</code></h3>
+<p>
+<a href="{{.Repository.URL}}">{{.Repository.Organization}}/{{.Repository.Repository}}</a>:
+<a href="{{.Repository.CompareURL .XRevision .YRevision}}"><code>{{shortHash .XRevision}}...{{shortHash .YRevision}}</code></a>
+</p>
+{{if .Groups -}}
+<ul>
+{{range .Groups -}}
+<li><a class="revision" href="{{$.Repository.CommitURL .Change.Revision}}">{{shortHash .Change.Revision}}</a> {{firstLine .Change.Message}}
+{{- if .Merged}}
+<ul>
+{{range .Merged -}}
+<li><a class="revision" href="{{$.Repository.CommitURL .Revision}}">{{shortHash .Revision}}</a> {{firstLine .Message}}</li>
+{{end -}}
+</ul>
+{{end -}}
+</li>
+{{end -}}
+</ul>
+{{else -}}
+<p>No changes.</p>
+{{end -}}
+{{template "extras-html" .}}</body>
+</html>
+{{define "extras-html" -}}
+{{with .Submodules -}}
+<h4>Submodule changes</h4>
+{{range $submodule := . -}}
+{{with .Repository -}}
+<p><code>{{$submodule.Path}}</code>: <a href="{{.URL}}">{{.Organization}}/{{.Repository}}</a>:
+<a href="{{.CompareURL $submodule.XRevision $submodule.YRevision}}"><code>{{shortHash $submodule.XRevision}}...{{shortHash $submodule.YRevision}}</code></a></p>
+{{with groups $submodule.ChangeLog -}}
+<ul>
+{{range . -}}
+<li><a class="revision" href="{{$submodule.Repository.CommitURL .Change.Revision}}">{{shortHash .Change.Revision}}</a> {{firstLine .Change.Message}}
+{{- if .Merged}}
+<ul>
+{{range .Merged -}}
+<li><a class="revision" href="{{$submodule.Repository.CommitURL .Revision}}">{{shortHash .Revision}}</a> {{firstLine .Message}}</li>
+{{end -}}
+</ul>
+{{end -}}
+</li>
+{{end -}}
+</ul>
+{{else -}}
+<p>No changes.</p>
+{{end -}}
+{{else -}}
+<p><code>{{.Path}}</code>: <code>{{shortHash .XRevision}}...{{shortHash .YRevision}}</code></p>
+<p>Changes could not be listed.</p>
+{{end -}}
+{{end -}}
+{{end -}}
+{{with .Dependencies -}}
+<h4>Dependency changes</h4>
+{{range (byManifest .) -}}
+<p><code>{{.Path}}</code>:</p>
+<ul>
+{{range .Changes -}}
+<li><code>{{.Name}}</code>: {{.Change}}{{if and .X .Y}} from <code>{{value .X}}</code> to <code>{{value .Y}}</code>{{else}}{{with .X}} <code>{{.}}</code>{{end}}{{with .Y}} <code>{{.}}</code>{{end}}{{end}}{{with .CompareURL}} (<a href="{{.}}">compare</a>){{end}}</li>
+{{end -}}
+</ul>
+{{end -}}
+{{end -}}
+{{with .Config -}}
+<h4>Configuration changes</h4>
+<ul>
+{{range . -}}
+<li><code>{{.Name}}</code>: {{.Change}}{{if eq .Change "changed"}} from <code>{{value .X}}</code> to <code>{{value .Y}}</code>{{else}}{{with .X}} <code>{{.}}</code>{{end}}{{with .Y}} <code>{{.}}</code>{{end}}{{end}}</li>
+{{end -}}
+</ul>
+{{end -}}
+{{with .Layers -}}
+<h4>Layers</h4>
+<p>{{layerSummary .}}</p>
+{{if or .Added .Removed -}}
+<ul>
+{{range .Added -}}
+<li>added <code>{{shortDigest .Digest}}</code> {{humanSize .Size}}{{with .CreatedBy}} <code>{{truncate . 100}}</code>{{end}}</li>
+{{end -}}
+{{range .Removed -}}
+<li>removed <code>{{shortDigest .Digest}}</code> {{humanSize .Size}}{{with .CreatedBy}} <code>{{truncate . 100}}</code>{{end}}</li>
+{{end -}}
+</ul>
+{{end -}}
+{{with .Files -}}
+<h4>Files</h4>
+<ul>
+{{range (headFiles .) -}}
+<li>{{.Change}} <code>{{.Path}}</code> ({{fileSize .}})</li>
+{{end -}}
+{{with (moreFiles .)}}<li>…and {{.}} more files</li>
+{{end -}}
+</ul>
+{{end -}}
+{{end -}}
+{{with .Packages -}}
+<h4>Packages</h4>
+<ul>
+{{range . -}}
+<li><code>{{.Package}}</code>: {{.Change}}{{if eq .Change "changed"}} from <code>{{value .X}}</code> to <code>{{value .Y}}</code>{{else}}{{with .X}} <code>{{.}}</code>{{end}}{{with .Y}} <code>{{.}}</code>{{end}}{{end}}</li>
+{{end -}}
+</ul>
+{{end -}}
+{{with .Base}}{{template "base-html" .}}{{end -}}
+{{end}}
+{{- define "base-html" -}}
+<h4>Base image changes between <code>{{.X}}</code> and <code>{{.Y}}</code></h4>
+{{with .Repository -}}
+<p>
+<a href="{{.URL}}">{{.Organization}}/{{.Repository}}</a>:
+<a href="{{.CompareURL $.XRevision $.YRevision}}"><code>{{shortHash $.XRevision}}...{{shortHash $.YRevision}}</code></a>
+</p>
+{{with groups $.ChangeLog -}}
+<ul>
+{{range . -}}
+<li><a class="revision" href="{{$.Repository.CommitURL .Change.Revision}}">{{shortHash .Change.Revision}}</a> {{firstLine .Change.Message}}
+{{- if .Merged}}
+<ul>
+{{range .Merged -}}
+<li><a class="revision" href="{{$.Repository.CommitURL .Revision}}">{{shortHash .Revision}}</a> {{firstLine .Message}}</li>
+{{end -}}
+</ul>
+{{end -}}
+</li>
+{{end -}}
+</ul>
+{{else -}}
+<p>No changes.</p>
+{{end -}}
+{{else -}}
+<p>No source code repository found.</p>
+{{end -}}
+{{template "extras-html" .}}
+{{- end}}`))
+
+// HTML renders the provided diff result as a self-contained HTML page, i.e.
+// without any external stylesheet or script. Each change links to its page on
+// the source code repository's host, and merge commits are listed along with
+// the changes they merged in.
+func HTML(w io.Writer, result *diff.Result) error {
+	return htmlTemplate.Execute(w, NewData(result))
+}
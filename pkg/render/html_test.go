@@ -0,0 +1,29 @@
+package render_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/render"
+)
+
+func TestHTML(t *testing.T) {
+	result := sampleResult()
+	result.ChangeLog[3].Message = "Escape <script> tags\n"
+	var buf bytes.Buffer
+	err := render.HTML(&buf, result)
+	assert.NoError(t, err)
+	html := buf.String()
+	assert.Contains(t, html, "<!DOCTYPE html>")
+	assert.Contains(t, html, "<style>")
+	assert.NotContains(t, html, "<link")
+	assert.Contains(t, html, `<a href="https://github.com/microscaling/microscaling/compare/4756fd6e3d1b8a3d9b7e2a3f5c6d7e8f9a0b1c2d...45b22cbb0f6b8a5c4e4a8e6d2bbd0b9cde0a1f23"><code>4756fd6...45b22cb</code></a>`)
+	assert.Contains(t, html, `<li><a class="revision" href="https://github.com/microscaling/microscaling/commit/45b22cbb0f6b8a5c4e4a8e6d2bbd0b9cde0a1f23">45b22cb</a> Merge pull request #40 from microscaling/k8s-labels
+<ul>
+<li><a class="revision" href="https://github.com/microscaling/microscaling/commit/91740fb2c2c5b2a0fd1c8f2d7e4f0a3b6c9d8e7f">91740fb</a> Bump version</li>
+<li><a class="revision" href="https://github.com/microscaling/microscaling/commit/309eece4d1a2b3c4d5e6f708192a3b4c5d6e7f80">309eece</a> Use a separate _KubeLabelConfig_ type</li>
+</ul>
+</li>`)
+	assert.Contains(t, html, "Escape &lt;script&gt; tags</li>")
+}
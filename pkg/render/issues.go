@@ -0,0 +1,33 @@
+package render
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+)
+
+// IssuesText renders the issues referenced in the provided diff result as plain text, one issue per line.
+func IssuesText(w io.Writer, result *diff.Result) error {
+	for _, issue := range result.Issues {
+		if _, err := fmt.Fprintf(w, "%v %v\n", issue.Key, issue.URL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IssuesMarkdown renders the issues referenced in the provided diff result as a Markdown list.
+func IssuesMarkdown(w io.Writer, result *diff.Result) error {
+	for _, issue := range result.Issues {
+		if _, err := fmt.Fprintf(w, "- [%v](%v)\n", escapeMarkdown(issue.Key), issue.URL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IssuesJSON renders the issues referenced in the provided diff result as a JSON array.
+func IssuesJSON(w io.Writer, result *diff.Result) error {
+	return writeJSON(w, result.Issues)
+}
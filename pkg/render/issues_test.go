@@ -0,0 +1,37 @@
+package render_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/render"
+)
+
+func TestIssuesText(t *testing.T) {
+	var buf bytes.Buffer
+	err := render.IssuesText(&buf, sampleResult())
+	assert.NoError(t, err)
+	assert.Equal(t, `#12 https://github.com/microscaling/microscaling/issues/12
+OPS-7 https://jira.example.com/browse/OPS-7
+`, buf.String())
+}
+
+func TestIssuesMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	err := render.IssuesMarkdown(&buf, sampleResult())
+	assert.NoError(t, err)
+	assert.Equal(t, `- [#12](https://github.com/microscaling/microscaling/issues/12)
+- [OPS-7](https://jira.example.com/browse/OPS-7)
+`, buf.String())
+}
+
+func TestIssuesJSON(t *testing.T) {
+	var buf bytes.Buffer
+	err := render.IssuesJSON(&buf, sampleResult())
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"key": "#12", "url": "https://github.com/microscaling/microscaling/issues/12", "revisions": ["aa0ff4c1b2c3d4e5f60718293a4b5c6d7e8f9012"]},
+		{"key": "OPS-7", "url": "https://jira.example.com/browse/OPS-7", "revisions": ["309eece4d1a2b3c4d5e6f708192a3b4c5d6e7f80", "aa0ff4c1b2c3d4e5f60718293a4b5c6d7e8f9012"]}
+	]`, buf.String())
+}
@@ -0,0 +1,19 @@
+package render
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+)
+
+// JSON renders the provided diff result as JSON, e.g. for other tools to consume.
+func JSON(w io.Writer, result *diff.Result) error {
+	return writeJSON(w, result)
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
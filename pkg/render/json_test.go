@@ -0,0 +1,28 @@
+package render_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/render"
+)
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	err := render.JSON(&buf, sampleResult())
+	assert.NoError(t, err)
+
+	var result diff.Result
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &result))
+	assert.Equal(t, sampleResult(), &result)
+
+	var fields map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &fields))
+	assert.Equal(t, "microscaling/microscaling:0.9.0", fields["x"])
+	assert.Equal(t, map[string]interface{}{"host": "github.com", "organization": "microscaling", "repository": "microscaling"}, fields["repository"])
+	assert.Len(t, fields["changeLog"], 4)
+	assert.Len(t, fields["issues"], 2)
+}
@@ -0,0 +1,145 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	units "github.com/docker/go-units"
+	"github.com/weaveworks-experiments/imagediff/pkg/layers"
+)
+
+// maxFiles bounds how many changed files are listed, as new base images
+// easily add thousands. All are listed in JSON.
+const maxFiles = 100
+
+// layersMarkdown compares the images' layers, and lists the changed files, if
+// diffed with these, at the end of Markdown templates, e.g.:
+//
+//	#### Layers
+//
+//	3 shared layers (25.3MB), 1 added (12.1MB), 1 removed (10MB): 35.3MB → 37.4MB (+2.1MB)
+//
+//	- added `sha256:8a9e9863dbb6` 12.1MB `RUN apk add --no-cache ca-certificates`
+const layersMarkdown = `{{with .Layers -}}
+#### Layers
+
+{{layerSummary .}}
+{{if or .Added .Removed}}
+{{end -}}
+{{range .Added -}}
+- added {{code (shortDigest .Digest)}} {{humanSize .Size}}{{with .CreatedBy}} {{code (truncate . 100)}}{{end}}
+{{end -}}
+{{range .Removed -}}
+- removed {{code (shortDigest .Digest)}} {{humanSize .Size}}{{with .CreatedBy}} {{code (truncate . 100)}}{{end}}
+{{end -}}
+{{with .Files}}
+#### Files
+
+{{range (headFiles .) -}}
+- {{.Change}} {{code .Path}} ({{fileSize .}})
+{{end -}}
+{{with (moreFiles .)}}- …and {{.}} more files
+{{end -}}
+{{end -}}
+{{end -}}
+`
+
+// layersText compares the images' layers, and lists the changed files, if diffed with these, at the end of plain text templates.
+const layersText = `{{with .Layers -}}
+Layers: {{layerSummary .}}
+{{range .Added -}}
+{{"    "}}added {{shortDigest .Digest}} {{humanSize .Size}}{{with .CreatedBy}} {{truncate . 100}}{{end}}
+{{end -}}
+{{range .Removed -}}
+{{"    "}}removed {{shortDigest .Digest}} {{humanSize .Size}}{{with .CreatedBy}} {{truncate . 100}}{{end}}
+{{end -}}
+{{with .Files}}
+Files:
+{{range (headFiles .) -}}
+{{"    "}}{{.Change}} {{.Path}} ({{fileSize .}})
+{{end -}}
+{{with (moreFiles .)}}{{"    "}}…and {{.}} more files
+{{end -}}
+{{end -}}
+{{end -}}
+`
+
+var layersFuncs = map[string]interface{}{
+	"layerSummary": layerSummary,
+	"shortDigest":  shortDigest,
+	"humanSize":    humanSize,
+	"truncate":     truncate,
+	"headFiles":    headFiles,
+	"moreFiles":    moreFiles,
+	"fileSize":     fileSize,
+}
+
+// layerSummary summarises the provided comparison of layers, e.g.:
+//
+//	3 shared layers (25.3MB), 1 added (12.1MB), 1 removed (10MB): 35.3MB → 37.4MB (+2.1MB)
+func layerSummary(diff *layers.Diff) string {
+	delta := diff.YSize - diff.XSize
+	sign := "+"
+	if delta < 0 {
+		sign, delta = "-", -delta
+	}
+	shared := "layers"
+	if len(diff.Shared) == 1 {
+		shared = "layer"
+	}
+	return fmt.Sprintf("%v shared %v (%v), %v added (%v), %v removed (%v): %v → %v (%v%v)",
+		len(diff.Shared), shared, humanSize(diff.SharedSize()),
+		len(diff.Added), humanSize(diff.AddedSize()),
+		len(diff.Removed), humanSize(diff.RemovedSize()),
+		humanSize(diff.XSize), humanSize(diff.YSize), sign, humanSize(delta))
+}
+
+// shortDigest abbreviates the provided digest the same way Docker abbreviates IDs, e.g. "sha256:8a9e9863dbb6".
+func shortDigest(digest string) string {
+	algorithm, hex := "", digest
+	if i := strings.Index(digest, ":"); i != -1 {
+		algorithm, hex = digest[:i+1], digest[i+1:]
+	}
+	if len(hex) > 12 {
+		hex = hex[:12]
+	}
+	return algorithm + hex
+}
+
+func humanSize(size int64) string {
+	return units.HumanSize(float64(size))
+}
+
+// truncate shortens the provided text to at most max characters, ellipsis included.
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
+
+func headFiles(files []*layers.FileChange) []*layers.FileChange {
+	if len(files) > maxFiles {
+		return files[:maxFiles]
+	}
+	return files
+}
+
+func moreFiles(files []*layers.FileChange) int {
+	if len(files) > maxFiles {
+		return len(files) - maxFiles
+	}
+	return 0
+}
+
+func fileSize(file *layers.FileChange) string {
+	switch file.Change {
+	case layers.FileAdded:
+		return humanSize(file.YSize)
+	case layers.FileRemoved:
+		return humanSize(file.XSize)
+	default:
+		return humanSize(file.XSize) + " → " + humanSize(file.YSize)
+	}
+}
@@ -0,0 +1,85 @@
+package render_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/layers"
+	"github.com/weaveworks-experiments/imagediff/pkg/render"
+)
+
+func sampleResultWithLayers() *diff.Result {
+	result := sampleResult()
+	result.Layers = &layers.Diff{
+		Shared:  []*layers.Layer{{Digest: "sha256:8a9e9863dbb6e10edb5adfe917c00da84e1700fa76e7ed02476aa6e6fb8ee0d8", Size: 5000000, CreatedBy: "ADD file:1234 in /"}},
+		Added:   []*layers.Layer{{Digest: "sha256:fea8895f450959fa676bcc1df0611ea93823a735a01205fd8622846041d0c7cf", Size: 12000000, CreatedBy: "COPY app /app"}},
+		Removed: []*layers.Layer{{Digest: "sha256:320678d8ca14430f8bb64ca139cd83f7320678d8ca14430f8bb64ca139cd83f7", Size: 10000000, CreatedBy: "COPY app /app"}},
+		XSize:   15000000,
+		YSize:   17000000,
+		Files: []*layers.FileChange{
+			{Path: "/app/assets/logo.png", Change: layers.FileAdded, YSize: 2000000},
+			{Path: "/app/server", Change: layers.FileModified, XSize: 10000000, YSize: 10000000},
+		},
+	}
+	return result
+}
+
+func TestLayersMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, render.Markdown(&buf, sampleResultWithLayers()))
+	assert.Contains(t, buf.String(), "Fix typo\n"+
+		"\n"+
+		"#### Layers\n"+
+		"\n"+
+		"1 shared layer (5MB), 1 added (12MB), 1 removed (10MB): 15MB → 17MB (+2MB)\n"+
+		"\n"+
+		"- added `sha256:fea8895f4509` 12MB `COPY app /app`\n"+
+		"- removed `sha256:320678d8ca14` 10MB `COPY app /app`\n"+
+		"\n"+
+		"#### Files\n"+
+		"\n"+
+		"- added `/app/assets/logo.png` (2MB)\n"+
+		"- modified `/app/server` (10MB → 10MB)\n")
+	assert.NotContains(t, buf.String(), "\n\n\n")
+
+	// Along with configuration changes:
+	result := sampleResultWithLayers()
+	result.Config = sampleResultWithConfig().Config
+	result.Layers.Files = nil
+	buf.Reset()
+	assert.NoError(t, render.Markdown(&buf, result))
+	assert.Contains(t, buf.String(), "- `ExposedPorts 8080/tcp`: removed\n\n#### Layers\n")
+	assert.NotContains(t, buf.String(), "#### Files")
+	assert.NotContains(t, buf.String(), "\n\n\n")
+	assert.False(t, strings.HasSuffix(buf.String(), "\n\n"))
+}
+
+func TestLayersText(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, render.Text(&buf, sampleResultWithLayers()))
+	assert.Contains(t, buf.String(), "Fix typo\n"+
+		"\n"+
+		"Layers: 1 shared layer (5MB), 1 added (12MB), 1 removed (10MB): 15MB → 17MB (+2MB)\n"+
+		"    added sha256:fea8895f4509 12MB COPY app /app\n"+
+		"    removed sha256:320678d8ca14 10MB COPY app /app\n"+
+		"\n"+
+		"Files:\n"+
+		"    added /app/assets/logo.png (2MB)\n"+
+		"    modified /app/server (10MB → 10MB)\n")
+}
+
+func TestLayersTruncatesFiles(t *testing.T) {
+	result := sampleResultWithLayers()
+	result.Layers.Files = nil
+	for i := 0; i < 150; i++ {
+		result.Layers.Files = append(result.Layers.Files, &layers.FileChange{Path: fmt.Sprintf("/app/%03d", i), Change: layers.FileAdded, YSize: 1})
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, render.HTML(&buf, result))
+	assert.Contains(t, buf.String(), "<li>added <code>/app/099</code> (1B)</li>\n<li>…and 50 more files</li>\n</ul>\n</body>")
+	assert.NotContains(t, buf.String(), "/app/100")
+}
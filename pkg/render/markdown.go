@@ -0,0 +1,71 @@
+package render
+
+import (
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+)
+
+var markdownFuncs = funcs(template.FuncMap{
+	"shortHash": ShortHash,
+	"firstLine": FirstLine,
+	"escape":    escapeMarkdown,
+	"code":      code,
+	"extras":    extras,
+	"groups":    diff.GroupByMerge,
+}, layersFuncs, dependenciesFuncs)
+
+// funcs merges the provided functions.
+func funcs(maps ...map[string]interface{}) template.FuncMap {
+	merged := template.FuncMap{}
+	for _, m := range maps {
+		for name, fn := range m {
+			merged[name] = fn
+		}
+	}
+	return merged
+}
+
+const markdownHeader = "### Changes between `{{.X}}` and `{{.Y}}`" + `
+
+[{{.Repository.Organization}}/{{.Repository.Repository}}]({{.Repository.URL}}): ` +
+	"[`{{shortHash .XRevision}}...{{shortHash .YRevision}}`]({{.Repository.CompareURL .XRevision .YRevision}})" + `
+
+`
+
+var markdownTemplate = template.Must(template.New("markdown").Funcs(markdownFuncs).Parse(markdownHeader + `{{range .Groups -}}
+- [` + "`{{shortHash .Change.Revision}}`" + `]({{$.Repository.CommitURL .Change.Revision}}) {{escape (firstLine .Change.Message)}}
+{{range .Merged -}}
+{{"  "}}- [` + "`{{shortHash .Revision}}`" + `]({{$.Repository.CommitURL .Revision}}) {{escape (firstLine .Message)}}
+{{end -}}
+{{else -}}
+No changes.
+{{end -}}
+{{if extras .Result}}
+{{end -}}
+` + extrasMarkdown))
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	`*`, `\*`,
+	`_`, `\_`,
+	`[`, `\[`,
+	`]`, `\]`,
+	`<`, `\<`,
+	`>`, `\>`,
+)
+
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+// Markdown renders the provided diff result as Markdown, e.g. to be pasted in
+// pull requests' descriptions or release notes. Each change links to its page
+// on the source code repository's host, and merge commits are listed along
+// with the changes they merged in.
+func Markdown(w io.Writer, result *diff.Result) error {
+	return markdownTemplate.Execute(w, NewData(result))
+}
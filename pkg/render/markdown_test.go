@@ -0,0 +1,80 @@
+package render_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/issue"
+	"github.com/weaveworks-experiments/imagediff/pkg/render"
+	"github.com/weaveworks-experiments/imagediff/pkg/repository"
+)
+
+func TestMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	err := render.Markdown(&buf, sampleResult())
+	assert.NoError(t, err)
+	assert.Equal(t, "### Changes between `microscaling/microscaling:0.9.0` and `microscaling/microscaling:0.9.1`\n"+
+		"\n"+
+		"[microscaling/microscaling](https://github.com/microscaling/microscaling): [`4756fd6...45b22cb`](https://github.com/microscaling/microscaling/compare/4756fd6e3d1b8a3d9b7e2a3f5c6d7e8f9a0b1c2d...45b22cbb0f6b8a5c4e4a8e6d2bbd0b9cde0a1f23)\n"+
+		"\n"+
+		"- [`45b22cb`](https://github.com/microscaling/microscaling/commit/45b22cbb0f6b8a5c4e4a8e6d2bbd0b9cde0a1f23) Merge pull request #40 from microscaling/k8s-labels\n"+
+		"  - [`91740fb`](https://github.com/microscaling/microscaling/commit/91740fb2c2c5b2a0fd1c8f2d7e4f0a3b6c9d8e7f) Bump version\n"+
+		"  - [`309eece`](https://github.com/microscaling/microscaling/commit/309eece4d1a2b3c4d5e6f708192a3b4c5d6e7f80) Use a separate \\_KubeLabelConfig\\_ type\n"+
+		"- [`aa0ff4c`](https://github.com/microscaling/microscaling/commit/aa0ff4c1b2c3d4e5f60718293a4b5c6d7e8f9012) Fix typo\n",
+		buf.String())
+}
+
+func TestMarkdownWithoutChanges(t *testing.T) {
+	result := sampleResult()
+	result.ChangeLog = []*diff.Change{}
+	var buf bytes.Buffer
+	err := render.Markdown(&buf, result)
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "\nNo changes.\n")
+}
+
+func sampleResult() *diff.Result {
+	repo, _ := repository.New("https://github.com/microscaling/microscaling")
+	return &diff.Result{
+		X:          "microscaling/microscaling:0.9.0",
+		Y:          "microscaling/microscaling:0.9.1",
+		Repository: repo,
+		XRevision:  "4756fd6e3d1b8a3d9b7e2a3f5c6d7e8f9a0b1c2d",
+		YRevision:  "45b22cbb0f6b8a5c4e4a8e6d2bbd0b9cde0a1f23",
+		ChangeLog: []*diff.Change{
+			{
+				Revision: "45b22cbb0f6b8a5c4e4a8e6d2bbd0b9cde0a1f23",
+				Message:  "Merge pull request #40 from microscaling/k8s-labels\n\nKubernetes labels\n",
+				Author:   diff.Signature{Name: "Jane Doe", Email: "jane@example.com"},
+				Parents:  []string{"aa0ff4c1b2c3d4e5f60718293a4b5c6d7e8f9012", "91740fb2c2c5b2a0fd1c8f2d7e4f0a3b6c9d8e7f"},
+				Merge:    true,
+			},
+			{
+				Revision: "91740fb2c2c5b2a0fd1c8f2d7e4f0a3b6c9d8e7f",
+				Message:  "Bump version\n",
+				Author:   diff.Signature{Name: "Jane Doe", Email: "jane@example.com"},
+				Parents:  []string{"309eece4d1a2b3c4d5e6f708192a3b4c5d6e7f80"},
+				Files:    []*diff.FileStat{{Path: "VERSION", Added: 1, Deleted: 1}},
+			},
+			{
+				Revision: "309eece4d1a2b3c4d5e6f708192a3b4c5d6e7f80",
+				Message:  "Use a separate _KubeLabelConfig_ type\n",
+				Author:   diff.Signature{Name: "Jane Doe", Email: "jane@example.com"},
+				Files:    []*diff.FileStat{{Path: "utils/k8s.go", Added: 42, Deleted: 7}},
+				Parents:  []string{"4756fd6e3d1b8a3d9b7e2a3f5c6d7e8f9a0b1c2d"},
+			},
+			{
+				Revision: "aa0ff4c1b2c3d4e5f60718293a4b5c6d7e8f9012",
+				Message:  "Fix typo\n",
+				Author:   diff.Signature{Name: "John Doe", Email: "john@example.com"},
+				Parents:  []string{"4756fd6e3d1b8a3d9b7e2a3f5c6d7e8f9a0b1c2d"},
+			},
+		},
+		Issues: []*issue.Issue{
+			{Key: "#12", URL: "https://github.com/microscaling/microscaling/issues/12", Revisions: []string{"aa0ff4c1b2c3d4e5f60718293a4b5c6d7e8f9012"}},
+			{Key: "OPS-7", URL: "https://jira.example.com/browse/OPS-7", Revisions: []string{"309eece4d1a2b3c4d5e6f708192a3b4c5d6e7f80", "aa0ff4c1b2c3d4e5f60718293a4b5c6d7e8f9012"}},
+		},
+	}
+}
@@ -0,0 +1,26 @@
+package render
+
+// packagesMarkdown lists the changes to the images' OS packages, if diffed
+// with these, at the end of Markdown templates, e.g.:
+//
+//	#### Packages
+//
+//	- `ca-certificates`: added `20191127-r2`
+//	- `openssl`: changed from `1.1.1d-r3` to `1.1.1g-r0`
+const packagesMarkdown = `{{with .Packages -}}
+#### Packages
+
+{{range . -}}
+- {{code .Package}}: {{.Change}}{{if eq .Change "changed"}} from {{code .X}} to {{code .Y}}{{else}}{{with .X}} {{code .}}{{end}}{{with .Y}} {{code .}}{{end}}{{end}}
+{{end -}}
+{{end -}}
+`
+
+// packagesText lists the changes to the images' OS packages, if diffed with these, at the end of plain text templates.
+const packagesText = `{{with .Packages -}}
+Packages:
+{{range . -}}
+{{"    "}}{{.}}
+{{end -}}
+{{end -}}
+`
@@ -0,0 +1,61 @@
+package render_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/packages"
+	"github.com/weaveworks-experiments/imagediff/pkg/render"
+)
+
+func sampleResultWithPackages() *diff.Result {
+	result := sampleResultWithLayers()
+	result.Packages = []*packages.Change{
+		{Name: "ca-certificates", Manager: packages.Apk, Change: packages.Added, Y: "20191127-r2"},
+		{Name: "openssl", Manager: packages.Apk, Change: packages.Changed, X: "1.1.1d-r3", Y: "1.1.1g-r0"},
+		{Name: "wget", Manager: packages.Apk, Change: packages.Removed, X: "1.20.3-r0"},
+	}
+	return result
+}
+
+func TestPackagesMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, render.Markdown(&buf, sampleResultWithPackages()))
+	assert.Contains(t, buf.String(), "(10MB → 10MB)\n"+
+		"\n"+
+		"#### Packages\n"+
+		"\n"+
+		"- `ca-certificates`: added `20191127-r2`\n"+
+		"- `openssl`: changed from `1.1.1d-r3` to `1.1.1g-r0`\n"+
+		"- `wget`: removed `1.20.3-r0`\n")
+	assert.NotContains(t, buf.String(), "\n\n\n")
+
+	// Without layers:
+	result := sampleResultWithPackages()
+	result.Layers = nil
+	buf.Reset()
+	assert.NoError(t, render.Markdown(&buf, result))
+	assert.Contains(t, buf.String(), "Fix typo\n\n#### Packages\n")
+}
+
+func TestPackagesText(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, render.Text(&buf, sampleResultWithPackages()))
+	assert.Contains(t, buf.String(), "\n\nPackages:\n"+
+		"    ca-certificates: added 20191127-r2\n"+
+		"    openssl: 1.1.1d-r3 → 1.1.1g-r0\n"+
+		"    wget: removed 1.20.3-r0\n")
+	assert.NotContains(t, buf.String(), "\n\n\n")
+}
+
+func TestPackagesHTML(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, render.HTML(&buf, sampleResultWithPackages()))
+	assert.Contains(t, buf.String(), "<h4>Packages</h4>\n<ul>\n"+
+		"<li><code>ca-certificates</code>: added <code>20191127-r2</code></li>\n"+
+		"<li><code>openssl</code>: changed from <code>1.1.1d-r3</code> to <code>1.1.1g-r0</code></li>\n"+
+		"<li><code>wget</code>: removed <code>1.20.3-r0</code></li>\n"+
+		"</ul>\n</body>")
+}
@@ -0,0 +1,62 @@
+package render
+
+import (
+	"io"
+	"text/template"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+)
+
+var pullRequestsTextTemplate = template.Must(template.New("pullrequests-text").Funcs(markdownFuncs).Parse(`{{range .PullRequests -}}
+#{{.Number}} {{.Title}} ({{shortHash .Merge.Revision}})
+{{range .Changes -}}
+{{"    "}}{{shortHash .Revision}} {{firstLine .Message}}
+{{end -}}
+{{end -}}
+{{if .Others}}{{if .PullRequests}}
+{{end}}Other changes:
+{{range .Others -}}
+{{"    "}}{{shortHash .Revision}} {{firstLine .Message}}
+{{end -}}
+{{end -}}
+{{if and (extras .Result) (or .PullRequests .Others)}}
+{{end -}}
+` + extrasText))
+
+// PullRequestsText renders the provided diff result as plain text, listing
+// each pull request once, along with its commits, and then the changes which
+// are not part of any pull request.
+func PullRequestsText(w io.Writer, result *diff.Result) error {
+	return pullRequestsTextTemplate.Execute(w, NewData(result))
+}
+
+var pullRequestsMarkdownTemplate = template.Must(template.New("pullrequests-markdown").Funcs(markdownFuncs).Parse(markdownHeader + `{{if .PullRequests -}}
+#### Pull Requests
+
+{{range .PullRequests -}}
+- [#{{.Number}}]({{$.Repository.PullRequestURL .Number}}) {{escape .Title}}{{with .Author}} by @{{.}}{{end}}{{range .Labels}} ` + "`{{.}}`" + `{{end}} ([` + "`{{shortHash .Merge.Revision}}`" + `]({{$.Repository.CommitURL .Merge.Revision}}))
+{{range .Changes -}}
+{{"  "}}- [` + "`{{shortHash .Revision}}`" + `]({{$.Repository.CommitURL .Revision}}) {{escape (firstLine .Message)}}
+{{end -}}
+{{end}}
+{{end -}}
+{{if .Others -}}
+#### Other Changes
+
+{{range .Others -}}
+- [` + "`{{shortHash .Revision}}`" + `]({{$.Repository.CommitURL .Revision}}) {{escape (firstLine .Message)}}
+{{end}}
+{{end -}}
+{{if not (or .PullRequests .Others) -}}
+No changes.
+{{if extras .Result}}
+{{end -}}
+{{end -}}
+` + extrasMarkdown))
+
+// PullRequestsMarkdown renders the provided diff result as Markdown, listing
+// each pull request once, along with its commits, and then the changes which
+// are not part of any pull request.
+func PullRequestsMarkdown(w io.Writer, result *diff.Result) error {
+	return pullRequestsMarkdownTemplate.Execute(w, NewData(result))
+}
@@ -0,0 +1,38 @@
+package render_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/render"
+)
+
+func TestPullRequestsText(t *testing.T) {
+	var buf bytes.Buffer
+	err := render.PullRequestsText(&buf, sampleResult())
+	assert.NoError(t, err)
+	assert.Equal(t, `#40 Kubernetes labels (45b22cb)
+    91740fb Bump version
+    309eece Use a separate _KubeLabelConfig_ type
+
+Other changes:
+    aa0ff4c Fix typo
+`, buf.String())
+}
+
+func TestPullRequestsMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	err := render.PullRequestsMarkdown(&buf, sampleResult())
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "\n\n#### Pull Requests\n"+
+		"\n"+
+		"- [#40](https://github.com/microscaling/microscaling/pull/40) Kubernetes labels ([`45b22cb`](https://github.com/microscaling/microscaling/commit/45b22cbb0f6b8a5c4e4a8e6d2bbd0b9cde0a1f23))\n"+
+		"  - [`91740fb`](https://github.com/microscaling/microscaling/commit/91740fb2c2c5b2a0fd1c8f2d7e4f0a3b6c9d8e7f) Bump version\n"+
+		"  - [`309eece`](https://github.com/microscaling/microscaling/commit/309eece4d1a2b3c4d5e6f708192a3b4c5d6e7f80) Use a separate \\_KubeLabelConfig\\_ type\n"+
+		"\n"+
+		"#### Other Changes\n"+
+		"\n"+
+		"- [`aa0ff4c`](https://github.com/microscaling/microscaling/commit/aa0ff4c1b2c3d4e5f60718293a4b5c6d7e8f9012) Fix typo\n"+
+		"\n")
+}
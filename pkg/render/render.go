@@ -0,0 +1,135 @@
+package render
+
+import (
+	"strings"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/conventional"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/pullrequest"
+)
+
+// Data is the data model all templates, built-in or user-defined, get rendered with:
+//
+//	.X, .Y                  names of the two images, e.g. "microscaling/microscaling:0.9.0".
+//	.Repository             source code repository of both images, with .Host, .Organization,
+//	                        .Repository, and .URL, .CommitURL <revision>, .CompareURL <from> <to>.
+//	.XRevision, .YRevision  full hashes of the revisions the two images were built from.
+//	.ChangeLog              changes between these revisions, most recent first, each with
+//	                        .Revision, .Message, .Author and .Committer (with .Name, .Email and
+//	                        .When), .Parents, .Merge, .Files (with .Path, .Added and .Deleted)
+//	                        if diffed with stats, and .PullRequests (with .Number, .Title, .URL,
+//	                        .Author, .Labels, .Reviewers, and on GitLab, .Milestone and
+//	                        .PipelineStatus) if enriched.
+//	.Issues                 issues referenced by these changes, each with .Key, .URL, and
+//	                        .Revisions, the revisions of the changes referencing it.
+//	.Submodules             submodules bumped between these revisions, if diffed with these,
+//	                        each with .Path, .Repository, which may be nil, .XRevision,
+//	                        .YRevision, .ChangeLog and .Issues.
+//	.Dependencies           changes to the source code's dependencies, if diffed with these,
+//	                        each with .Path, the manifest's, .Ecosystem, e.g. go or npm,
+//	                        .Name, .Change, one of added, removed, upgraded, downgraded or
+//	                        changed, .X and .Y, the versions, if any, and .CompareURL, if any.
+//	.Config                 changes to the images' configuration, if diffed with these, each
+//	                        with .Field, e.g. Env, .Key, e.g. the variable's name, .Change, one
+//	                        of added, removed or changed, and .X and .Y, the values, if any.
+//	.Layers                 comparison of the images' layers, if diffed with these, with .Shared,
+//	                        .Added and .Removed, each with .Digest, .Size and .CreatedBy,
+//	                        .XSize and .YSize, and .Files, if diffed with these, each with
+//	                        .Path, .Change, one of added, removed or modified, .XSize and .YSize.
+//	.Packages               changes to the images' OS packages, if diffed with these, and if
+//	                        both images' could be read, each with .Name, .Arch, only set for
+//	                        packages installed for several architectures, .Package, the name
+//	                        qualified with .Arch, if set, e.g. libc6:i386, .Manager, one of
+//	                        dpkg, apk or rpm, .Change, one of added, removed or changed, and
+//	                        .X and .Y, the versions, if any.
+//	.Base                   the diff of the images' base images, if diffed with these, and if
+//	                        these changed, with .X, .Y, .Repository, which may be nil,
+//	                        .XRevision, .YRevision, .ChangeLog, .Issues, .Submodules,
+//	                        .Dependencies, .Config, .Layers, .Packages, and .Base,
+//	                        recursively.
+//	.Groups                 the above changes grouped by merge, each with .Change, and .Merged,
+//	                        the changes .Change merged in, if any.
+//	.Sections               the above changes grouped by Conventional Commits type, each with
+//	                        .Title, and .Entries, the changes along with their .Commit, parsed
+//	                        into .Type, .Scope, .Breaking, .Description, .Body and
+//	                        .BreakingChange, or nil if not following Conventional Commits.
+//	.Breaking               the above entries which are breaking changes.
+//	.PullRequests           the pull requests the above changes landed, each with .Number, .Title,
+//	                        .Author and .Labels if enriched, .Merge, the change which landed it,
+//	                        and .Changes, its changes.
+//	.Others                 the above changes which are not part of any pull request.
+//	.Stats                  .Stats.Changes, .Stats.Merges and .Stats.Authors, the numbers of
+//	                        changes, merges and distinct authors, and .Stats.Added and
+//	                        .Stats.Deleted, the numbers of lines added and deleted, if diffed
+//	                        with stats.
+type Data struct {
+	*diff.Result
+	Groups       []*diff.MergeGroup
+	Sections     []*conventional.Section
+	Breaking     []*conventional.Entry
+	PullRequests []*pullrequest.PullRequest
+	Others       []*diff.Change
+	Stats        Stats
+}
+
+// Stats summarises a change log.
+type Stats struct {
+	Changes int
+	Merges  int
+	Authors int
+	Added   int
+	Deleted int
+}
+
+// NewData creates the data model to render the provided diff result with.
+func NewData(result *diff.Result) *Data {
+	stats := Stats{Changes: len(result.ChangeLog)}
+	authors := map[string]bool{}
+	for _, change := range result.ChangeLog {
+		if change.Merge {
+			stats.Merges++
+		}
+		authors[change.Author.Email] = true
+		for _, file := range change.Files {
+			stats.Added += file.Added
+			stats.Deleted += file.Deleted
+		}
+	}
+	stats.Authors = len(authors)
+	pullRequests, others := pullrequest.Extract(result.ChangeLog)
+	return &Data{
+		Result:       result,
+		Groups:       diff.GroupByMerge(result.ChangeLog),
+		Sections:     conventional.Group(result.ChangeLog),
+		Breaking:     conventional.Breaking(result.ChangeLog),
+		PullRequests: pullRequests,
+		Others:       others,
+		Stats:        stats,
+	}
+}
+
+// ShortHash abbreviates the provided revision the same way Git does by default.
+func ShortHash(revision string) string {
+	if len(revision) > 7 {
+		return revision[:7]
+	}
+	return revision
+}
+
+// FirstLine extracts the first line, i.e. the subject, of the provided commit message.
+func FirstLine(message string) string {
+	message = strings.TrimSpace(message)
+	if idx := strings.Index(message, "\n"); idx != -1 {
+		return strings.TrimSpace(message[:idx])
+	}
+	return message
+}
+
+// Body extracts everything but the first line, i.e. the body, of the provided commit message.
+func Body(message string) string {
+	message = strings.TrimSpace(message)
+	if idx := strings.Index(message, "\n"); idx != -1 {
+		return strings.TrimSpace(message[idx+1:])
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+package render
+
+// submodulesMarkdown lists the changes between the revisions of the
+// submodules bumped between the source code's revisions, if diffed with
+// these, at the end of Markdown templates, e.g.:
+//
+//	#### Submodule Changes
+//
+//	`third_party/lib`: [weaveworks/lib](https://github.com/weaveworks/lib): [`1a2b3c4...5d6e7f8`](https://github.com/weaveworks/lib/compare/1a2b3c4...5d6e7f8)
+//
+//	- [`5d6e7f8`](https://github.com/weaveworks/lib/commit/5d6e7f8) Fix retries
+const submodulesMarkdown = `{{with .Submodules -}}
+#### Submodule Changes
+{{range $submodule := .}}
+{{code .Path}}: {{with .Repository}}[{{.Organization}}/{{.Repository}}]({{.URL}}): [` + "`{{shortHash $submodule.XRevision}}...{{shortHash $submodule.YRevision}}`" + `]({{.CompareURL $submodule.XRevision $submodule.YRevision}}){{else}}` + "`{{shortHash .XRevision}}...{{shortHash .YRevision}}`" + `{{end}}
+
+{{if .Repository -}}
+{{range groups .ChangeLog -}}
+- [` + "`{{shortHash .Change.Revision}}`" + `]({{$submodule.Repository.CommitURL .Change.Revision}}) {{escape (firstLine .Change.Message)}}
+{{range .Merged -}}
+{{"  "}}- [` + "`{{shortHash .Revision}}`" + `]({{$submodule.Repository.CommitURL .Revision}}) {{escape (firstLine .Message)}}
+{{end -}}
+{{else -}}
+No changes.
+{{end -}}
+{{else -}}
+Changes could not be listed.
+{{end -}}
+{{end -}}
+{{end -}}
+`
+
+// submodulesText lists the changes between the revisions of the submodules
+// bumped between the source code's revisions, if diffed with these, at the end
+// of plain text templates.
+const submodulesText = `{{with .Submodules -}}
+Submodule changes:
+{{range . -}}
+{{"    "}}{{.Path}} ({{shortHash .XRevision}}...{{shortHash .YRevision}}):
+{{range .ChangeLog -}}
+{{"        "}}{{shortHash .Revision}} {{firstLine .Message}}
+{{else -}}
+{{"        "}}{{if .Repository}}No changes.{{else}}Changes could not be listed.{{end}}
+{{end -}}
+{{end -}}
+{{end -}}
+`
@@ -0,0 +1,90 @@
+package render_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/issue"
+	"github.com/weaveworks-experiments/imagediff/pkg/render"
+	"github.com/weaveworks-experiments/imagediff/pkg/repository"
+)
+
+// sampleResultWithSubmodules bumps two submodules, the changes of the second
+// of which could not be listed.
+func sampleResultWithSubmodules() *diff.Result {
+	repo, _ := repository.New("https://github.com/weaveworks/lib")
+	result := sampleResultWithDependencies()
+	result.Submodules = []*diff.Submodule{
+		{
+			Path:       "third_party/lib",
+			Repository: repo,
+			XRevision:  "1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d",
+			YRevision:  "5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f80",
+			ChangeLog: []*diff.Change{
+				{
+					Revision: "5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f80",
+					Message:  "Fix retries\n",
+					Parents:  []string{"1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d"},
+				},
+			},
+			Issues: []*issue.Issue{},
+		},
+		{
+			Path:      "vendor/private",
+			XRevision: "0123456789abcdef0123456789abcdef01234567",
+			YRevision: "89abcdef0123456789abcdef0123456789abcdef",
+			ChangeLog: []*diff.Change{},
+			Issues:    []*issue.Issue{},
+		},
+	}
+	return result
+}
+
+func TestSubmodulesMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, render.Markdown(&buf, sampleResultWithSubmodules()))
+	assert.Contains(t, buf.String(), "Fix typo\n"+
+		"\n"+
+		"#### Submodule Changes\n"+
+		"\n"+
+		"`third_party/lib`: [weaveworks/lib](https://github.com/weaveworks/lib): [`1a2b3c4...5d6e7f8`](https://github.com/weaveworks/lib/compare/1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d...5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f80)\n"+
+		"\n"+
+		"- [`5d6e7f8`](https://github.com/weaveworks/lib/commit/5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f80) Fix retries\n"+
+		"\n"+
+		"`vendor/private`: `0123456...89abcde`\n"+
+		"\n"+
+		"Changes could not be listed.\n"+
+		"\n"+
+		"#### Dependency Changes\n")
+	assert.NotContains(t, buf.String(), "\n\n\n")
+}
+
+func TestSubmodulesText(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, render.Text(&buf, sampleResultWithSubmodules()))
+	assert.Contains(t, buf.String(), "aa0ff4c Fix typo\n"+
+		"\n"+
+		"Submodule changes:\n"+
+		"    third_party/lib (1a2b3c4...5d6e7f8):\n"+
+		"        5d6e7f8 Fix retries\n"+
+		"    vendor/private (0123456...89abcde):\n"+
+		"        Changes could not be listed.\n"+
+		"\n"+
+		"Dependency changes:\n")
+}
+
+func TestSubmodulesHTML(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, render.HTML(&buf, sampleResultWithSubmodules()))
+	assert.Contains(t, buf.String(), "<h4>Submodule changes</h4>\n"+
+		"<p><code>third_party/lib</code>: <a href=\"https://github.com/weaveworks/lib\">weaveworks/lib</a>:\n"+
+		"<a href=\"https://github.com/weaveworks/lib/compare/1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d...5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f80\"><code>1a2b3c4...5d6e7f8</code></a></p>\n"+
+		"<ul>\n"+
+		"<li><a class=\"revision\" href=\"https://github.com/weaveworks/lib/commit/5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f80\">5d6e7f8</a> Fix retries</li>\n"+
+		"</ul>\n"+
+		"<p><code>vendor/private</code>: <code>0123456...89abcde</code></p>\n"+
+		"<p>Changes could not be listed.</p>\n"+
+		"<h4>Dependency changes</h4>\n")
+}
@@ -0,0 +1,42 @@
+package render
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"text/template"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+)
+
+// Funcs are the helper functions available to user-defined templates:
+//
+//	shortHash <revision>  abbreviates the revision, e.g. "45b22cb".
+//	firstLine <message>   extracts the subject of the commit message.
+//	body <message>        extracts the body of the commit message.
+//	trimSpace <string>    removes leading and trailing white spaces.
+var Funcs = template.FuncMap{
+	"shortHash": ShortHash,
+	"firstLine": FirstLine,
+	"body":      Body,
+	"trimSpace": strings.TrimSpace,
+}
+
+// NewTemplate parses the provided text as a user-defined template, to be rendered with Data.
+func NewTemplate(text string) (*template.Template, error) {
+	return template.New("template").Funcs(Funcs).Parse(text)
+}
+
+// NewTemplateFromFile reads and parses the provided file as a user-defined template, to be rendered with Data.
+func NewTemplateFromFile(path string) (*template.Template, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewTemplate(string(bytes))
+}
+
+// Template renders the provided diff result with the provided user-defined template.
+func Template(w io.Writer, tmpl *template.Template, result *diff.Result) error {
+	return tmpl.Execute(w, NewData(result))
+}
@@ -0,0 +1,48 @@
+package render_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/render"
+)
+
+func TestTemplate(t *testing.T) {
+	tmpl, err := render.NewTemplate(`{{.Stats.Changes}} changes ({{.Stats.Merges}} merge, {{.Stats.Authors}} authors, +{{.Stats.Added}} -{{.Stats.Deleted}}) in {{.Repository.URL}} between {{shortHash .XRevision}} and {{shortHash .YRevision}}:
+{{range .ChangeLog}}* {{shortHash .Revision}} {{firstLine .Message}}{{with body .Message}} ({{trimSpace .}}){{end}} by {{.Author.Name}}
+{{end}}`)
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	err = render.Template(&buf, tmpl, sampleResult())
+	assert.NoError(t, err)
+	assert.Equal(t, `4 changes (1 merge, 2 authors, +43 -8) in https://github.com/microscaling/microscaling between 4756fd6 and 45b22cb:
+* 45b22cb Merge pull request #40 from microscaling/k8s-labels (Kubernetes labels) by Jane Doe
+* 91740fb Bump version by Jane Doe
+* 309eece Use a separate _KubeLabelConfig_ type by Jane Doe
+* aa0ff4c Fix typo by John Doe
+`, buf.String())
+}
+
+func TestTemplateFromFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`{{range .Groups}}{{.Change.Revision}}:{{len .Merged}} {{end}}`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	tmpl, err := render.NewTemplateFromFile(f.Name())
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	err = render.Template(&buf, tmpl, sampleResult())
+	assert.NoError(t, err)
+	assert.Equal(t, "45b22cbb0f6b8a5c4e4a8e6d2bbd0b9cde0a1f23:2 aa0ff4c1b2c3d4e5f60718293a4b5c6d7e8f9012:0 ", buf.String())
+}
+
+func TestInvalidTemplate(t *testing.T) {
+	_, err := render.NewTemplate(`{{unknownFunction .X}}`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,54 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+)
+
+// Text renders the provided diff result as plain text, one change after the
+// other, and then the changes to the source code's submodules and
+// dependencies, and to the images' configuration, layers, packages and base
+// images, if diffed with these.
+func Text(w io.Writer, result *diff.Result) error {
+	var last *diff.Change
+	for _, change := range result.ChangeLog {
+		if err := textChange(w, "", change); err != nil {
+			return err
+		}
+		last = change
+	}
+	return textExtras(w, result, last)
+}
+
+// NestedText renders the provided diff result as plain text, with the changes
+// merge commits brought in nested, i.e. indented, underneath these.
+func NestedText(w io.Writer, result *diff.Result) error {
+	var last *diff.Change
+	for _, group := range diff.GroupByMerge(result.ChangeLog) {
+		if err := textChange(w, "", group.Change); err != nil {
+			return err
+		}
+		last = group.Change
+		for _, change := range group.Merged {
+			if err := textChange(w, "    ", change); err != nil {
+				return err
+			}
+			last = change
+		}
+	}
+	return textExtras(w, result, last)
+}
+
+func textChange(w io.Writer, indent string, change *diff.Change) error {
+	lines := strings.Split(change.Message, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = indent + lines[i]
+		}
+	}
+	_, err := fmt.Fprintf(w, "%v%v %v\n", indent, ShortHash(change.Revision), strings.Join(lines, "\n"))
+	return err
+}
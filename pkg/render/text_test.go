@@ -0,0 +1,43 @@
+package render_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/render"
+)
+
+func TestText(t *testing.T) {
+	var buf bytes.Buffer
+	err := render.Text(&buf, sampleResult())
+	assert.NoError(t, err)
+	assert.Equal(t, `45b22cb Merge pull request #40 from microscaling/k8s-labels
+
+Kubernetes labels
+
+91740fb Bump version
+
+309eece Use a separate _KubeLabelConfig_ type
+
+aa0ff4c Fix typo
+
+`, buf.String())
+}
+
+func TestNestedText(t *testing.T) {
+	var buf bytes.Buffer
+	err := render.NestedText(&buf, sampleResult())
+	assert.NoError(t, err)
+	assert.Equal(t, `45b22cb Merge pull request #40 from microscaling/k8s-labels
+
+Kubernetes labels
+
+    91740fb Bump version
+
+    309eece Use a separate _KubeLabelConfig_ type
+
+aa0ff4c Fix typo
+
+`, buf.String())
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -18,9 +19,9 @@ import (
 
 // GitRepository encapsulates data and behavior about a Git repository.
 type GitRepository struct {
-	Host         string
-	Organization string
-	Repository   string
+	Host         string `json:"host"`
+	Organization string `json:"organization"`
+	Repository   string `json:"repository"`
 }
 
 // Options encapsulates the various options we can pass in to interact with a Git repository.
@@ -38,12 +39,75 @@ func (r GitRepository) SSH() string {
 	return fmt.Sprintf("git@%v:%v/%v.git", r.Host, r.Organization, r.Repository)
 }
 
-// Clone clones this repository in memory.
-func (r GitRepository) Clone(options *Options) (*git.Repository, error) {
+// Path of this repository on its host, e.g. "organization/repository", or "group/subgroup/project" on GitLab.
+func (r GitRepository) Path() string {
+	return fmt.Sprintf("%v/%v", r.Organization, r.Repository)
+}
+
+// URL of this repository's web page on its host.
+func (r GitRepository) URL() string {
+	return fmt.Sprintf("https://%v/%v/%v", r.Host, r.Organization, r.Repository)
+}
+
+// CommitURL is the URL of the web page of the provided revision on this repository's host.
+func (r GitRepository) CommitURL(revision string) string {
+	switch {
+	case r.IsGitLab():
+		return fmt.Sprintf("%v/-/commit/%v", r.URL(), revision)
+	case r.isBitbucket():
+		return fmt.Sprintf("%v/commits/%v", r.URL(), revision)
+	default:
+		return fmt.Sprintf("%v/commit/%v", r.URL(), revision)
+	}
+}
+
+// CompareURL is the URL of the web page comparing the two provided revisions on this repository's host.
+func (r GitRepository) CompareURL(from, to string) string {
+	switch {
+	case r.IsGitLab():
+		return fmt.Sprintf("%v/-/compare/%v...%v", r.URL(), from, to)
+	case r.isBitbucket():
+		return fmt.Sprintf("%v/branches/compare/%v%%0D%v", r.URL(), to, from)
+	default:
+		return fmt.Sprintf("%v/compare/%v...%v", r.URL(), from, to)
+	}
+}
+
+// PullRequestURL is the URL of the web page of the provided pull request (or merge request, on GitLab) on this repository's host.
+func (r GitRepository) PullRequestURL(number int) string {
+	switch {
+	case r.IsGitLab():
+		return fmt.Sprintf("%v/-/merge_requests/%v", r.URL(), number)
+	case r.isBitbucket():
+		return fmt.Sprintf("%v/pull-requests/%v", r.URL(), number)
+	default:
+		return fmt.Sprintf("%v/pull/%v", r.URL(), number)
+	}
+}
+
+// IssueURL is the URL of the web page of the provided issue on this repository's host.
+func (r GitRepository) IssueURL(id string) string {
+	if r.IsGitLab() {
+		return fmt.Sprintf("%v/-/issues/%v", r.URL(), id)
+	}
+	return fmt.Sprintf("%v/issues/%v", r.URL(), id)
+}
+
+// IsGitLab tells whether this repository is hosted on GitLab, judging by its host's name, e.g. gitlab.com.
+func (r GitRepository) IsGitLab() bool {
+	return strings.Contains(r.Host, "gitlab")
+}
+
+func (r GitRepository) isBitbucket() bool {
+	return strings.Contains(r.Host, "bitbucket")
+}
+
+// Clone clones this repository in memory, until the provided context is done.
+func (r GitRepository) Clone(ctx context.Context, options *Options) (*git.Repository, error) {
 	logger := log.WithField("repository", r)
 	logger.Info("cloning repository via HTTPS")
 	storage := memory.NewStorage()
-	repo, err := git.Clone(storage, nil, &git.CloneOptions{
+	repo, err := git.CloneContext(ctx, storage, nil, &git.CloneOptions{
 		URL: r.HTTPS(),
 	})
 	if err != nil {
@@ -53,7 +117,7 @@ func (r GitRepository) Clone(options *Options) (*git.Repository, error) {
 			if err != nil {
 				return nil, err
 			}
-			repo, err = git.Clone(memory.NewStorage(), nil, &git.CloneOptions{
+			repo, err = git.CloneContext(ctx, memory.NewStorage(), nil, &git.CloneOptions{
 				URL:  r.SSH(),
 				Auth: &git_ssh.PublicKeys{User: "git", Signer: sshKey},
 			})
@@ -67,6 +131,69 @@ func (r GitRepository) Clone(options *Options) (*git.Repository, error) {
 	return repo, nil
 }
 
+// Fetch fetches the branches and tags of this repository missing from the
+// provided clone of it, as made by Clone, until the provided context is done.
+// These are fetched into a copy of the clone, returned, so that the clone
+// itself can still be read concurrently, e.g. by other diffs. The clone is
+// returned as is if it is up to date.
+func (r GitRepository) Fetch(ctx context.Context, clone *git.Repository, options *Options) (*git.Repository, error) {
+	storage, ok := clone.Storer.(*memory.Storage)
+	if !ok {
+		return nil, fmt.Errorf("cannot fetch into clone of %v: not stored in memory", r)
+	}
+	remote, err := clone.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return nil, err
+	}
+	fetchOptions := &git.FetchOptions{}
+	// Clones made via SSH, as HTTPS required authentication, fetch via SSH too:
+	if urls := remote.Config().URLs; len(urls) > 0 && urls[0] == r.SSH() {
+		sshKey, err := getOrDefaultPrivateSSHKey(options)
+		if err != nil {
+			return nil, err
+		}
+		fetchOptions.Auth = &git_ssh.PublicKeys{User: "git", Signer: sshKey}
+	}
+	copied, err := copyStorage(storage)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := git.Open(copied, nil)
+	if err != nil {
+		return nil, err
+	}
+	log.WithField("repository", r).Info("fetching repository")
+	err = repo.FetchContext(ctx, fetchOptions)
+	if err == git.NoErrAlreadyUpToDate {
+		return clone, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// copyStorage shallowly copies the provided storage, i.e. shares its objects,
+// which are immutable, but not its indexes of these, or its references, which
+// fetching updates.
+func copyStorage(storage *memory.Storage) (*memory.Storage, error) {
+	copied := memory.NewStorage()
+	for _, object := range storage.Objects {
+		if _, err := copied.SetEncodedObject(object); err != nil {
+			return nil, err
+		}
+	}
+	for name, ref := range storage.ReferenceStorage {
+		copied.ReferenceStorage[name] = ref
+	}
+	copied.ShallowStorage = append(memory.ShallowStorage{}, storage.ShallowStorage...)
+	config, err := storage.Config()
+	if err != nil {
+		return nil, err
+	}
+	return copied, copied.SetConfig(config)
+}
+
 func getOrDefaultPrivateSSHKey(options *Options) (ssh.Signer, error) {
 	sshKey, err := getOrDefaultPrivateSSHKeyBytes(options)
 	if err != nil {
@@ -143,23 +270,47 @@ func New(url string) (*GitRepository, error) {
 	}, nil
 }
 
+var (
+	httpsRegex = regexp.MustCompile(`https://([^/]+)/(.+)`)
+	sshRegex   = regexp.MustCompile(`git@([^:]+):(.+)`)
+	// dotGitRegex and gitLabPathSeparator mark the end of a repository's path in a URL.
+	dotGitRegex = regexp.MustCompile(`\.git(?:/|$)`)
+)
+
+const gitLabPathSeparator = "/-/"
+
 func parseURL(url string) (string, string, string, error) {
-	httpsRegex, _ := regexp.Compile(`https://([^/]+)/([^/]+)/([^/]+).*?`)
 	matches := httpsRegex.FindStringSubmatch(url)
-	if len(matches) > 0 {
-		return matches[1], matches[2], withoutDotGit(matches[3]), nil
+	if len(matches) == 0 {
+		matches = sshRegex.FindStringSubmatch(url)
 	}
-	sshRegex, _ := regexp.Compile(`git@([^:]+):([^/]+)/([^/]+).*?`)
-	matches = sshRegex.FindStringSubmatch(url)
 	if len(matches) > 0 {
-		return matches[1], matches[2], withoutDotGit(matches[3]), nil
+		if org, repo, ok := splitPath(matches[1], matches[2]); ok {
+			return matches[1], org, repo, nil
+		}
 	}
 	return "", "", "", fmt.Errorf("failed to parse URL: [%v]", url)
 }
 
-func withoutDotGit(repo string) string {
-	if idx := strings.LastIndex(repo, ".git"); idx != -1 {
-		return repo[:idx]
+// splitPath splits the provided path into the repository's organization and
+// name, ignoring any trailing path, e.g. "/tree/master". GitLab projects may
+// be nested in subgroups, e.g. "group/subgroup/project", in which case the
+// organization is "group/subgroup": their path ends with ".git", "/-/", or,
+// on gitlab.com and alike, the URL itself.
+func splitPath(host, path string) (string, string, bool) {
+	path = strings.Trim(path, "/")
+	if idx := strings.Index(path, gitLabPathSeparator); idx != -1 {
+		path = path[:idx]
+	} else if loc := dotGitRegex.FindStringIndex(path); loc != nil {
+		path = path[:loc[0]]
+	} else if !strings.Contains(host, "gitlab") {
+		if segments := strings.SplitN(path, "/", 3); len(segments) >= 2 {
+			path = segments[0] + "/" + segments[1]
+		}
+	}
+	idx := strings.LastIndex(path, "/")
+	if idx <= 0 || idx == len(path)-1 {
+		return "", "", false
 	}
-	return repo
+	return path[:idx], path[idx+1:], true
 }
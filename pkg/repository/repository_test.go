@@ -1,10 +1,18 @@
 package repository_test
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
 	"testing"
+	"time"
 
+	"github.com/src-d/go-git/storage/memory"
 	"github.com/stretchr/testify/assert"
 	"github.com/weaveworks-experiments/imagediff/pkg/repository"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
 )
 
 func TestNewRepositoryFromSSH(t *testing.T) {
@@ -67,3 +75,91 @@ func TestNewRepositoryFromInvalidString(t *testing.T) {
 	assert.Error(t, err, "failed to parse [g0t r00t?]")
 	assert.Nil(t, r)
 }
+
+func TestWebURLsOnGitHub(t *testing.T) {
+	r, err := repository.New("https://github.com/microscaling/microscaling")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://github.com/microscaling/microscaling", r.URL())
+	assert.Equal(t, "https://github.com/microscaling/microscaling/commit/45b22cb", r.CommitURL("45b22cb"))
+	assert.Equal(t, "https://github.com/microscaling/microscaling/compare/4756fd6...45b22cb", r.CompareURL("4756fd6", "45b22cb"))
+	assert.Equal(t, "https://github.com/microscaling/microscaling/pull/40", r.PullRequestURL(40))
+	assert.Equal(t, "https://github.com/microscaling/microscaling/issues/12", r.IssueURL("12"))
+}
+
+func TestWebURLsOnGitLab(t *testing.T) {
+	r, err := repository.New("git@gitlab.com:bar/baz.git")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://gitlab.com/bar/baz", r.URL())
+	assert.Equal(t, "https://gitlab.com/bar/baz/-/commit/45b22cb", r.CommitURL("45b22cb"))
+	assert.Equal(t, "https://gitlab.com/bar/baz/-/compare/4756fd6...45b22cb", r.CompareURL("4756fd6", "45b22cb"))
+	assert.Equal(t, "https://gitlab.com/bar/baz/-/merge_requests/12", r.PullRequestURL(12))
+	assert.Equal(t, "https://gitlab.com/bar/baz/-/issues/12", r.IssueURL("12"))
+}
+
+func TestWebURLsOnBitbucket(t *testing.T) {
+	r, err := repository.New("https://bitbucket.org/bar/baz.git")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://bitbucket.org/bar/baz", r.URL())
+	assert.Equal(t, "https://bitbucket.org/bar/baz/commits/45b22cb", r.CommitURL("45b22cb"))
+	assert.Equal(t, "https://bitbucket.org/bar/baz/branches/compare/45b22cb%0D4756fd6", r.CompareURL("4756fd6", "45b22cb"))
+	assert.Equal(t, "https://bitbucket.org/bar/baz/pull-requests/7", r.PullRequestURL(7))
+	assert.Equal(t, "https://bitbucket.org/bar/baz/issues/7", r.IssueURL("7"))
+}
+
+func TestNewRepositoryInGitLabSubgroups(t *testing.T) {
+	for _, url := range []string{
+		"https://gitlab.com/foo/bar/baz",
+		"https://gitlab.com/foo/bar/baz.git",
+		"https://gitlab.com/foo/bar/baz/-/tree/master/path/to/some/dir",
+		"https://git.example.com/foo/bar/baz.git",
+		"git@git.example.com:foo/bar/baz.git",
+	} {
+		r, err := repository.New(url)
+		assert.NoError(t, err, url)
+		assert.Equal(t, "foo/bar", r.Organization, url)
+		assert.Equal(t, "baz", r.Repository, url)
+		assert.Equal(t, "foo/bar/baz", r.Path(), url)
+	}
+	r, err := repository.New("https://gitlab.com/foo/bar/baz")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://gitlab.com/foo/bar/baz.git", r.HTTPS())
+	assert.Equal(t, "git@gitlab.com:foo/bar/baz.git", r.SSH())
+	assert.Equal(t, "https://gitlab.com/foo/bar/baz/-/merge_requests/40", r.PullRequestURL(40))
+}
+
+func TestFetchIntoCopyOfClone(t *testing.T) {
+	dir, err := ioutil.TempDir("", "imagediff")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	origin, err := git.PlainInit(dir, false)
+	assert.NoError(t, err)
+	first := commit(t, origin, "Initial commit")
+	clone, err := git.Clone(memory.NewStorage(), nil, &git.CloneOptions{URL: dir})
+	assert.NoError(t, err)
+	second := commit(t, origin, "Fix retries")
+
+	r := repository.GitRepository{Host: "github.com", Organization: "weaveworks", Repository: "lib"}
+	fetched, err := r.Fetch(context.Background(), clone, nil)
+	assert.NoError(t, err)
+	_, err = fetched.CommitObject(second)
+	assert.NoError(t, err)
+	_, err = fetched.CommitObject(first)
+	assert.NoError(t, err)
+	// The clone itself is left untouched, for concurrent readers:
+	_, err = clone.CommitObject(second)
+	assert.Equal(t, plumbing.ErrObjectNotFound, err)
+
+	upToDate, err := r.Fetch(context.Background(), fetched, nil)
+	assert.NoError(t, err)
+	assert.True(t, upToDate == fetched)
+}
+
+func commit(t *testing.T, repo *git.Repository, message string) plumbing.Hash {
+	worktree, err := repo.Worktree()
+	assert.NoError(t, err)
+	hash, err := worktree.Commit(message, &git.CommitOptions{
+		Author: &object.Signature{Name: "Jane Doe", Email: "jane@example.com", When: time.Now()},
+	})
+	assert.NoError(t, err)
+	return hash
+}
@@ -0,0 +1,73 @@
+package semver
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version.
+type Version struct {
+	// Numbers are the major, minor and patch numbers.
+	Numbers    [3]int
+	Prerelease []string
+}
+
+// versionRegex matches semantic versions, optionally prefixed with "v", as
+// commonly used in tags, and optionally without their patch number, e.g.
+// "v1.2".
+var versionRegex = regexp.MustCompile(`^v?(\d+)\.(\d+)(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+
+// Parse parses the provided semantic version, e.g. a tag, or tells it is not one.
+func Parse(tag string) (*Version, bool) {
+	matches := versionRegex.FindStringSubmatch(tag)
+	if matches == nil {
+		return nil, false
+	}
+	v := &Version{}
+	for i := range v.Numbers {
+		if matches[i+1] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(matches[i+1])
+		if err != nil {
+			return nil, false
+		}
+		v.Numbers[i] = n
+	}
+	if matches[4] != "" {
+		v.Prerelease = strings.Split(matches[4], ".")
+	}
+	return v, true
+}
+
+// Less compares versions as per semver's precedence rules.
+func (v *Version) Less(other *Version) bool {
+	for i := range v.Numbers {
+		if v.Numbers[i] != other.Numbers[i] {
+			return v.Numbers[i] < other.Numbers[i]
+		}
+	}
+	// Pre-releases precede their release:
+	if len(v.Prerelease) == 0 || len(other.Prerelease) == 0 {
+		return len(v.Prerelease) > len(other.Prerelease)
+	}
+	for i := 0; i < len(v.Prerelease) && i < len(other.Prerelease); i++ {
+		x, y := v.Prerelease[i], other.Prerelease[i]
+		if x == y {
+			continue
+		}
+		xn, xErr := strconv.Atoi(x)
+		yn, yErr := strconv.Atoi(y)
+		switch {
+		case xErr == nil && yErr == nil:
+			return xn < yn
+		case xErr == nil || yErr == nil:
+			// Numeric identifiers precede alphanumeric ones:
+			return xErr == nil
+		default:
+			return x < y
+		}
+	}
+	return len(v.Prerelease) < len(other.Prerelease)
+}
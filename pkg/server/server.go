@@ -0,0 +1,232 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/metrics"
+	"github.com/weaveworks-experiments/imagediff/pkg/render"
+)
+
+// DiffFunc diffs the two provided images, e.g. diff.DiffContext, along with
+// its options, until the provided context is done.
+type DiffFunc func(ctx context.Context, x, y string) (*diff.Result, error)
+
+// Options encapsulates the various options of the server.
+type Options struct {
+	// Timeout bounds how long requests wait for their diff. Diffs are
+	// cancelled along with their request, e.g. once timed out, but keep
+	// counting against MaxConcurrentDiffs until they return.
+	Timeout time.Duration
+	// MaxConcurrentDiffs bounds how many diffs run concurrently. Requests
+	// wait for one to complete, within their timeout, past that.
+	MaxConcurrentDiffs int
+	// Ready checks whether the server is ready to serve diffs, e.g. whether
+	// the Docker daemon is reachable. Optional.
+	Ready func() error
+}
+
+// Server serves diffs over HTTP.
+type Server struct {
+	diff    DiffFunc
+	options Options
+	slots   chan struct{}
+}
+
+// New creates a new server of the diffs computed by the provided function.
+func New(diff DiffFunc, options *Options) *Server {
+	s := &Server{diff: diff, options: *options}
+	if s.options.Timeout <= 0 {
+		s.options.Timeout = 2 * time.Minute
+	}
+	if s.options.MaxConcurrentDiffs < 1 {
+		s.options.MaxConcurrentDiffs = 1
+	}
+	s.slots = make(chan struct{}, s.options.MaxConcurrentDiffs)
+	return s
+}
+
+// Handler routes requests to this server's endpoints:
+//   - GET /diff?from=<image>&to=<image>: diffs both images, and renders the
+//     result as JSON, Markdown, HTML or plain text, as negotiated with the
+//     request's Accept header, JSON being the default,
+//   - GET /healthz: liveness, i.e. whether the server is up,
+//   - GET /readyz: readiness, i.e. whether the server is ready to serve diffs,
+//   - GET /metrics: Prometheus metrics.
+func (s *Server) Handler() http.Handler {
+	router := mux.NewRouter()
+	router.Handle("/diff", promhttp.InstrumentHandlerDuration(metrics.RequestDuration, http.HandlerFunc(s.serveDiff))).Methods(http.MethodGet)
+	router.Handle("/metrics", promhttp.Handler()).Methods(http.MethodGet)
+	router.HandleFunc("/healthz", s.serveHealth).Methods(http.MethodGet)
+	router.HandleFunc("/readyz", s.serveReadiness).Methods(http.MethodGet)
+	return router
+}
+
+type renderFunc func(io.Writer, *diff.Result) error
+
+// renderers maps the media types diffs can be rendered as to their renderers.
+var renderers = map[string]renderFunc{
+	"application/json": render.JSON,
+	"text/markdown":    render.Markdown,
+	"text/html":        render.HTML,
+	"text/plain":       render.Text,
+}
+
+// errTooBusy is returned when no diff completed within a request's timeout, for it to run its own.
+var errTooBusy = errors.New("too many concurrent diffs, please retry later")
+
+func (s *Server) serveDiff(w http.ResponseWriter, r *http.Request) {
+	mediaType, ok := negotiate(r.Header.Get("Accept"))
+	if !ok {
+		writeError(w, "application/json", http.StatusNotAcceptable, fmt.Errorf("unsupported media types: %v", r.Header.Get("Accept")))
+		return
+	}
+	x, y := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if x == "" || y == "" {
+		writeError(w, mediaType, http.StatusBadRequest, errors.New("please provide two images to diff, as ?from=<image>&to=<image>"))
+		return
+	}
+	logger := log.WithFields(log.Fields{"x": x, "y": y})
+	ctx, cancel := context.WithTimeout(r.Context(), s.options.Timeout)
+	defer cancel()
+	result, err := s.diffWithin(ctx, x, y)
+	switch {
+	case err == errTooBusy:
+		logger.Warn(err)
+		w.Header().Set("Retry-After", strconv.Itoa(int(s.options.Timeout.Seconds())))
+		writeError(w, mediaType, http.StatusServiceUnavailable, err)
+		return
+	case err == context.DeadlineExceeded:
+		logger.Warn("diff timed out")
+		writeError(w, mediaType, http.StatusGatewayTimeout, fmt.Errorf("diff did not complete within %v", s.options.Timeout))
+		return
+	case err == context.Canceled:
+		return
+	case err != nil:
+		logger.Error(err)
+		writeError(w, mediaType, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType(mediaType))
+	if err := renderers[mediaType](w, result); err != nil {
+		logger.Errorf("failed to render diff: %v", err)
+	}
+}
+
+// diffWithin diffs the provided images, unless the provided context is done
+// before a slot to run the diff becomes available, or before the diff
+// completes.
+func (s *Server) diffWithin(ctx context.Context, x, y string) (*diff.Result, error) {
+	select {
+	case s.slots <- struct{}{}:
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, errTooBusy
+		}
+		return nil, ctx.Err()
+	}
+	type outcome struct {
+		result *diff.Result
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		metrics.DiffsInFlight.Inc()
+		defer func() {
+			metrics.DiffsInFlight.Dec()
+			<-s.slots
+		}()
+		result, err := s.diff(ctx, x, y)
+		done <- outcome{result, err}
+	}()
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *Server) serveHealth(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintln(w, "ok")
+}
+
+func (s *Server) serveReadiness(w http.ResponseWriter, r *http.Request) {
+	if s.options.Ready != nil {
+		if err := s.options.Ready(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+// negotiate picks the media type to render diffs as, from the provided
+// Accept header, honouring its quality factors and wildcards.
+func negotiate(accept string) (string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return "application/json", true
+	}
+	type candidate struct {
+		mediaType string
+		quality   float64
+	}
+	candidates := []candidate{}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil {
+			quality = q
+		}
+		if quality > 0 {
+			candidates = append(candidates, candidate{mediaType, quality})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].quality > candidates[j].quality })
+	for _, c := range candidates {
+		switch {
+		case renderers[c.mediaType] != nil:
+			return c.mediaType, true
+		case c.mediaType == "*/*" || c.mediaType == "application/*":
+			return "application/json", true
+		case c.mediaType == "text/*":
+			return "text/markdown", true
+		}
+	}
+	return "", false
+}
+
+func contentType(mediaType string) string {
+	if mediaType == "application/json" {
+		return mediaType
+	}
+	return mediaType + "; charset=utf-8"
+}
+
+func writeError(w http.ResponseWriter, mediaType string, status int, err error) {
+	if mediaType != "application/json" {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,246 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/repository"
+	"github.com/weaveworks-experiments/imagediff/pkg/server"
+)
+
+// fakeDiff emulates diffing images built from a fake repository, without
+// pulling images nor cloning repositories.
+func fakeDiff(ctx context.Context, x, y string) (*diff.Result, error) {
+	if !strings.HasPrefix(x, "microscaling/microscaling:") {
+		return nil, errors.New("no source code repository found in the image's labels")
+	}
+	return &diff.Result{
+		X:          x,
+		Y:          y,
+		Repository: &repository.GitRepository{Host: "github.com", Organization: "microscaling", Repository: "microscaling"},
+		XRevision:  "4756fd6",
+		YRevision:  "91740fb",
+		ChangeLog: []*diff.Change{
+			{Revision: "91740fb2e9d8e4b8fa0d9b3e0e8a3c7c3b5e6a1f", Message: "Bump version\n"},
+		},
+	}, nil
+}
+
+func get(t *testing.T, server *httptest.Server, path, accept string) (*http.Response, string) {
+	req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+	assert.NoError(t, err)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return resp, string(body)
+}
+
+func diffPath(x, y string) string {
+	return "/diff?" + url.Values{"from": {x}, "to": {y}}.Encode()
+}
+
+func TestDiffAsJSON(t *testing.T) {
+	s := httptest.NewServer(server.New(fakeDiff, &server.Options{}).Handler())
+	defer s.Close()
+
+	resp, body := get(t, s, diffPath("microscaling/microscaling:0.9.0", "microscaling/microscaling:0.9.1"), "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	var result diff.Result
+	assert.NoError(t, json.Unmarshal([]byte(body), &result))
+	assert.Equal(t, "microscaling/microscaling:0.9.1", result.Y)
+	assert.Len(t, result.ChangeLog, 1)
+}
+
+func TestDiffContentNegotiation(t *testing.T) {
+	s := httptest.NewServer(server.New(fakeDiff, &server.Options{}).Handler())
+	defer s.Close()
+	path := diffPath("microscaling/microscaling:0.9.0", "microscaling/microscaling:0.9.1")
+
+	resp, body := get(t, s, path, "text/markdown")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/markdown; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Contains(t, body, "[`91740fb`](https://github.com/microscaling/microscaling/commit/91740fb2e9d8e4b8fa0d9b3e0e8a3c7c3b5e6a1f) Bump version")
+
+	resp, _ = get(t, s, path, "application/xml;q=1, text/html;q=0.5, application/json;q=0.2")
+	assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+	resp, _ = get(t, s, path, "text/*")
+	assert.Equal(t, "text/markdown; charset=utf-8", resp.Header.Get("Content-Type"))
+	resp, _ = get(t, s, path, "*/*")
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	resp, _ = get(t, s, path, "image/png")
+	assert.Equal(t, http.StatusNotAcceptable, resp.StatusCode)
+}
+
+func TestDiffErrors(t *testing.T) {
+	s := httptest.NewServer(server.New(fakeDiff, &server.Options{}).Handler())
+	defer s.Close()
+
+	resp, body := get(t, s, "/diff?from=microscaling/microscaling:0.9.0", "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.JSONEq(t, `{"error": "please provide two images to diff, as ?from=<image>&to=<image>"}`, body)
+
+	resp, body = get(t, s, diffPath("postgres:10", "postgres:11"), "text/plain")
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, "no source code repository found in the image's labels\n", body)
+
+	req, err := http.NewRequest(http.MethodPost, s.URL+"/diff", nil)
+	assert.NoError(t, err)
+	postResp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	postResp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, postResp.StatusCode)
+}
+
+func TestDiffTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	slowDiff := func(ctx context.Context, x, y string) (*diff.Result, error) {
+		<-release
+		return fakeDiff(ctx, x, y)
+	}
+	s := httptest.NewServer(server.New(slowDiff, &server.Options{Timeout: 50 * time.Millisecond}).Handler())
+	defer s.Close()
+
+	resp, body := get(t, s, diffPath("microscaling/microscaling:0.9.0", "microscaling/microscaling:0.9.1"), "")
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	assert.Contains(t, body, "diff did not complete within 50ms")
+}
+
+func TestDiffCancelledOnTimeout(t *testing.T) {
+	cancelled := make(chan error, 1)
+	slowDiff := func(ctx context.Context, x, y string) (*diff.Result, error) {
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+		return nil, ctx.Err()
+	}
+	s := httptest.NewServer(server.New(slowDiff, &server.Options{Timeout: 50 * time.Millisecond}).Handler())
+	defer s.Close()
+
+	resp, _ := get(t, s, diffPath("microscaling/microscaling:0.9.0", "microscaling/microscaling:0.9.1"), "")
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	select {
+	case err := <-cancelled:
+		assert.Equal(t, context.DeadlineExceeded, err)
+	case <-time.After(time.Second):
+		assert.Fail(t, "diff not cancelled")
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	var mutex sync.Mutex
+	running, maxRunning := 0, 0
+	slowDiff := func(ctx context.Context, x, y string) (*diff.Result, error) {
+		mutex.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mutex.Unlock()
+		started <- struct{}{}
+		<-release
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+		return fakeDiff(ctx, x, y)
+	}
+	s := httptest.NewServer(server.New(slowDiff, &server.Options{Timeout: time.Second, MaxConcurrentDiffs: 1}).Handler())
+	defer s.Close()
+	path := diffPath("microscaling/microscaling:0.9.0", "microscaling/microscaling:0.9.1")
+
+	var wg sync.WaitGroup
+	statuses := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, _ := get(t, s, path, "")
+			statuses <- resp.StatusCode
+		}()
+	}
+	<-started
+	// The second request waits for the first diff to complete, then runs its own:
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(statuses)
+	for status := range statuses {
+		assert.Equal(t, http.StatusOK, status)
+	}
+	assert.Equal(t, 1, maxRunning)
+}
+
+func TestTooBusy(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	slowDiff := func(ctx context.Context, x, y string) (*diff.Result, error) {
+		close(started)
+		<-release
+		return fakeDiff(ctx, x, y)
+	}
+	s := httptest.NewServer(server.New(slowDiff, &server.Options{Timeout: 50 * time.Millisecond, MaxConcurrentDiffs: 1}).Handler())
+	defer s.Close()
+	path := diffPath("microscaling/microscaling:0.9.0", "microscaling/microscaling:0.9.1")
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		resp, _ := get(t, s, path, "")
+		assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	}()
+	<-started
+	resp, body := get(t, s, path, "")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Contains(t, body, "too many concurrent diffs")
+	wg.Wait()
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	var readiness error
+	s := httptest.NewServer(server.New(fakeDiff, &server.Options{Ready: func() error { return readiness }}).Handler())
+	defer s.Close()
+
+	resp, body := get(t, s, "/healthz", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "ok\n", body)
+	resp, _ = get(t, s, "/readyz", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	readiness = errors.New("Cannot connect to the Docker daemon")
+	resp, body = get(t, s, "/readyz", "")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "Cannot connect to the Docker daemon\n", body)
+	resp, _ = get(t, s, "/healthz", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestMetrics(t *testing.T) {
+	s := httptest.NewServer(server.New(fakeDiff, &server.Options{}).Handler())
+	defer s.Close()
+
+	get(t, s, diffPath("microscaling/microscaling:0.9.0", "microscaling/microscaling:0.9.1"), "")
+	resp, body := get(t, s, "/metrics", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, `imagediff_http_request_duration_seconds_count{code="200"}`)
+	assert.Contains(t, body, "imagediff_diffs_in_flight 0")
+}
@@ -0,0 +1,107 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Push is an image's tag pushed to a registry.
+type Push struct {
+	// Image is the image's repository, including its registry, e.g. "quay.io/org/app".
+	Image string `json:"image"`
+	Tag   string `json:"tag"`
+}
+
+func (p Push) String() string {
+	return p.Image + ":" + p.Tag
+}
+
+// parseFunc extracts the pushed tags from a registry's webhook's payload.
+type parseFunc func(payload []byte) ([]Push, error)
+
+// parseRegistry parses Docker Registry's notifications, see also:
+// https://docs.docker.com/registry/notifications/
+// Pushes of blobs, and of manifests by digest, are ignored, as they have no tag.
+func parseRegistry(payload []byte) ([]Push, error) {
+	var envelope struct {
+		Events []struct {
+			Action string `json:"action"`
+			Target struct {
+				Repository string `json:"repository"`
+				Tag        string `json:"tag"`
+			} `json:"target"`
+			Request struct {
+				Host string `json:"host"`
+			} `json:"request"`
+		} `json:"events"`
+	}
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return nil, err
+	}
+	pushes := []Push{}
+	for _, event := range envelope.Events {
+		if event.Action != "push" || event.Target.Tag == "" {
+			continue
+		}
+		if event.Request.Host == "" || event.Target.Repository == "" {
+			return nil, fmt.Errorf("invalid push event for tag [%v]: missing host or repository", event.Target.Tag)
+		}
+		pushes = append(pushes, Push{Image: event.Request.Host + "/" + event.Target.Repository, Tag: event.Target.Tag})
+	}
+	return pushes, nil
+}
+
+// parseHarbor parses Harbor's webhooks, as sent by Harbor 2 (PUSH_ARTIFACT)
+// and Harbor 1 (pushImage), see also:
+// https://goharbor.io/docs/main/working-with-projects/project-configuration/configure-webhooks/
+func parseHarbor(payload []byte) ([]Push, error) {
+	var event struct {
+		Type      string `json:"type"`
+		EventData struct {
+			Resources []struct {
+				Tag         string `json:"tag"`
+				ResourceURL string `json:"resource_url"`
+			} `json:"resources"`
+		} `json:"event_data"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+	if event.Type != "PUSH_ARTIFACT" && event.Type != "pushImage" {
+		return []Push{}, nil
+	}
+	pushes := []Push{}
+	for _, resource := range event.EventData.Resources {
+		if resource.Tag == "" {
+			continue
+		}
+		// The resource's URL is the pushed image, e.g. "harbor.example.com/library/app:v1.0.0":
+		image := strings.TrimSuffix(resource.ResourceURL, ":"+resource.Tag)
+		if image == "" || image == resource.ResourceURL {
+			return nil, fmt.Errorf("invalid push event for tag [%v]: unexpected resource URL [%v]", resource.Tag, resource.ResourceURL)
+		}
+		pushes = append(pushes, Push{Image: image, Tag: resource.Tag})
+	}
+	return pushes, nil
+}
+
+// parseQuay parses Quay's "Push to Repository" notifications, see also:
+// https://docs.quay.io/guides/notifications.html
+func parseQuay(payload []byte) ([]Push, error) {
+	var event struct {
+		DockerURL   string   `json:"docker_url"`
+		UpdatedTags []string `json:"updated_tags"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+	if event.DockerURL == "" {
+		return nil, fmt.Errorf("invalid push event: missing docker_url")
+	}
+	pushes := []Push{}
+	for _, tag := range event.UpdatedTags {
+		pushes = append(pushes, Push{Image: event.DockerURL, Tag: tag})
+	}
+	return pushes, nil
+}
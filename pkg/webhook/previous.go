@@ -0,0 +1,112 @@
+package webhook
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/weaveworks-experiments/imagediff/pkg/semver"
+)
+
+// ErrNoPrevious is returned by finders when a tag has no previous tag, e.g. when first pushed.
+var ErrNoPrevious = errors.New("no previous tag")
+
+// Finder finds the tag preceding a pushed tag, in the same repository, to diff the pushed tag against.
+type Finder interface {
+	Previous(image, tag string) (string, error)
+}
+
+// Lister lists the tags of an image's repository, e.g. registry.Client.
+type Lister interface {
+	Tags(image string) ([]string, error)
+}
+
+// SemverFinder finds the greatest semantic version preceding the pushed
+// one, amongst the repository's tags. Pre-releases are only considered when
+// the pushed tag is itself a pre-release, so that a release is diffed
+// against the previous release.
+type SemverFinder struct {
+	Lister Lister
+}
+
+// Previous finds the tag preceding the provided one.
+func (f *SemverFinder) Previous(image, tag string) (string, error) {
+	pushed, ok := semver.Parse(tag)
+	if !ok {
+		return "", ErrNoPrevious
+	}
+	tags, err := f.Lister.Tags(image)
+	if err != nil {
+		return "", err
+	}
+	var previous string
+	var greatest *semver.Version
+	for _, t := range tags {
+		v, ok := semver.Parse(t)
+		if !ok || !v.Less(pushed) || (len(v.Prerelease) > 0 && len(pushed.Prerelease) == 0) {
+			continue
+		}
+		if greatest == nil || greatest.Less(v) {
+			previous, greatest = t, v
+		}
+	}
+	if previous == "" {
+		return "", ErrNoPrevious
+	}
+	return previous, nil
+}
+
+// PushedFinder remembers the tags pushed to each repository, and finds the
+// one pushed before the provided one, i.e. what was likely previously
+// deployed. Pushes are only remembered in memory, and therefore lost on
+// restart.
+type PushedFinder struct {
+	mutex  sync.Mutex
+	pushed map[string]string
+}
+
+// NewPushedFinder creates a new finder of previously pushed tags.
+func NewPushedFinder() *PushedFinder {
+	return &PushedFinder{pushed: map[string]string{}}
+}
+
+// Previous finds the tag pushed before the provided one, and remembers the provided one.
+func (f *PushedFinder) Previous(image, tag string) (string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	previous, ok := f.pushed[image]
+	f.pushed[image] = tag
+	if !ok || previous == tag {
+		return "", ErrNoPrevious
+	}
+	return previous, nil
+}
+
+// Chain tries each of its finders in turn, and returns the first previous
+// tag found. All finders are called, even once one found a previous tag, for
+// stateful finders like PushedFinder to remember every push.
+type Chain []Finder
+
+// Previous finds the tag preceding the provided one.
+func (c Chain) Previous(image, tag string) (string, error) {
+	var previous string
+	var lastErr error
+	for _, finder := range c {
+		p, err := finder.Previous(image, tag)
+		if err != nil {
+			if err != ErrNoPrevious {
+				lastErr = err
+			}
+			continue
+		}
+		if previous == "" {
+			previous = p
+		}
+	}
+	if previous != "" {
+		return previous, nil
+	}
+	if lastErr != nil {
+		return "", lastErr
+	}
+	return "", ErrNoPrevious
+}
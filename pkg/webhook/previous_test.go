@@ -0,0 +1,78 @@
+package webhook_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/webhook"
+)
+
+type fakeLister []string
+
+func (l fakeLister) Tags(image string) ([]string, error) {
+	if image == "broken" {
+		return nil, errors.New("failed to list tags")
+	}
+	return l, nil
+}
+
+func TestSemverFinder(t *testing.T) {
+	finder := &webhook.SemverFinder{Lister: fakeLister{
+		"latest", "v0.9.0", "v0.10.0-rc.1", "v0.10.0-rc.2", "v0.10.0", "v0.10.1", "v0.11.0-rc.1", "v1.0", "master-1234567",
+	}}
+	for tag, expected := range map[string]string{
+		"v0.10.1":       "v0.10.0",
+		"v0.11.0":       "v0.10.1",
+		"v0.11.0-rc.1":  "v0.10.1",
+		"v0.11.0-rc.2":  "v0.11.0-rc.1",
+		"v0.10.0-rc.10": "v0.10.0-rc.2",
+		"v1.0.1":        "v1.0",
+		"0.10.0":        "v0.9.0",
+	} {
+		previous, err := finder.Previous("org/app", tag)
+		assert.NoError(t, err, tag)
+		assert.Equal(t, expected, previous, tag)
+	}
+	for _, tag := range []string{"v0.9.0", "v0.1.0", "latest", "master-89abcde"} {
+		_, err := finder.Previous("org/app", tag)
+		assert.Equal(t, webhook.ErrNoPrevious, err, tag)
+	}
+	_, err := finder.Previous("broken", "v1.0.0")
+	assert.EqualError(t, err, "failed to list tags")
+}
+
+func TestPushedFinder(t *testing.T) {
+	finder := webhook.NewPushedFinder()
+	_, err := finder.Previous("org/app", "master-1234567")
+	assert.Equal(t, webhook.ErrNoPrevious, err)
+	_, err = finder.Previous("org/other", "master-89abcde")
+	assert.Equal(t, webhook.ErrNoPrevious, err)
+	previous, err := finder.Previous("org/app", "master-89abcde")
+	assert.NoError(t, err)
+	assert.Equal(t, "master-1234567", previous)
+	_, err = finder.Previous("org/app", "master-89abcde")
+	assert.Equal(t, webhook.ErrNoPrevious, err)
+}
+
+func TestChain(t *testing.T) {
+	pushed := webhook.NewPushedFinder()
+	chain := webhook.Chain{&webhook.SemverFinder{Lister: fakeLister{"v1.0.0", "v1.1.0"}}, pushed}
+
+	// The first push is only remembered:
+	_, err := chain.Previous("org/app", "master-1234567")
+	assert.Equal(t, webhook.ErrNoPrevious, err)
+	previous, err := chain.Previous("org/app", "master-89abcde")
+	assert.NoError(t, err)
+	assert.Equal(t, "master-1234567", previous)
+	// Semantic versions take precedence, yet pushes are still remembered:
+	previous, err = chain.Previous("org/app", "v1.1.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.0.0", previous)
+	previous, err = pushed.Previous("org/app", "v1.2.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.1.0", previous)
+
+	_, err = webhook.Chain{&webhook.SemverFinder{Lister: fakeLister{}}}.Previous("broken", "v1.0.0")
+	assert.EqualError(t, err, "failed to list tags")
+}
@@ -0,0 +1,193 @@
+package webhook
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/weaveworks-experiments/imagediff/pkg/metrics"
+	"github.com/weaveworks-experiments/imagediff/pkg/notify"
+	"github.com/weaveworks-experiments/imagediff/pkg/server"
+)
+
+// maxPayloadSize bounds the size of webhooks' payloads read.
+const maxPayloadSize = 1 << 20
+
+// Options encapsulates the various options of the receiver.
+type Options struct {
+	// Finder finds the tags to diff pushed tags against.
+	Finder Finder
+	// Notifiers are sent the result of each diff.
+	Notifiers []notify.Notifier
+	// Token is a shared secret webhooks must provide, either as a bearer
+	// token in their Authorization header, or as a "token" query parameter,
+	// for registries which cannot set headers. Optional.
+	Token string
+	// Workers is how many pushes are diffed concurrently.
+	Workers int
+	// QueueSize bounds how many pushes wait to be diffed. Webhooks are
+	// rejected past that, for registries to retry them later.
+	QueueSize int
+}
+
+// Receiver receives registries' push webhooks, diffs the pushed tags against
+// their previous tags, and notifies the results.
+type Receiver struct {
+	diff    server.DiffFunc
+	options Options
+	queue   chan Push
+	wg      sync.WaitGroup
+	// mutex guards closed, so that handlers never queue pushes once the queue
+	// is closed, which would panic.
+	mutex  sync.RWMutex
+	closed bool
+}
+
+// New creates a new receiver, diffing pushed tags with the provided function, and starts its workers.
+func New(diff server.DiffFunc, options *Options) *Receiver {
+	r := &Receiver{diff: diff, options: *options}
+	if r.options.Workers < 1 {
+		r.options.Workers = 1
+	}
+	if r.options.QueueSize < 1 {
+		r.options.QueueSize = 100
+	}
+	r.queue = make(chan Push, r.options.QueueSize)
+	for i := 0; i < r.options.Workers; i++ {
+		r.wg.Add(1)
+		go r.work()
+	}
+	return r
+}
+
+// Close stops accepting pushes, and waits for queued ones to be diffed and notified.
+func (r *Receiver) Close() {
+	r.mutex.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.queue)
+	}
+	r.mutex.Unlock()
+	r.wg.Wait()
+}
+
+// Handler routes webhooks to this receiver's endpoints, which all respond
+// with 202 Accepted and the pushes queued, as diffs run asynchronously:
+//   - POST /webhooks/registry: Docker Registry's notifications,
+//   - POST /webhooks/harbor: Harbor's webhooks,
+//   - POST /webhooks/quay: Quay's "Push to Repository" notifications.
+func (r *Receiver) Handler() http.Handler {
+	router := mux.NewRouter()
+	router.HandleFunc("/webhooks/registry", r.handle(parseRegistry)).Methods(http.MethodPost)
+	router.HandleFunc("/webhooks/harbor", r.handle(parseHarbor)).Methods(http.MethodPost)
+	router.HandleFunc("/webhooks/quay", r.handle(parseQuay)).Methods(http.MethodPost)
+	return router
+}
+
+var (
+	// errQueueFull is returned when too many pushes already wait to be diffed.
+	errQueueFull = errors.New("too many pushes queued, please retry later")
+	// errClosed is returned once the receiver stopped accepting pushes, e.g. when shutting down.
+	errClosed = errors.New("shutting down, please retry later")
+)
+
+func (r *Receiver) handle(parse parseFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if !r.authorized(req) {
+			writeError(w, http.StatusUnauthorized, errors.New("invalid or missing token"))
+			return
+		}
+		payload, err := ioutil.ReadAll(http.MaxBytesReader(w, req.Body, maxPayloadSize))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		pushes, err := parse(payload)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+			return
+		}
+		r.mutex.RLock()
+		defer r.mutex.RUnlock()
+		if r.closed {
+			writeError(w, http.StatusServiceUnavailable, errClosed)
+			return
+		}
+		for i, push := range pushes {
+			select {
+			case r.queue <- push:
+				log.WithField("push", push).Info("queued push")
+			default:
+				// Pushes already queued are diffed, and therefore not retried:
+				log.WithField("push", push).Warn(errQueueFull)
+				writeError(w, http.StatusServiceUnavailable, fmt.Errorf("%v, queued %v out of %v pushes", errQueueFull, i, len(pushes)))
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string][]Push{"pushes": pushes})
+	}
+}
+
+func (r *Receiver) authorized(req *http.Request) bool {
+	if r.options.Token == "" {
+		return true
+	}
+	token := req.URL.Query().Get("token")
+	if auth := req.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(r.options.Token)) == 1
+}
+
+func (r *Receiver) work() {
+	defer r.wg.Done()
+	for push := range r.queue {
+		r.process(push)
+	}
+}
+
+// process diffs the provided push against its previous tag, and notifies the result.
+func (r *Receiver) process(push Push) {
+	logger := log.WithField("push", push)
+	previous, err := r.options.Finder.Previous(push.Image, push.Tag)
+	if err == ErrNoPrevious {
+		logger.Info("no previous tag to diff against")
+		return
+	}
+	if err != nil {
+		logger.Errorf("failed to find previous tag: %v", err)
+		return
+	}
+	x, y := push.Image+":"+previous, push.String()
+	logger = logger.WithFields(log.Fields{"x": x, "y": y})
+	result, err := r.diff(context.Background(), x, y)
+	if err != nil {
+		logger.Errorf("failed to diff: %v", err)
+		return
+	}
+	for _, notifier := range r.options.Notifiers {
+		step := metrics.StartStep(metrics.Notify)
+		if err := step.Done(notifier.Notify(result)); err != nil {
+			logger.Errorf("failed to notify: %v", err)
+		}
+	}
+	logger.Info("notified diff")
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(map[string]string{"error": err.Error()})
+}
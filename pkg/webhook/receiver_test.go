@@ -0,0 +1,215 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks-experiments/imagediff/pkg/diff"
+	"github.com/weaveworks-experiments/imagediff/pkg/notify"
+	"github.com/weaveworks-experiments/imagediff/pkg/webhook"
+)
+
+// registryPayload is a Docker Registry notification, for the push of a layer, then of a tagged manifest.
+const registryPayload = `{
+  "events": [
+    {
+      "id": "320678d8-ca14-430f-8bb6-4ca139cd83f7",
+      "timestamp": "2018-03-15T10:29:00.000Z",
+      "action": "push",
+      "target": {
+        "mediaType": "application/octet-stream",
+        "size": 2094,
+        "digest": "sha256:fea8895f450959fa676bcc1df0611ea93823a735a01205fd8622846041d0c7cf",
+        "repository": "org/app",
+        "url": "https://registry.example.com/v2/org/app/blobs/sha256:fea8895f450959fa676bcc1df0611ea93823a735a01205fd8622846041d0c7cf"
+      },
+      "request": {"id": "6df24a34-0959-4923-81ca-14f09767db19", "addr": "192.168.64.11:42961", "host": "registry.example.com", "method": "PUT", "useragent": "docker/17.12.0-ce"},
+      "source": {"addr": "xtal.local:5000", "instanceID": "a53db899-3b4b-4a62-a067-8dd013beaca4"}
+    },
+    {
+      "id": "6b8b4a9f-0d2c-4e33-9f3c-4c8e1f2a7d10",
+      "timestamp": "2018-03-15T10:29:01.000Z",
+      "action": "push",
+      "target": {
+        "mediaType": "application/vnd.docker.distribution.manifest.v2+json",
+        "size": 708,
+        "digest": "sha256:fea8895f450959fa676bcc1df0611ea93823a735a01205fd8622846041d0c7cf",
+        "length": 708,
+        "repository": "org/app",
+        "url": "https://registry.example.com/v2/org/app/manifests/sha256:fea8895f450959fa676bcc1df0611ea93823a735a01205fd8622846041d0c7cf",
+        "tag": "v1.1.0"
+      },
+      "request": {"id": "6df24a34-0959-4923-81ca-14f09767db19", "addr": "192.168.64.11:42961", "host": "registry.example.com", "method": "PUT", "useragent": "docker/17.12.0-ce"},
+      "source": {"addr": "xtal.local:5000", "instanceID": "a53db899-3b4b-4a62-a067-8dd013beaca4"}
+    }
+  ]
+}`
+
+// harborPayload is a Harbor 2 webhook, for the push of an artifact.
+const harborPayload = `{
+  "type": "PUSH_ARTIFACT",
+  "occur_at": 1586922308,
+  "operator": "admin",
+  "event_data": {
+    "resources": [
+      {
+        "digest": "sha256:8a9e9863dbb6e10edb5adfe917c00da84e1700fa76e7ed02476aa6e6fb8ee0d8",
+        "tag": "v1.1.0",
+        "resource_url": "harbor.example.com/library/app:v1.1.0"
+      }
+    ],
+    "repository": {"date_created": 1586922308, "name": "app", "namespace": "library", "repo_full_name": "library/app", "repo_type": "private"}
+  }
+}`
+
+// quayPayload is a Quay "Push to Repository" notification.
+const quayPayload = `{
+  "name": "app",
+  "repository": "org/app",
+  "namespace": "org",
+  "docker_url": "quay.io/org/app",
+  "homepage": "https://quay.io/repository/org/app",
+  "updated_tags": ["v1.1.0"]
+}`
+
+// fakeFinder finds the previous tags of v1.1.0 pushes.
+type fakeFinder struct{}
+
+func (fakeFinder) Previous(image, tag string) (string, error) {
+	if tag != "v1.1.0" {
+		return "", webhook.ErrNoPrevious
+	}
+	return "v1.0.0", nil
+}
+
+func fakeDiff(ctx context.Context, x, y string) (*diff.Result, error) {
+	return &diff.Result{X: x, Y: y}, nil
+}
+
+// sink records the results POSTed to it.
+type sink struct {
+	*httptest.Server
+	mutex   sync.Mutex
+	results []diff.Result
+}
+
+func newSink(t *testing.T) *sink {
+	s := &sink{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var result diff.Result
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&result))
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.results = append(s.results, result)
+	}))
+	return s
+}
+
+func TestReceiver(t *testing.T) {
+	for _, test := range []struct {
+		path, payload, image string
+	}{
+		{"/webhooks/registry", registryPayload, "registry.example.com/org/app"},
+		{"/webhooks/harbor", harborPayload, "harbor.example.com/library/app"},
+		{"/webhooks/quay", quayPayload, "quay.io/org/app"},
+	} {
+		t.Run(test.path, func(t *testing.T) {
+			sink := newSink(t)
+			defer sink.Close()
+			receiver := webhook.New(fakeDiff, &webhook.Options{Finder: fakeFinder{}, Notifiers: []notify.Notifier{notify.NewWebhook(sink.URL)}})
+			server := httptest.NewServer(receiver.Handler())
+			defer server.Close()
+
+			resp, err := http.Post(server.URL+test.path, "application/json", strings.NewReader(test.payload))
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+			var accepted struct {
+				Pushes []webhook.Push `json:"pushes"`
+			}
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&accepted))
+			assert.Equal(t, []webhook.Push{{Image: test.image, Tag: "v1.1.0"}}, accepted.Pushes)
+
+			receiver.Close()
+			assert.Equal(t, []diff.Result{{X: test.image + ":v1.0.0", Y: test.image + ":v1.1.0"}}, sink.results)
+		})
+	}
+}
+
+func TestReceiverSkipsPushesWithoutPreviousTag(t *testing.T) {
+	sink := newSink(t)
+	defer sink.Close()
+	receiver := webhook.New(fakeDiff, &webhook.Options{Finder: fakeFinder{}, Notifiers: []notify.Notifier{notify.NewWebhook(sink.URL)}})
+	server := httptest.NewServer(receiver.Handler())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/webhooks/quay", "application/json", strings.NewReader(strings.Replace(quayPayload, "v1.1.0", "v1.0.0", 1)))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	receiver.Close()
+	assert.Empty(t, sink.results)
+}
+
+func TestReceiverRejectsInvalidPayloads(t *testing.T) {
+	receiver := webhook.New(fakeDiff, &webhook.Options{Finder: fakeFinder{}})
+	defer receiver.Close()
+	server := httptest.NewServer(receiver.Handler())
+	defer server.Close()
+
+	for _, path := range []string{"/webhooks/registry", "/webhooks/harbor", "/webhooks/quay"} {
+		resp, err := http.Post(server.URL+path, "application/json", strings.NewReader(`{"events": "nope"`))
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, path)
+	}
+	resp, err := http.Post(server.URL+"/webhooks/quay", "application/json", strings.NewReader(`{"updated_tags": ["v1.1.0"]}`))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestReceiverRejectsPushesOnceClosed(t *testing.T) {
+	receiver := webhook.New(fakeDiff, &webhook.Options{Finder: fakeFinder{}})
+	server := httptest.NewServer(receiver.Handler())
+	defer server.Close()
+	receiver.Close()
+
+	resp, err := http.Post(server.URL+"/webhooks/registry", "application/json", strings.NewReader(registryPayload))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	receiver.Close()
+}
+
+func TestReceiverToken(t *testing.T) {
+	receiver := webhook.New(fakeDiff, &webhook.Options{Finder: fakeFinder{}, Token: "s3cr3t"})
+	defer receiver.Close()
+	server := httptest.NewServer(receiver.Handler())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/webhooks/quay", "application/json", strings.NewReader(quayPayload))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = http.Post(server.URL+"/webhooks/quay?token=s3cr3t", "application/json", strings.NewReader(quayPayload))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/webhooks/quay", strings.NewReader(quayPayload))
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer s3cr3t")
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+}